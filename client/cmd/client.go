@@ -28,13 +28,21 @@ func (c *Client) Run(inputpath string) {
 	inputs := ReadClientInput(inputpath)
 	urls := c.cfg.URLs
 
-	zk, err := ligero.NewLigeroZK(c.cfg.N_secrets, c.cfg.M, c.cfg.N, c.cfg.T, c.cfg.Q, c.cfg.N_open)
-	if err != nil {
-		log.Fatalf("err: %v", err)
-	}
-
 	for _, input := range inputs {
 
+		pred, err := ligero.ParsePredicate(input.Predicate)
+		if err != nil {
+			log.Fatalf("err: %v", err)
+		}
+
+		//the products of pairs of attributes are appended to the attributes
+		input.Secrets = ligero.FillProducts(pred, input.Secrets)
+
+		zk, err := ligero.NewLigeroZKFromParams(c.cfg.LigeroParams(), pred)
+		if err != nil {
+			log.Fatalf("err: %v", err)
+		}
+
 		/**
 		//test c1's input is malformed
 		if c.cfg.Client_ID == "c1" {
@@ -43,7 +51,7 @@ func (c *Client) Run(inputpath string) {
 
 		proof_start := time.Now() //Proof generation start time
 
-		proof, err := zk.GenerateProof(input.Secrets)
+		proof, err := zk.GenerateProof(ligero.Context{Exp_ID: input.Exp_ID, Client_ID: c.cfg.Client_ID}, input.Secrets)
 
 		proof_end := time.Since(proof_start) //Proof generation end time
 
@@ -78,14 +86,18 @@ func (c *Client) Run(inputpath string) {
 					mal_proof := proof[idx]
 					mal_proof.CodeTest = make([]int, len(proof[0].CodeTest))
 
-					msg = ClientRequest{Exp_ID: input.Exp_ID, Client_ID: c.cfg.Client_ID, Token: c.cfg.Token, Proof: *mal_proof, Timestamp: current_time.String()}
+					msg = ClientRequest{Exp_ID: input.Exp_ID, Client_ID: c.cfg.Client_ID, Token: c.cfg.Token, Proof: *mal_proof, Timestamp: current_time.String(), Predicate: pred.String()}
 				} else {
-					msg = ClientRequest{Exp_ID: input.Exp_ID, Client_ID: c.cfg.Client_ID, Token: c.cfg.Token, Proof: *proof[idx], Timestamp: current_time.String()}
+					msg = ClientRequest{Exp_ID: input.Exp_ID, Client_ID: c.cfg.Client_ID, Token: c.cfg.Token, Proof: *proof[idx], Timestamp: current_time.String(), Predicate: pred.String()}
 				}
 
 				writer := &msg
 				log.Printf("client %s is sending data of %s to server%d ...\n", msg.Client_ID, msg.Exp_ID, msg.Proof.Shares.PartyIndex)
-				c.Send(urls[idx], writer.ToJson())
+				//servers that cannot decode binary proofs reject them as unsupported media type
+				status := c.Send(urls[idx], writer.ToBinary(c.cfg.Q), ligero.ContentTypeBinary)
+				if status == http.StatusUnsupportedMediaType {
+					c.Send(urls[idx], writer.ToJson(), "application/json; charset=UTF-8")
+				}
 			}(i)
 
 		}
@@ -94,26 +106,28 @@ func (c *Client) Run(inputpath string) {
 
 }
 
-func (c *Client) Send(address string, data []byte) {
+// Send posts data with the given Content-Type and returns the response status code, or 0 if the request failed
+func (c *Client) Send(address string, data []byte, content_type string) int {
 	req, err := http.NewRequest("POST", address, bytes.NewBuffer(data))
 	if err != nil {
 		log.Fatalf("impossible to build http post request: %s", err)
 	}
-	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	req.Header.Set("Content-Type", content_type)
 
 	client := &http.Client{}
 	res, err := client.Do(req)
 	if err != nil {
 		log.Printf("impossible to send http request: %s", err)
-	} else {
-		log.Printf("response Status:%s", res.Status)
+		return 0
+	}
 
-		defer res.Body.Close()
-		body, _ := io.ReadAll(res.Body)
-		if len(body) > 0 {
-			fmt.Println("response Body:", string(body))
-		}
+	log.Printf("response Status:%s", res.Status)
 
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	if len(body) > 0 {
+		fmt.Println("response Body:", string(body))
 	}
 
+	return res.StatusCode
 }
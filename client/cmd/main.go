@@ -53,6 +53,7 @@ func main() {
 		"T":         conf.T,
 		"Q":         conf.Q,
 		"N_secrets": conf.N_secrets,
+		"Sharing":   conf.Sharing,
 		"M":         conf.M,
 		"N_open":    conf.N_open,
 		"URLs":      conf.URLs,
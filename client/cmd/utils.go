@@ -3,7 +3,9 @@ package main
 import (
 	"bytes"
 	"compress/gzip"
+	"encoding/binary"
 	"encoding/json"
+	"fmt"
 	"log"
 	"os"
 
@@ -15,12 +17,17 @@ type ClientRequest struct {
 	Client_ID string       `json:"Client_ID"`
 	Token     string       `json:"Token"`
 	Timestamp string       `json:"Timestamp"`
+	Predicate string       `json:"Predicate"`
 	Proof     ligero.Proof `json:"Proof"`
 }
 
 type Input struct {
-	Exp_ID  string `json:"Exp_ID"`
-	Secrets []int  `json:"Secrets"`
+	Exp_ID    string    `json:"Exp_ID"`
+	Secrets   []int     `json:"Secrets"`
+	Values    []float64 `json:"Values"`
+	Frac_bits int       `json:"Frac_bits"`
+	Bit_width int       `json:"Bit_width"` //deprecated, the predicate "range:k" without Predicate
+	Predicate string    `json:"Predicate"`
 }
 
 func (c *ClientRequest) ToJson() []byte {
@@ -29,6 +36,7 @@ func (c *ClientRequest) ToJson() []byte {
 		Client_ID: c.Client_ID,
 		Proof:     c.Proof,
 		Timestamp: c.Timestamp,
+		Predicate: c.Predicate,
 	}
 	message, err := json.Marshal(msg)
 
@@ -50,6 +58,23 @@ func (c *ClientRequest) ToJson() []byte {
 	return compressedData.Bytes()
 }
 
+// ToBinary encodes the request as its uvarint length-prefixed ids and predicate followed by the proof, binary encoded
+// for the field with modulus q
+func (c *ClientRequest) ToBinary(q int) []byte {
+	var message []byte
+	for _, field := range []string{c.Exp_ID, c.Client_ID, c.Token, c.Timestamp, c.Predicate} {
+		message = binary.AppendUvarint(message, uint64(len(field)))
+		message = append(message, field...)
+	}
+
+	proof, err := c.Proof.MarshalBinary(q)
+	if err != nil {
+		log.Fatalf("Cannot encode client request: %s", err)
+	}
+
+	return append(message, proof...)
+}
+
 func ReadClientInput(path string) []Input {
 	jsonData, err := os.ReadFile(path)
 	if err != nil {
@@ -63,6 +88,38 @@ func ReadClientInput(path string) []Input {
 		log.Fatalf("%s", err)
 		return nil
 	}
+
+	for i := range items {
+		items[i].Predicate, err = inputPredicate(items[i].Bit_width, items[i].Predicate)
+		if err != nil {
+			log.Fatalf("input of %s: %s", items[i].Exp_ID, err)
+			return nil
+		}
+		//real values are encoded as fixed-point secrets
+		if len(items[i].Values) > 0 {
+			secrets, err := ligero.EncodeFixedPoint(items[i].Values, items[i].Frac_bits)
+			if err != nil {
+				log.Fatalf("%s", err)
+				return nil
+			}
+			items[i].Secrets = secrets
+		}
+	}
 	return items
 
 }
+
+// inputPredicate returns the predicate an input is proven with. Bit_width is a deprecated alias of the predicate
+// "range:k" that cannot be combined with a predicate, inputs without either are 0/1 vectors.
+func inputPredicate(bit_width int, predicate string) (string, error) {
+	if bit_width != 0 && predicate != "" {
+		return "", fmt.Errorf("Bit_width is deprecated and cannot be combined with Predicate %q", predicate)
+	}
+	if predicate != "" {
+		return predicate, nil
+	}
+	if bit_width != 0 {
+		return ligero.RangePredicate(bit_width).String(), nil
+	}
+	return ligero.BitPredicate().String(), nil
+}
@@ -4,19 +4,24 @@ import (
 	"encoding/json"
 	"log"
 	"os"
+
+	"example.com/SMC/pkg/ligero"
 )
 
 type Client struct {
-	Client_ID string
-	Token     string
-	URLs      []string
-	N         int
-	T         int
-	K         int
-	Q         int
-	N_secrets int
-	M         int
-	N_open    int
+	Client_ID          string
+	Token              string
+	URLs               []string
+	N                  int
+	T                  int
+	K                  int
+	Q                  int
+	N_secrets          int
+	M                  int
+	N_open             int
+	Repetitions        int
+	Min_soundness_bits float64
+	Sharing            string
 }
 
 func NewConfig() *Client {
@@ -39,5 +44,23 @@ func Load(path string) *Client {
 		log.Fatalf("unable to read from client config file: %s", err)
 		return nil
 	}
+
+	config.check_soundness()
 	return &config
 }
+
+// LigeroParams returns the parameters of the Ligero proofs, Repetitions 0 means a single repetition
+func (config *Client) LigeroParams() ligero.Params {
+	return ligero.Params{N_secret: config.N_secrets, M: config.M, N_server: config.N, T: config.T, Q: config.Q, N_open: config.N_open, Repetitions: config.Repetitions, Sharing: config.Sharing}
+}
+
+// check_soundness refuses Ligero parameters below Min_soundness_bits and warns below the recommended soundness
+func (config *Client) check_soundness() {
+	s, err := ligero.CheckSoundness(config.LigeroParams(), config.Min_soundness_bits)
+	if err != nil {
+		log.Fatalf("insecure ligero parameters in client config file: %s", err)
+	}
+	if s.Bits < ligero.RecommendedSoundnessBits {
+		log.Printf("warning: ligero parameters give %.1f bits of soundness, %d bits are recommended\n", s.Bits, ligero.RecommendedSoundnessBits)
+	}
+}
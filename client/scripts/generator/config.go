@@ -10,15 +10,17 @@ import (
 )
 
 type Client struct {
-	Client_ID string
-	Token     string
-	URLs      []string //servers urls
-	N         int
-	T         int
-	Q         int
-	N_secrets int
-	M         int
-	N_open    int
+	Client_ID   string
+	Token       string
+	URLs        []string //servers urls
+	N           int
+	T           int
+	Q           int
+	N_secrets   int
+	M           int
+	N_open      int
+	Repetitions int
+	Sharing     string
 }
 
 func GenerateClientConfig(client_num int, src string, des string) {
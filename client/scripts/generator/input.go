@@ -10,7 +10,9 @@ import (
 	"strconv"
 )
 
-func GenerateClientInput(client_num int, exp_num int, value_num []int, des string) {
+// GenerateClientInput generates random 0/1 secrets of every client for every experiment, proven with the predicate
+// of the experiments unless it is empty
+func GenerateClientInput(client_num int, exp_num int, value_num []int, predicate string, des string) {
 	// Ensure the folder exists
 	err := os.MkdirAll(des, os.ModePerm)
 	if err != nil {
@@ -31,6 +33,9 @@ func GenerateClientInput(client_num int, exp_num int, value_num []int, des strin
 				"Exp_ID":  expID,
 				"Secrets": secrets,
 			}
+			if predicate != "" {
+				data["Predicate"] = predicate
+			}
 
 			dataList = append(dataList, data)
 		}
@@ -60,7 +65,7 @@ func GenerateClientInput(client_num int, exp_num int, value_num []int, des strin
 
 }
 
-func GenerateClientInputCloud(client_num, start_cid int, exp_num int, value_num []int, des string) {
+func GenerateClientInputCloud(client_num, start_cid int, exp_num int, value_num []int, predicate string, des string) {
 	// Ensure the folder exists
 	err := os.MkdirAll(des, os.ModePerm)
 	if err != nil {
@@ -82,6 +87,9 @@ func GenerateClientInputCloud(client_num, start_cid int, exp_num int, value_num
 				"Exp_ID":  expID,
 				"Secrets": secrets,
 			}
+			if predicate != "" {
+				data["Predicate"] = predicate
+			}
 
 			dataList = append(dataList, data)
 		}
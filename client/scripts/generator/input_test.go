@@ -7,9 +7,10 @@ import (
 )
 
 func TestGenerateInput(t *testing.T) {
-	generator.GenerateClientInput(6, 2, []int{1, 1}, "./input")
+	generator.GenerateClientInput(6, 2, []int{1, 1}, "", "./input")
+	generator.GenerateClientInput(2, 1, []int{2}, "bit", "./input")
 }
 
 func TestGenerateInputCloud(t *testing.T) {
-	generator.GenerateClientInputCloud(4, 10, 2, []int{1, 1}, "./input")
+	generator.GenerateClientInputCloud(4, 10, 2, []int{1, 1}, "", "./input")
 }
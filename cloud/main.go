@@ -31,6 +31,8 @@ func main() {
 	d1 := flag.Int("d1", 0, "duration from client share due to complaint due")
 	d2 := flag.Int("d2", 0, "duration from client share due to masked share due")
 	d3 := flag.Int("d3", 0, "duration from client share due to server share due")
+	d4 := flag.Int("d4", 0, "duration from client share due to reshare due, with products or noise")
+	predicate := flag.String("predicate", "", "predicate of the client inputs")
 
 	flag.Parse()
 
@@ -49,19 +51,20 @@ func main() {
 	t1 := *d1 // ComplaintDue = ClientShareDue + t1
 	t2 := *d2 // MaskedShareDue = ClientShareDue + t2
 	t3 := *d3 // ServerShareDue = ClientShareDue + t3
+	t4 := *d4 // ReshareDue = ClientShareDue + t4
 
 	if *party == "client" {
 
 		client_gen.GenerateClientConfigCloud(*client_threads, *start_cid, filepath.Join(*template_path, "client_template.json"), "./client_config")
 
-		client_gen.GenerateClientInputCloud(*client_threads, *start_cid, n_exp, input_list, "./client_input")
+		client_gen.GenerateClientInputCloud(*client_threads, *start_cid, n_exp, input_list, *predicate, "./client_input")
 
 		run(*client_threads, *n_clients_mal, *start_cid)
 
 	} else if *party == "server" {
 		server_gen.GenerateServerConfigCloud(*n_servers, server_port[:*n_servers], filepath.Join(*template_path, "server_template.json"), "./server_config")
 
-		server_gen.GenerateServerInput(n_exp, clientShareDue, t1, t2, "https://outputparty.privatestats.org/serverShare/", "./server_input")
+		server_gen.GenerateServerInput(n_exp, clientShareDue, t1, t2, t4, "https://outputparty.privatestats.org/serverShare/", server_gen.Options{Predicate: *predicate}, "./server_input")
 
 		arg := make([]string, 6)
 		arg[0] = "../server/cmd/cmd"
@@ -97,7 +100,7 @@ func main() {
 	} else if *party == "outputparty" {
 		output_gen.GenerateOPConfig(n_outputparty, op_port, filepath.Join(*template_path, "outputparty_template.json"), "./op_config")
 
-		output_gen.GenerateOPInput(n_exp, clientShareDue, t3, "./op_input")
+		output_gen.GenerateOPInput(n_exp, clientShareDue, t3, output_gen.Options{Predicate: *predicate}, "./op_input")
 
 		arg := make([]string, 5)
 		arg[0] = "../outputparty/cmd/cmd"
@@ -10,6 +10,7 @@ import (
 
 	client_gen "example.com/SMC/client/scripts/generator"
 	output_gen "example.com/SMC/outputparty/scripts/generator"
+	"example.com/SMC/pkg/dp"
 	server_gen "example.com/SMC/server/scripts/generator"
 )
 
@@ -31,18 +32,25 @@ func main() {
 	t1 := 2 // ComplaintDue = ClientShareDue + t1
 	t2 := 4 // MaskedShareDue = ClientShareDue + t2
 	t3 := 6 // ServerShareDue = ClientShareDue + t3
+	t4 := 5 // ReshareDue = ClientShareDue + t4, with products or noise
+
+	predicate := ""       //predicate of the client inputs, e.g. "bit"
+	var products [][2]int //pairs of inputs whose products are summed, e.g. {{0, 1}}, needs n > 3t
+	var noise *dp.Params  //noise of the sums, needs a predicate bounding the inputs
+	server_options := server_gen.Options{Predicate: predicate, Products: products, Noise: noise}
+	op_options := output_gen.Options{Predicate: predicate, Products: products, Noise: noise}
 
 	client_gen.GenerateClientConfig(n_client, "client_template.json", "./client_config")
 
-	client_gen.GenerateClientInput(n_client, n_exp, n_input, "./client_input")
+	client_gen.GenerateClientInput(n_client, n_exp, n_input, predicate, "./client_input")
 
 	server_gen.GenerateServerConfigLocal(n_server, server_port[:n_server], "server_template.json", "./server_config")
 
-	server_gen.GenerateServerInput(n_exp, clientShareDue, t1, t2, "http://127.0.0.1:60000/serverShare/", "./server_input")
+	server_gen.GenerateServerInput(n_exp, clientShareDue, t1, t2, t4, "http://127.0.0.1:60000/serverShare/", server_options, "./server_input")
 
 	output_gen.GenerateOPConfig(n_outputparty, op_port, "outputparty_template.json", "./op_config")
 
-	output_gen.GenerateOPInput(n_exp, clientShareDue, t3, "./op_input")
+	output_gen.GenerateOPInput(n_exp, clientShareDue, t3, op_options, "./op_input")
 
 	run(n_server, n_outputparty, n_client, n_client_mal)
 
@@ -6,6 +6,7 @@ import (
 	"log"
 
 	"example.com/SMC/outputparty/sqlstore"
+	"example.com/SMC/pkg/ligero"
 )
 
 type ServerService struct {
@@ -35,13 +36,13 @@ func (ss *ServerService) CreateServerShare(request AggregatedShareRequest) error
 
 	log.Printf("outputparty received server shares from %s\n", request.Server_ID)
 
-	shares, err := json.Marshal(request.Shares)
+	record, err := serverShare(request)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	//insert share to server share table
-	err = ss.store.InsertServerShare(request.Exp_ID, request.Server_ID, shares)
+	err = ss.store.InsertServerShare(record.Exp_ID, record.Server_ID, record.Shares, record.Products, record.Checks, record.Count, record.Reshared, record.Clients)
 	if err != nil {
 		return err
 	}
@@ -50,8 +51,73 @@ func (ss *ServerService) CreateServerShare(request AggregatedShareRequest) error
 
 }
 
+// serverShare is the record of the aggregated shares a server sent
+func serverShare(request AggregatedShareRequest) (sqlstore.ServerShare, error) {
+	shares, err := json.Marshal(request.Shares)
+	if err != nil {
+		return sqlstore.ServerShare{}, err
+	}
+	products, err := json.Marshal(request.Products)
+	if err != nil {
+		return sqlstore.ServerShare{}, err
+	}
+	checks, err := json.Marshal(request.Checks)
+	if err != nil {
+		return sqlstore.ServerShare{}, err
+	}
+	count, err := json.Marshal(request.Count)
+	if err != nil {
+		return sqlstore.ServerShare{}, err
+	}
+	reshared, err := json.Marshal(request.Reshared)
+	if err != nil {
+		return sqlstore.ServerShare{}, err
+	}
+
+	return sqlstore.ServerShare{
+		Exp_ID:    request.Exp_ID,
+		Server_ID: request.Server_ID,
+		Shares:    shares,
+		Products:  products,
+		Checks:    checks,
+		Count:     count,
+		Reshared:  string(reshared),
+		Clients:   request.Clients,
+	}, nil
+}
+
 func (e *ExperimentService) CreateExperiment(exp Experiment) error {
-	err := e.store.InsertExperiment(exp.Exp_ID, exp.ClientShareDue, exp.ServerShareDue)
+	_, err := ligero.ParsePredicate(exp.Predicate)
+	if err != nil {
+		return err
+	}
+
+	if exp.Frac_bits < 0 {
+		return errors.New("frac_bits cannot be negative")
+	}
+
+	for _, pair := range exp.Products {
+		if pair[0] < 0 || pair[1] < 0 {
+			return errors.New("product of a negative input")
+		}
+	}
+	products, err := json.Marshal(exp.Products)
+	if err != nil {
+		return err
+	}
+
+	if exp.Noise != nil {
+		err = exp.Noise.Validate()
+		if err != nil {
+			return err
+		}
+	}
+	noise, err := json.Marshal(exp.Noise)
+	if err != nil {
+		return err
+	}
+
+	err = e.store.InsertExperiment(exp.Exp_ID, exp.ClientShareDue, exp.ServerShareDue, exp.Predicate, exp.Frac_bits, string(products), string(noise))
 	if err != nil {
 		return err
 	}
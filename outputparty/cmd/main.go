@@ -9,8 +9,8 @@ import (
 	"time"
 
 	"example.com/SMC/outputparty/config"
+	"example.com/SMC/pkg/ligero"
 	"github.com/sirupsen/logrus"
-	"gonum.org/v1/gonum/stat/combin"
 )
 
 var logger *logrus.Logger
@@ -32,7 +32,7 @@ func main() {
 	}
 
 	conf := config.Load(*confpath)
-	p_sh := combin.Binomial(conf.N-1, conf.T) //total number of shares per secret stored by each server
+	p_sh := ligero.SharesPerServer(ligero.SharingShamir, conf.N, conf.T) //servers send one shamir share per secret
 	n_sh = p_sh * conf.N * conf.N_secrets
 
 	logger = logrus.New()
@@ -6,10 +6,13 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"sort"
 	"time"
 
 	"example.com/SMC/outputparty/config"
 	"example.com/SMC/outputparty/sqlstore"
+	"example.com/SMC/pkg/dp"
+	"example.com/SMC/pkg/ligero"
 	"example.com/SMC/pkg/rss"
 	"github.com/sirupsen/logrus"
 )
@@ -32,6 +35,8 @@ func (op *OutputParty) HandelExp(path string) {
 			"exp_id":           exp.Exp_ID,
 			"client_share_due": exp.ClientShareDue,
 			"server_share_due": exp.ServerShareDue,
+			"predicate":        exp.Predicate,
+			"noise":            exp.Noise,
 		}).Info("")
 
 		err := expService.CreateExperiment(exp)
@@ -45,9 +50,16 @@ func (op *OutputParty) HandelExp(path string) {
 func (op *OutputParty) serverRequestHandler(rw http.ResponseWriter, req *http.Request) {
 	var request AggregatedShareRequest
 
+	data, err := request.ReadJson(req)
+	if err != nil {
+		log.Printf("%s cannot read server request - error: %s\n", op.cfg.OutputParty_ID, err)
+		rw.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(rw, err)
+		return
+	}
+
 	serverService := NewServerService(op.store)
-	data := request.ReadJson(req)
-	err := serverService.CreateServerShare(data)
+	err = serverService.CreateServerShare(data)
 
 	if err != nil {
 		log.Printf("error: %s\n", err)
@@ -88,78 +100,388 @@ func (op *OutputParty) WaitForEndOfExperiment(ticker *time.Ticker) {
 					panic(err)
 				}
 
-				inputShares := make(map[int]map[string][]rss.Share)
-				for _, record := range list {
-					var shares Shares
-					err = json.Unmarshal(record.Shares, &shares)
-					if err != nil {
-						log.Printf("%s cannot unmarshall %s masked shares record\n", op.cfg.OutputParty_ID, record.Server_ID)
-						panic(err)
-					}
-
-					for input_index, sh_list := range shares.Values {
-						_, check1 := inputShares[input_index]
-						if !check1 {
-							inputShares[input_index] = make(map[string][]rss.Share)
-							inputShares[input_index][record.Server_ID] = make([]rss.Share, len(sh_list))
-
-						}
-						temp := make([]rss.Share, len(sh_list))
-						for idx, value := range sh_list {
-
-							temp[idx] = rss.Share{Index: shares.Index[idx], Value: value}
-						}
-						inputShares[input_index][record.Server_ID] = temp
-					}
-				}
+				expResult := op.reconstructExperiment(exp, list)
+				WriteResult(expResult)
 
-				// reconstruct sum of secrets
-				nrss, err := rss.NewReplicatedSecretSharing(op.cfg.N, op.cfg.T, op.cfg.Q)
+				err = op.store.UpdateCompletedExperiment(exp.Exp_ID) //set experiments to completed
 				if err != nil {
-					log.Println("NewReplicatedSecretSharing failes:", err)
+					log.Println("cannot set experiment to completed - error:", err)
 					panic(err)
 				}
 
-				result := make([]int, op.cfg.N_secrets)
-				for input_index, list := range inputShares {
-					size := len(list)
-					servers := make([][]rss.Share, size)
-					i := 0
-					for _, server_shares := range list {
-						servers[i] = server_shares
-						i++
-					}
+			}
 
-					sum, err := nrss.Reconstruct(servers)
-					if err != nil {
-						log.Println("cannot reconstruct:", err)
-						panic(err)
-					}
+		}
+	}
+}
 
-					result[input_index] = sum
+// reconstructExperiment reconstructs the sums of an experiment, and of its products and the number of clients, from
+// the records of the aggregated shares the servers sent
+func (op *OutputParty) reconstructExperiment(exp sqlstore.Experiment, list []sqlstore.ServerShare) ExpResult {
+	//servers that added up the resharings of other parties sent shares of other sums
+	var parties []int
+	if exp.Products != "" || exp.Noise != "" {
+		var ignored []string
+		list, parties, ignored = agreeing(list)
+		if len(ignored) > 0 {
+			logger.WithFields(logrus.Fields{
+				"exp_id":   exp.Exp_ID,
+				"reshared": parties,
+				"ignored":  ignored,
+			}).Warn("servers added up the resharings of other parties")
+		}
+	}
 
-				}
+	inputShares := make(map[int]map[string][]rss.Share)
+	productShares := make(map[int]map[string][]rss.Share)
+	checkShares := make(map[int]map[string][]rss.Share)
+	countShares := make(map[int]map[string][]rss.Share)
+	names := make(map[int]string)
+	for _, record := range list {
+		var shares Shares
+		err := json.Unmarshal(record.Shares, &shares)
+		if err != nil {
+			log.Printf("%s cannot unmarshall %s masked shares record\n", op.cfg.OutputParty_ID, record.Server_ID)
+			panic(err)
+		}
+		addServerShares(inputShares, record.Server_ID, shares)
+		names[shares.PartyIndex] = record.Server_ID
+
+		//servers without products send none
+		if len(record.Products) > 0 {
+			var products Shares
+			err = json.Unmarshal(record.Products, &products)
+			if err != nil {
+				log.Printf("%s cannot unmarshall %s product shares record\n", op.cfg.OutputParty_ID, record.Server_ID)
+				panic(err)
+			}
+			addServerShares(productShares, record.Server_ID, products)
+		}
+		if len(record.Checks) > 0 {
+			var checks Shares
+			err = json.Unmarshal(record.Checks, &checks)
+			if err != nil {
+				log.Printf("%s cannot unmarshall %s check shares record\n", op.cfg.OutputParty_ID, record.Server_ID)
+				panic(err)
+			}
+			addServerShares(checkShares, record.Server_ID, checks)
+		}
+		if len(record.Count) > 0 {
+			var count Shares
+			err = json.Unmarshal(record.Count, &count)
+			if err != nil {
+				log.Printf("%s cannot unmarshall %s count shares record\n", op.cfg.OutputParty_ID, record.Server_ID)
+				panic(err)
+			}
+			addServerShares(countShares, record.Server_ID, count)
+		}
+	}
 
-				reconstruction_start, _ := time.Parse("2006-01-02 15:04:05.999999999 +0000 UTC", exp.ServerShareDue)
-				reconstruction_end = time.Since(reconstruction_start)
+	// reconstruct sum of secrets
+	//servers convert their replicated shares, every server sends a single shamir share per input
+	nrss, err := ligero.NewSecretSharing(ligero.SharingShamir, op.cfg.N, op.cfg.T, op.cfg.Q, nil)
+	if err != nil {
+		log.Println("NewSecretSharing failes:", err)
+		panic(err)
+	}
 
-				logger.WithFields(logrus.Fields{
-					"exp_id": exp.Exp_ID,
-					"result": result,
-				}).Info("")
+	var pairs [][2]int
+	if exp.Products != "" {
+		err = json.Unmarshal([]byte(exp.Products), &pairs)
+		if err != nil {
+			log.Printf("cannot read products of %s\n", exp.Exp_ID)
+			panic(err)
+		}
+	}
+	var noise *dp.Params
+	if exp.Noise != "" {
+		err = json.Unmarshal([]byte(exp.Noise), &noise)
+		if err != nil {
+			log.Printf("cannot read noise of %s\n", exp.Exp_ID)
+			panic(err)
+		}
+	}
 
-				WriteResult(exp.Exp_ID, result)
+	expResult := ExpResult{Exp_ID: exp.Exp_ID, Predicate: exp.Predicate, Products: pairs, Noise: noise}
+	if noise == nil {
+		expResult.Clients = clients(list, op.cfg.T)
+	}
+	result, misbehaving, err := reconstruct(nrss, inputShares, op.cfg.N_secrets)
+	var product_results []int
+	if err == nil && len(pairs) > 0 {
+		var product_misbehaving []string
+		product_results, product_misbehaving, err = reconstruct(nrss, productShares, len(pairs))
+		if err != nil {
+			err = fmt.Errorf("products: %w", err)
+		}
+		misbehaving = union(misbehaving, product_misbehaving)
+	}
+	if err == nil && len(pairs) > 0 {
+		holders := resharedHolders(rss.Groups(op.cfg.N, op.cfg.T), parties)
+		var check_results []int
+		var check_misbehaving []string
+		check_results, check_misbehaving, err = reconstruct(nrss, checkShares, countChecks(holders, len(pairs)))
+		if err == nil {
+			err = verifyChecks(check_results, holders, len(pairs), names)
+		}
+		if err != nil {
+			err = fmt.Errorf("checks: %w", err)
+		}
+		misbehaving = union(misbehaving, check_misbehaving)
+	}
+	//with noise the servers send a share of the noised number of clients instead of the number
+	if err == nil && noise != nil {
+		var count []int
+		var count_misbehaving []string
+		count, count_misbehaving, err = reconstruct(nrss, countShares, 1)
+		if err != nil {
+			err = fmt.Errorf("clients: %w", err)
+		} else {
+			expResult.Clients = signed(count, op.cfg.Q)[0]
+		}
+		misbehaving = union(misbehaving, count_misbehaving)
+	}
+	expResult.Misbehaving = misbehaving
+	if len(misbehaving) > 0 {
+		logger.WithFields(logrus.Fields{
+			"exp_id":      exp.Exp_ID,
+			"misbehaving": misbehaving,
+		}).Warn("servers sent inconsistent shares")
+	}
 
-				err = op.store.UpdateCompletedExperiment(exp.Exp_ID) //set experiments to completed
-				if err != nil {
-					log.Println("cannot set experiment to completed - error:", err)
-					panic(err)
-				}
+	if err != nil {
+		//the experiment is completed without result, too many servers misbehaved
+		log.Printf("cannot reconstruct %s: %s\n", exp.Exp_ID, err)
+		expResult.Error = err.Error()
+	} else {
+		reconstruction_start, _ := time.Parse("2006-01-02 15:04:05.999999999 +0000 UTC", exp.ServerShareDue)
+		reconstruction_end = time.Since(reconstruction_start)
+
+		//noise can make sums negative
+		if noise != nil {
+			result = signed(result, op.cfg.Q)
+			product_results = signed(product_results, op.cfg.Q)
+		}
+
+		logger.WithFields(logrus.Fields{
+			"exp_id": exp.Exp_ID,
+			"result": result,
+		}).Info("")
 
+		expResult.Result = result
+		expResult.Product_results = product_results
+		//fixed-point results are signed and scaled by 2^frac_bits
+		if exp.Frac_bits > 0 {
+			expResult.Values = ligero.DecodeFixedPoint(result, exp.Frac_bits, op.cfg.Q)
+		}
+		//products of fixed-point inputs are scaled by 2^(2 frac_bits)
+		if exp.Frac_bits > 0 && len(product_results) > 0 {
+			expResult.Product_values = ligero.DecodeFixedPoint(product_results, 2*exp.Frac_bits, op.cfg.Q)
+		}
+	}
+
+	return expResult
+}
+
+// reconstruct reconstructs the sum of each input from the aggregated shares of the servers and returns it with
+// the sorted ids of the servers whose shares were inconsistent
+func reconstruct(sharing ligero.SecretSharing, inputShares map[int]map[string][]rss.Share, n_secrets int) ([]int, []string, error) {
+	result := make([]int, n_secrets)
+	misbehaving := make(map[string]bool)
+	var err error
+	for input_index, list := range inputShares {
+		if input_index < 0 || input_index >= n_secrets {
+			err = fmt.Errorf("input %d out of range", input_index)
+			break
+		}
+
+		servers := make([][]rss.Share, 0, len(list))
+		ids := make([]string, 0, len(list))
+		for server_id, server_shares := range list {
+			servers = append(servers, server_shares)
+			ids = append(ids, server_id)
+		}
+
+		sum, cheaters, e := sharing.RobustReconstruct(servers)
+		if e != nil {
+			err = fmt.Errorf("input %d: %w", input_index, e)
+			break
+		}
+		for _, i := range cheaters {
+			misbehaving[ids[i]] = true
+		}
+		result[input_index] = sum
+	}
+
+	//every sum has to be reconstructed, a sum without shares is not 0
+	for input_index := 0; err == nil && input_index < n_secrets; input_index++ {
+		if _, ok := inputShares[input_index]; !ok {
+			err = fmt.Errorf("no shares of input %d", input_index)
+		}
+	}
+
+	ids := make([]string, 0, len(misbehaving))
+	for id := range misbehaving {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return result, ids, err
+}
+
+// clients returns the number of valid clients reported by most servers, 0 unless more than t servers report it
+func clients(list []sqlstore.ServerShare, t int) int {
+	counts := make(map[int]int)
+	result, max := 0, 0
+	for _, record := range list {
+		counts[record.Clients]++
+		if counts[record.Clients] > max {
+			result, max = record.Clients, counts[record.Clients]
+		}
+	}
+	if max <= t {
+		return 0
+	}
+	return result
+}
+
+// signed maps the values above q/2 to negative values
+func signed(values []int, q int) []int {
+	result := make([]int, len(values))
+	for i, value := range values {
+		result[i] = value
+		if value > q/2 {
+			result[i] = value - q
+		}
+	}
+	return result
+}
+
+// agreeing returns the records of the servers that added up the resharings of the same parties as most servers,
+// the larger set of parties on a tie, with the parties and the sorted ids of the servers left out
+func agreeing(list []sqlstore.ServerShare) ([]sqlstore.ServerShare, []int, []string) {
+	counts := make(map[string]int)
+	for _, record := range list {
+		counts[record.Reshared]++
+	}
+	best, best_parties := "", []int(nil)
+	for key, count := range counts {
+		var parties []int
+		if json.Unmarshal([]byte(key), &parties) != nil {
+			continue
+		}
+		if best != "" {
+			if count < counts[best] || count == counts[best] && len(parties) < len(best_parties) {
+				continue
+			}
+			if count == counts[best] && len(parties) == len(best_parties) && key > best {
+				continue
+			}
+		}
+		best, best_parties = key, parties
+	}
+
+	var result []sqlstore.ServerShare
+	var ignored []string
+	for _, record := range list {
+		if record.Reshared == best {
+			result = append(result, record)
+		} else {
+			ignored = append(ignored, record.Server_ID)
+		}
+	}
+	sort.Strings(ignored)
+	return result, best_parties, ignored
+}
+
+// resharedHolders returns for every group the parties of the group whose resharings were added up, in the order the
+// servers compute the checks
+func resharedHolders(groups [][]int, parties []int) [][]int {
+	reshared := make(map[int]bool, len(parties))
+	for _, p := range parties {
+		reshared[p] = true
+	}
+	result := make([][]int, len(groups))
+	for g, group := range groups {
+		for _, p := range group {
+			if reshared[p] {
+				result[g] = append(result[g], p)
 			}
+		}
+	}
+	return result
+}
+
+// countChecks returns the number of checks of the servers, one per pair for every holder of a group but the first
+func countChecks(holders [][]int, n_pairs int) int {
+	count := 0
+	for _, group := range holders {
+		if len(group) > 1 {
+			count += (len(group) - 1) * n_pairs
+		}
+	}
+	return count
+}
 
+// verifyChecks fails unless every check is 0, i.e. all holders of a group reshared the same sums of the products,
+// naming the servers of the parties that did not
+func verifyChecks(checks []int, holders [][]int, n_pairs int, names map[int]string) error {
+	if len(checks) != countChecks(holders, n_pairs) {
+		return fmt.Errorf("expected %d checks, got %d", countChecks(holders, n_pairs), len(checks))
+	}
+	k := 0
+	for _, group := range holders {
+		if len(group) == 0 {
+			continue
 		}
+		for _, p := range group[1:] {
+			for pair := 0; pair < n_pairs; pair++ {
+				if checks[k] != 0 {
+					return fmt.Errorf("%s and %s reshared different sums of product %d", name(names, group[0]), name(names, p), pair)
+				}
+				k++
+			}
+		}
+	}
+	return nil
+}
+
+// name returns the id of the server of a party
+func name(names map[int]string, party int) string {
+	if id, ok := names[party]; ok {
+		return id
+	}
+	return fmt.Sprintf("party %d", party)
+}
+
+// addServerShares adds the shares of every input sent by a server to the <input_index, server_shares> map
+func addServerShares(inputShares map[int]map[string][]rss.Share, server_id string, shares Shares) {
+	for input_index, sh_list := range shares.Values {
+		_, check1 := inputShares[input_index]
+		if !check1 {
+			inputShares[input_index] = make(map[string][]rss.Share)
+		}
+		temp := make([]rss.Share, 0, len(sh_list))
+		for idx, value := range sh_list {
+			if idx < len(shares.Index) {
+				temp = append(temp, rss.Share{Index: shares.Index[idx], Value: value})
+			}
+		}
+		inputShares[input_index][server_id] = temp
+	}
+}
+
+// union returns the sorted ids of the servers in a or b
+func union(a, b []string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	for _, id := range append(append([]string{}, a...), b...) {
+		seen[id] = true
+	}
+	ids := make([]string, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
 	}
+	sort.Strings(ids)
+	return ids
 }
 
 func (op *OutputParty) Start() {
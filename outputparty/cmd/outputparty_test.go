@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+
+	"example.com/SMC/outputparty/config"
+	"example.com/SMC/outputparty/sqlstore"
+	"example.com/SMC/pkg/ligero"
+	"example.com/SMC/pkg/rss"
+	"github.com/sirupsen/logrus"
+)
+
+func TestReconstruct(t *testing.T) {
+	sharing, err := ligero.NewSecretSharing(ligero.SharingShamir, 4, 1, 41, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// shares of the servers of the sums 3 and 5
+	inputShares := make(map[int]map[string][]rss.Share)
+	for k, secret := range []int{3, 5} {
+		_, parties, err := sharing.Split(secret)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		inputShares[k] = make(map[string][]rss.Share)
+		for j, shares := range parties {
+			inputShares[k][fmt.Sprintf("s%d", j+1)] = shares
+		}
+	}
+
+	result, misbehaving, err := reconstruct(sharing, inputShares, 2)
+	if err != nil || !reflect.DeepEqual(result, []int{3, 5}) || len(misbehaving) != 0 {
+		t.Fatalf("expected [3 5], but got %v %v (%v)", result, misbehaving, err)
+	}
+
+	// a sum without shares, e.g. of products the servers did not send, is an error instead of 0
+	_, _, err = reconstruct(sharing, inputShares, 3)
+	if err == nil {
+		t.Fatalf("reconstruction succeeded without shares of a sum")
+	}
+	_, _, err = reconstruct(sharing, map[int]map[string][]rss.Share{}, 1)
+	if err == nil {
+		t.Fatalf("reconstruction succeeded without shares")
+	}
+}
+
+func TestAgreeing(t *testing.T) {
+	list := []sqlstore.ServerShare{
+		{Server_ID: "s1", Reshared: "[0,1,2,3]"},
+		{Server_ID: "s2", Reshared: "[0,1,2]"},
+		{Server_ID: "s3", Reshared: "[0,1,2,3]"},
+		{Server_ID: "s4", Reshared: "[0,1,2]"},
+		{Server_ID: "s5", Reshared: "[0,1,2,3]"},
+	}
+	result, parties, ignored := agreeing(list)
+	if len(result) != 3 || !reflect.DeepEqual(parties, []int{0, 1, 2, 3}) || !reflect.DeepEqual(ignored, []string{"s2", "s4"}) {
+		t.Fatalf("expected 3 servers of [0 1 2 3], but got %v %v ignoring %v", result, parties, ignored)
+	}
+
+	// on a tie the servers that added up more resharings are taken
+	result, parties, ignored = agreeing(list[:4])
+	if len(result) != 2 || !reflect.DeepEqual(parties, []int{0, 1, 2, 3}) || !reflect.DeepEqual(ignored, []string{"s2", "s4"}) {
+		t.Fatalf("expected 2 servers of [0 1 2 3], but got %v %v ignoring %v", result, parties, ignored)
+	}
+}
+
+func TestVerifyChecks(t *testing.T) {
+	// 4 servers with t = 1 form 4 groups of 3 and 6 groups of 2, without server 3 the groups have 8 holders
+	// besides the first
+	holders := resharedHolders(rss.Groups(4, 1), []int{0, 1, 2})
+	n_checks := countChecks(holders, 2)
+	if n_checks != 8*2 {
+		t.Fatalf("expected 16 checks, got %d", n_checks)
+	}
+
+	checks := make([]int, n_checks)
+	if err := verifyChecks(checks, holders, 2, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := verifyChecks(checks[1:], holders, 2, nil); err == nil {
+		t.Fatalf("checks verified with a check missing")
+	}
+	checks[n_checks-1] = 1
+	err := verifyChecks(checks, holders, 2, map[int]string{1: "s2", 2: "s3"})
+	if err == nil || err.Error() != "s2 and s3 reshared different sums of product 1" {
+		t.Fatalf("checks verified with a difference of s2 and s3: %v", err)
+	}
+}
+
+func TestAggregatedSharesOfServers(t *testing.T) {
+	// the requests the servers build and serialize in their TestAggregatedShareRequest
+	data, err := os.ReadFile("../../server/cmd/testdata/aggregated_shares.json")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var bodies []json.RawMessage
+	err = json.Unmarshal(data, &bodies)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	var list []sqlstore.ServerShare
+	for _, body := range bodies {
+		var compressed bytes.Buffer
+		writer := gzip.NewWriter(&compressed)
+		writer.Write(body)
+		writer.Close()
+
+		var request AggregatedShareRequest
+		data, err := request.ReadJson(httptest.NewRequest(http.MethodPost, "/server/shares", &compressed))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		record, err := serverShare(data)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		list = append(list, record)
+	}
+
+	logger = logrus.New()
+	logger.SetOutput(io.Discard)
+	op := &OutputParty{cfg: &config.OutputParty{OutputParty_ID: "op", N: 4, T: 1, N_secrets: 2, Q: 41}}
+	exp := sqlstore.Experiment{Exp_ID: "exp1", Products: "[[0,1]]", Noise: `{"Mechanism":"geometric","Epsilon":1,"Sensitivity":1}`}
+	result := op.reconstructExperiment(exp, list)
+
+	// the sums 2 and 2 with the noise -1 and 2, the products 1 with the noise 1 and the 3 clients with the noise -2
+	if result.Error != "" || len(result.Misbehaving) != 0 {
+		t.Fatalf("expected a result, but got %q from %v", result.Error, result.Misbehaving)
+	}
+	if !reflect.DeepEqual(result.Result, []int{1, 4}) || !reflect.DeepEqual(result.Product_results, []int{2}) || result.Clients != 1 {
+		t.Fatalf("expected [1 4], [2] and 1 client, but got %v, %v and %d clients", result.Result, result.Product_results, result.Clients)
+	}
+}
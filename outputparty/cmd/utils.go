@@ -3,9 +3,13 @@ package main
 import (
 	"compress/gzip"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
+
+	"example.com/SMC/pkg/dp"
+	"example.com/SMC/pkg/ligero"
 )
 
 type AggregatedShareRequest struct {
@@ -13,11 +17,17 @@ type AggregatedShareRequest struct {
 	Server_ID string `json:"Server_ID"`
 	Timestamp string `json:"Timestamp"`
 	Shares    Shares `json:"Shares"`
+	Products  Shares `json:"Products"`
+	Checks    Shares `json:"Checks"`
+	Count     Shares `json:"Count"`
+	Reshared  []int  `json:"Reshared"`
+	Clients   int    `json:"Clients"`
 }
 
 type Shares struct {
-	Index  []int   `json:"Index"`
-	Values [][]int `json:"Values"`
+	Index      []int   `json:"Index"`
+	Values     [][]int `json:"Values"`
+	PartyIndex int     `json:"PartyIndex"`
 }
 
 type OutputPartyRequest struct {
@@ -30,11 +40,32 @@ type Experiment struct {
 	Exp_ID         string
 	ClientShareDue string
 	ServerShareDue string
+	Predicate      string
+	Frac_bits      int
+	Products       [][2]int   //pairs of inputs whose products are summed
+	Noise          *dp.Params //noise the servers add to the sums, none if nil
 }
 
 type ExpResult struct {
-	Exp_ID string `json:"Exp_ID"`
-	Result []int  `json:"Result"`
+	Exp_ID          string             `json:"Exp_ID"`
+	Predicate       string             `json:"Predicate"`
+	Result          []int              `json:"Result"`
+	Values          []float64          `json:"Values,omitempty"`
+	Products        [][2]int           `json:"Products,omitempty"`
+	Product_results []int              `json:"Product_results,omitempty"` //sums of the products of the pairs of inputs
+	Product_values  []float64          `json:"Product_values,omitempty"`
+	Clients         int                `json:"Clients,omitempty"` //number of valid clients reported by a majority of servers, noised with noise
+	Noise           *dp.Params         `json:"Noise,omitempty"`   //differential privacy of the sums, which are signed with noise
+	Tables          []ContingencyTable `json:"Tables,omitempty"`
+	Misbehaving     []string           `json:"Misbehaving,omitempty"` //servers whose shares were inconsistent
+	Error           string             `json:"Error,omitempty"`
+}
+
+// ContingencyTable counts the clients by two 0/1 attributes, Counts[i][j] clients have the first attribute i
+// and the second attribute j
+type ContingencyTable struct {
+	Attributes [2]int    `json:"Attributes"`
+	Counts     [2][2]int `json:"Counts"`
 }
 
 func (op *OutputPartyRequest) ToJson() []byte {
@@ -52,11 +83,11 @@ func (op *OutputPartyRequest) ToJson() []byte {
 	return message
 }
 
-func (s *AggregatedShareRequest) ReadJson(req *http.Request) AggregatedShareRequest {
+func (s *AggregatedShareRequest) ReadJson(req *http.Request) (AggregatedShareRequest, error) {
 	// Decompress the data using Gzip
 	gzipReader, err := gzip.NewReader(req.Body)
 	if err != nil {
-		log.Fatalf("Cannot decompress server request: %s", err)
+		return AggregatedShareRequest{}, fmt.Errorf("cannot decompress server request: %w", err)
 	}
 	defer gzipReader.Close()
 
@@ -65,10 +96,10 @@ func (s *AggregatedShareRequest) ReadJson(req *http.Request) AggregatedShareRequ
 	var t AggregatedShareRequest
 	err = decoder.Decode(&t)
 	if err != nil {
-		log.Fatalf("Cannot decode aggregated share request: %s", err)
+		return AggregatedShareRequest{}, fmt.Errorf("cannot decode aggregated share request: %w", err)
 	}
 
-	return t
+	return t, nil
 }
 
 func readDataFromFile(filename string) ([]ExpResult, error) {
@@ -105,12 +136,9 @@ func appendDataToFile(filename string, data []ExpResult) error {
 	return nil
 }
 
-// write reconstructed result to the file
-func WriteResult(id string, result []int) {
-	expResult := ExpResult{
-		Exp_ID: id,
-		Result: result,
-	}
+// write reconstructed result to the file, with the contingency tables of the products in the predicate
+func WriteResult(expResult ExpResult) {
+	expResult.Tables = contingencyTables(expResult)
 
 	// Read existing data
 	existingData, err := readDataFromFile("result.json")
@@ -128,6 +156,31 @@ func WriteResult(id string, result []int) {
 
 }
 
+// contingencyTables returns the table of each product predicate of an experiment from the sums of the attributes,
+// the sum of their product bits and the number of clients
+func contingencyTables(expResult ExpResult) []ContingencyTable {
+	pred, err := ligero.ParsePredicate(expResult.Predicate)
+	if err != nil {
+		return nil
+	}
+
+	var tables []ContingencyTable
+	for _, p := range ligero.Products(pred) {
+		a, b, c := p[0], p[1], p[2]
+		if a >= len(expResult.Result) || b >= len(expResult.Result) || c >= len(expResult.Result) {
+			continue
+		}
+		both := expResult.Result[c]
+		table := ContingencyTable{Attributes: [2]int{a, b}}
+		table.Counts[1][1] = both
+		table.Counts[1][0] = expResult.Result[a] - both
+		table.Counts[0][1] = expResult.Result[b] - both
+		table.Counts[0][0] = expResult.Clients - expResult.Result[a] - expResult.Result[b] + both
+		tables = append(tables, table)
+	}
+	return tables
+}
+
 func ReadOutputPartyInput(path string) []Experiment {
 	jsonData, err := os.ReadFile(path)
 	if err != nil {
@@ -141,6 +194,13 @@ func ReadOutputPartyInput(path string) []Experiment {
 		log.Fatalf("%s", err)
 		return nil
 	}
+
+	//experiments without predicate collect 0/1 inputs
+	for i := range items {
+		if items[i].Predicate == "" {
+			items[i].Predicate = ligero.BitPredicate().String()
+		}
+	}
 	return items
 
 }
@@ -7,15 +7,27 @@ import (
 	"path/filepath"
 	"strconv"
 	"time"
+
+	"example.com/SMC/pkg/dp"
 )
 
 type Experiment struct {
-	Exp_ID         string `json:"Exp_ID"`
-	ClientShareDue string `json:"ClientShareDue"`
-	ServerShareDue string `json:"ServerShareDue"`
+	Exp_ID         string     `json:"Exp_ID"`
+	ClientShareDue string     `json:"ClientShareDue"`
+	ServerShareDue string     `json:"ServerShareDue"`
+	Predicate      string     `json:"Predicate,omitempty"`
+	Products       [][2]int   `json:"Products,omitempty"`
+	Noise          *dp.Params `json:"Noise,omitempty"`
+}
+
+// Options are the optional fields of the generated experiments, the same for every experiment and for the servers
+type Options struct {
+	Predicate string
+	Products  [][2]int
+	Noise     *dp.Params
 }
 
-func GenerateOPInput(exp_num int, start_time time.Time, t int, des string) {
+func GenerateOPInput(exp_num int, start_time time.Time, t int, options Options, des string) {
 	// Ensure the folder exists
 	err := os.MkdirAll(des, os.ModePerm)
 	if err != nil {
@@ -34,6 +46,9 @@ func GenerateOPInput(exp_num int, start_time time.Time, t int, des string) {
 			Exp_ID:         expID,
 			ClientShareDue: client_share_due.String(),
 			ServerShareDue: server_share_due,
+			Predicate:      options.Predicate,
+			Products:       options.Products,
+			Noise:          options.Noise,
 		}
 
 		dataList = append(dataList, data)
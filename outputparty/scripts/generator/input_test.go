@@ -5,8 +5,14 @@ import (
 	"time"
 
 	"example.com/SMC/outputparty/scripts/generator"
+	"example.com/SMC/pkg/dp"
 )
 
 func TestGenerateServerInput(t *testing.T) {
-	generator.GenerateOPInput(2, time.Now(), 8, "./input")
+	generator.GenerateOPInput(2, time.Now(), 8, generator.Options{}, "./input")
+	generator.GenerateOPInput(1, time.Now(), 8, generator.Options{
+		Predicate: "bit",
+		Products:  [][2]int{{0, 1}},
+		Noise:     &dp.Params{Mechanism: dp.MechanismLaplace, Epsilon: 0.5, Sensitivity: 4},
+	}, "./input")
 }
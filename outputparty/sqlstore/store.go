@@ -55,11 +55,16 @@ func SetupDatabase(sid string) (*gorm.DB, error) {
 }
 
 // create server sumShare record in the server table
-func (db *DB) InsertServerShare(exp_id, server_id string, shares []byte) error {
+func (db *DB) InsertServerShare(exp_id, server_id string, shares, products, checks, count []byte, reshared string, clients int) error {
 	s := ServerShare{
 		Exp_ID:    exp_id,
 		Server_ID: server_id,
 		Shares:    shares,
+		Products:  products,
+		Checks:    checks,
+		Count:     count,
+		Reshared:  reshared,
+		Clients:   clients,
 	}
 	result := db.db.Create(&s)
 	if result.Error != nil {
@@ -97,11 +102,15 @@ func (db *DB) CountSharesPerExperiment(exp_id string) int64 {
 }
 
 // create experiment record in the experiment tables
-func (db *DB) InsertExperiment(exp_id, due1, due2 string) error {
+func (db *DB) InsertExperiment(exp_id, due1, due2, predicate string, frac_bits int, products, noise string) error {
 	exp := &Experiment{
 		Exp_ID:         exp_id,
 		ClientShareDue: due1,
 		ServerShareDue: due2,
+		Predicate:      predicate,
+		Frac_bits:      frac_bits,
+		Products:       products,
+		Noise:          noise,
 		Completed:      false,
 	}
 	result := db.db.Create(&exp)
@@ -10,7 +10,7 @@ func TestInsertServerShare(t *testing.T) {
 
 	//create a new server for experiment 1
 	shares, _ := json.Marshal([][]int{{1, 2, 3}, {4, 5, 6}, {7, 8, 9}})
-	err := db.InsertServerShare("exp1", "s1", shares)
+	err := db.InsertServerShare("exp1", "s1", shares, nil, nil, nil, "", 0)
 	if err != nil {
 		t.Log(err)
 	} else {
@@ -26,13 +26,13 @@ func TestInsertServerShare(t *testing.T) {
 	}
 
 	// create same server for experiment 1
-	err = db.InsertServerShare("exp1", "s1", shares)
+	err = db.InsertServerShare("exp1", "s1", shares, nil, nil, nil, "", 0)
 	if err != nil {
 		t.Log(err)
 	}
 
 	// create second client for experiment 1
-	err = db.InsertServerShare("exp1", "s2", shares)
+	err = db.InsertServerShare("exp1", "s2", shares, nil, nil, nil, "", 0)
 	if err != nil {
 		t.Fatal(err)
 	} else {
@@ -48,7 +48,7 @@ func TestInsertServerShare(t *testing.T) {
 	}
 
 	// create new client for experiment 2
-	err = db.InsertServerShare("exp2", "s2", shares)
+	err = db.InsertServerShare("exp2", "s2", shares, nil, nil, nil, "", 0)
 	if err != nil {
 		t.Fatal(err)
 	} else {
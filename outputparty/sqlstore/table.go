@@ -4,6 +4,10 @@ type Experiment struct {
 	Exp_ID         string `gorm:"primaryKey"`
 	ClientShareDue string
 	ServerShareDue string
+	Predicate      string //validity predicate of client inputs
+	Frac_bits      int    //fractional bits of fixed-point inputs
+	Products       string //json pairs of inputs whose products are summed
+	Noise          string //json parameters of the noise the servers add to the sums
 	Completed      bool
 }
 
@@ -11,4 +15,9 @@ type ServerShare struct {
 	Exp_ID    string `gorm:"primaryKey"`
 	Server_ID string `gorm:"primaryKey"`
 	Shares    []byte `gorm:"type:longblob"`
+	Products  []byte `gorm:"type:longblob"`
+	Checks    []byte `gorm:"type:longblob"` //differences of the reshared product sums, all 0
+	Count     []byte `gorm:"type:longblob"` //share of the noised number of valid clients, with noise
+	Reshared  string //parties whose resharings the server added up
+	Clients   int    //number of valid clients reported by the server
 }
@@ -0,0 +1,230 @@
+// Package dp samples the discrete noise the servers add to the sums of an experiment for differential privacy.
+// Every server adds noise of its own to its shares, so that no server knows the total noise, and the noise of
+// any n-t servers adds up to the noise of the mechanism.
+package dp
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Mechanisms the noise is drawn with
+const (
+	MechanismLaplace  = "laplace"  // discrete laplace noise, pure epsilon-differential privacy
+	MechanismGaussian = "gaussian" // discrete gaussian noise, (epsilon, delta)-differential privacy
+)
+
+// poisson_chunk bounds the mean of the poisson samples drawn at once, exp(-mean) must not underflow
+const poisson_chunk = 30.0
+
+// Params are the privacy parameters of the sums of an experiment. Sensitivity bounds the change of the sums by a
+// single client, in the L1 norm for the laplace and in the L2 norm for the gaussian mechanism.
+type Params struct {
+	Mechanism   string  `json:"Mechanism"`
+	Epsilon     float64 `json:"Epsilon"`
+	Delta       float64 `json:"Delta,omitempty"`
+	Sensitivity float64 `json:"Sensitivity"`
+}
+
+// Validate checks that the parameters give differential privacy with the mechanism
+func (p Params) Validate() error {
+	if !(p.Epsilon > 0) || math.IsInf(p.Epsilon, 0) {
+		return fmt.Errorf("%w: epsilon has to be positive, got %v", ErrInvalidParams, p.Epsilon)
+	}
+	if !(p.Sensitivity > 0) || math.IsInf(p.Sensitivity, 0) {
+		return fmt.Errorf("%w: sensitivity has to be positive, got %v", ErrInvalidParams, p.Sensitivity)
+	}
+
+	switch p.Mechanism {
+	case MechanismLaplace:
+		if p.Delta != 0 {
+			return fmt.Errorf("%w: the laplace mechanism has delta 0, got %v", ErrInvalidParams, p.Delta)
+		}
+	case MechanismGaussian:
+		if !(p.Delta > 0 && p.Delta < 1) {
+			return fmt.Errorf("%w: delta has to be in (0, 1), got %v", ErrInvalidParams, p.Delta)
+		}
+		// the bound on sigma of Scale only holds for epsilon < 1
+		if p.Epsilon >= 1 {
+			return fmt.Errorf("%w: the gaussian mechanism needs epsilon < 1, got %v", ErrInvalidParams, p.Epsilon)
+		}
+	default:
+		return fmt.Errorf("%w: unknown mechanism %q", ErrInvalidParams, p.Mechanism)
+	}
+	return nil
+}
+
+// Scale returns the scale b of the discrete laplace noise, with probabilities proportional to exp(-|x|/b), or the
+// sigma of the discrete gaussian noise, with probabilities proportional to exp(-x^2/(2 sigma^2)). The discrete
+// gaussian is at least as private as the continuous one of the same sigma (Canonne, Kamath and Steinke, 2020).
+func (p Params) Scale() float64 {
+	if p.Mechanism == MechanismGaussian {
+		return p.Sensitivity * math.Sqrt(2*math.Log(1.25/p.Delta)) / p.Epsilon
+	}
+	return p.Sensitivity / p.Epsilon
+}
+
+// Sample returns the noise of one of n servers, t of which may collude, drawing the randomness from r or from
+// crypto/rand if r is nil. The laplace noises of any n-t servers add up to discrete laplace noise of scale b:
+// each server draws the difference of two Polya variables with 1/(n-t) of the shape of the geometric ones. The
+// gaussian noises of any n-t servers have a variance of sigma^2 and add up to nearly discrete gaussian noise.
+// The noise of all servers has n/(n-t) times the variance.
+func (p Params) Sample(n, t int, r io.Reader) (int, error) {
+	if err := p.Validate(); err != nil {
+		return 0, err
+	}
+	if t < 0 || t >= n {
+		return 0, fmt.Errorf("%w: %d of %d servers cannot collude", ErrInvalidParams, t, n)
+	}
+	if r == nil {
+		r = rand.Reader
+	}
+	s := &sampler{rand: r}
+	honest := float64(n - t)
+
+	if p.Mechanism == MechanismGaussian {
+		return s.gaussian(p.Scale() / math.Sqrt(honest))
+	}
+
+	x, err := s.polya(1/honest, math.Exp(-1/p.Scale()))
+	if err != nil {
+		return 0, err
+	}
+	y, err := s.polya(1/honest, math.Exp(-1/p.Scale()))
+	if err != nil {
+		return 0, err
+	}
+	return x - y, nil
+}
+
+type sampler struct {
+	rand io.Reader
+}
+
+// uniform returns a uniform value in (0, 1] with 53 random bits
+func (s *sampler) uniform() (float64, error) {
+	var b [8]byte
+	_, err := io.ReadFull(s.rand, b[:])
+	if err != nil {
+		return 0, err
+	}
+	return float64(binary.LittleEndian.Uint64(b[:])>>11+1) / (1 << 53), nil
+}
+
+// normal returns a standard normal value (Box-Muller)
+func (s *sampler) normal() (float64, error) {
+	u, err := s.uniform()
+	if err != nil {
+		return 0, err
+	}
+	v, err := s.uniform()
+	if err != nil {
+		return 0, err
+	}
+	return math.Sqrt(-2*math.Log(u)) * math.Cos(2*math.Pi*v), nil
+}
+
+// geometric returns the number of failures before the first success, failing with probability q
+func (s *sampler) geometric(q float64) (int, error) {
+	u, err := s.uniform()
+	if err != nil {
+		return 0, err
+	}
+	return int(math.Floor(math.Log(u) / math.Log(q))), nil
+}
+
+// gamma returns a gamma value of the given shape and scale 1 (Marsaglia and Tsang, 2000)
+func (s *sampler) gamma(shape float64) (float64, error) {
+	if shape < 1 {
+		g, err := s.gamma(shape + 1)
+		if err != nil {
+			return 0, err
+		}
+		u, err := s.uniform()
+		if err != nil {
+			return 0, err
+		}
+		return g * math.Pow(u, 1/shape), nil
+	}
+
+	d := shape - 1.0/3
+	c := 1 / math.Sqrt(9*d)
+	for {
+		x, err := s.normal()
+		if err != nil {
+			return 0, err
+		}
+		v := 1 + c*x
+		if v <= 0 {
+			continue
+		}
+		v = v * v * v
+		u, err := s.uniform()
+		if err != nil {
+			return 0, err
+		}
+		if math.Log(u) < x*x/2+d-d*v+d*math.Log(v) {
+			return d * v, nil
+		}
+	}
+}
+
+// poisson returns a poisson value of the given mean, adding up samples of means of at most poisson_chunk (Knuth)
+func (s *sampler) poisson(mean float64) (int, error) {
+	k := 0
+	for mean > 0 {
+		chunk := math.Min(mean, poisson_chunk)
+		mean -= chunk
+		limit := math.Exp(-chunk)
+		for prod := 1.0; ; k++ {
+			u, err := s.uniform()
+			if err != nil {
+				return 0, err
+			}
+			prod *= u
+			if prod <= limit {
+				break
+			}
+		}
+	}
+	return k, nil
+}
+
+// polya returns a negative binomial value of real shape r and failure probability q, a poisson value whose mean
+// is gamma distributed. The sum of k such values of shape 1/k is geometric.
+func (s *sampler) polya(r, q float64) (int, error) {
+	g, err := s.gamma(r)
+	if err != nil {
+		return 0, err
+	}
+	return s.poisson(g * q / (1 - q))
+}
+
+// gaussian returns a discrete gaussian value, rejecting discrete laplace values (Canonne, Kamath and Steinke, 2020)
+func (s *sampler) gaussian(sigma float64) (int, error) {
+	b := math.Floor(sigma) + 1
+	q := math.Exp(-1 / b)
+	for {
+		x, err := s.geometric(q)
+		if err != nil {
+			return 0, err
+		}
+		y, err := s.geometric(q)
+		if err != nil {
+			return 0, err
+		}
+		lap := x - y
+
+		u, err := s.uniform()
+		if err != nil {
+			return 0, err
+		}
+		d := math.Abs(float64(lap)) - sigma*sigma/b
+		if u <= math.Exp(-d*d/(2*sigma*sigma)) {
+			return lap, nil
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package dp
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestSample(t *testing.T) {
+	for _, p := range []Params{
+		{Mechanism: MechanismLaplace, Epsilon: 0.5, Sensitivity: 2},
+		{Mechanism: MechanismGaussian, Epsilon: 0.5, Delta: 1e-6, Sensitivity: 1},
+	} {
+		// the noises of n-t = 3 servers add up to the noise of the mechanism
+		n, colluding := 4, 1
+		r := rand.New(rand.NewSource(1))
+		samples := 20000
+		sum, squares := 0.0, 0.0
+		for i := 0; i < samples; i++ {
+			noise := 0
+			for j := 0; j < n-colluding; j++ {
+				x, err := p.Sample(n, colluding, r)
+				if err != nil {
+					t.Fatalf("err: %v", err)
+				}
+				noise += x
+			}
+			sum += float64(noise)
+			squares += float64(noise * noise)
+		}
+		mean := sum / float64(samples)
+		variance := squares/float64(samples) - mean*mean
+
+		// discrete laplace noise has the variance 2q/(1-q)^2 with q = exp(-1/b), discrete gaussian noise about sigma^2
+		expected := p.Scale() * p.Scale()
+		if p.Mechanism == MechanismLaplace {
+			q := math.Exp(-1 / p.Scale())
+			expected = 2 * q / ((1 - q) * (1 - q))
+		}
+		if math.Abs(variance-expected) > 0.05*expected {
+			t.Fatalf("%s: variance %.1f, expected %.1f", p.Mechanism, variance, expected)
+		}
+		if math.Abs(mean) > 4*math.Sqrt(expected/float64(samples)) {
+			t.Fatalf("%s: mean %.2f, expected 0", p.Mechanism, mean)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	for _, p := range []Params{
+		{Mechanism: MechanismLaplace, Epsilon: 0, Sensitivity: 1},
+		{Mechanism: MechanismLaplace, Epsilon: 1, Sensitivity: 0},
+		{Mechanism: MechanismLaplace, Epsilon: 1, Delta: 0.1, Sensitivity: 1},
+		{Mechanism: MechanismGaussian, Epsilon: 0.5, Sensitivity: 1},
+		{Mechanism: MechanismGaussian, Epsilon: 2, Delta: 1e-6, Sensitivity: 1},
+		{Mechanism: "uniform", Epsilon: 1, Sensitivity: 1},
+		{Mechanism: MechanismLaplace, Epsilon: math.NaN(), Sensitivity: 1},
+	} {
+		err := p.Validate()
+		if !errors.Is(err, ErrInvalidParams) {
+			t.Fatalf("expected ErrInvalidParams for %+v, got %v", p, err)
+		}
+	}
+
+	p := Params{Mechanism: MechanismLaplace, Epsilon: 1, Sensitivity: 1}
+	_, err := p.Sample(3, 3, nil)
+	if !errors.Is(err, ErrInvalidParams) {
+		t.Fatalf("expected ErrInvalidParams for t = n, got %v", err)
+	}
+}
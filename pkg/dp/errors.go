@@ -0,0 +1,9 @@
+package dp
+
+import "errors"
+
+// Errors of the package wrap one of the following, callers tell them apart with errors.Is
+var (
+	// ErrInvalidParams is returned for noise parameters that do not give differential privacy
+	ErrInvalidParams = errors.New("invalid parameters")
+)
@@ -0,0 +1,142 @@
+package field
+
+import "fmt"
+
+// Reed-Solomon decoding with the Berlekamp-Welch algorithm: the values of a polynomial of degree below k at
+// n distinct points are corrected as long as at most (n-k)/2 of them are wrong.
+
+// BerlekampWelch returns the coefficients of the polynomial of degree below k that goes through all but at most
+// (n-k)/2 of the n points (x_i, y_i), and the positions of the points it does not go through
+func (f *Field) BerlekampWelch(x, y []uint64, k int) ([]uint64, []int, error) {
+	n := len(x)
+	if len(y) != n {
+		return nil, nil, fmt.Errorf("got %d points and %d values", n, len(y))
+	}
+	if k < 1 || n < k {
+		return nil, nil, fmt.Errorf("%d points cannot determine a polynomial of degree below %d", n, k)
+	}
+	e := (n - k) / 2
+
+	// the error locator E is monic of degree e and Q = P*E of degree below e+k, Q(x_i) = y_i*E(x_i) gives
+	// a linear system in the e lower coefficients of E followed by the e+k coefficients of Q
+	cols := 2*e + k
+	system := make([][]uint64, n)
+	for i := range system {
+		system[i] = make([]uint64, cols+1)
+		pow := uint64(1)
+		for j := 0; j < e+k; j++ {
+			if j < e {
+				system[i][j] = f.Neg(f.Mul(y[i], pow))
+			}
+			system[i][e+j] = pow
+			if j == e {
+				system[i][cols] = f.Mul(y[i], pow)
+			}
+			pow = f.Mul(pow, x[i])
+		}
+	}
+
+	solution, err := f.solve(system, cols)
+	if err != nil {
+		return nil, nil, fmt.Errorf("too many errors to decode: %w", err)
+	}
+
+	locator := append(append([]uint64{}, solution[:e]...), 1)
+	p, remainder := f.divide(solution[e:], locator)
+	for _, r := range remainder {
+		if r != 0 {
+			return nil, nil, fmt.Errorf("too many errors to decode")
+		}
+	}
+
+	var wrong []int
+	for i := range x {
+		if f.Eval(p, x[i]) != y[i] {
+			wrong = append(wrong, i)
+		}
+	}
+	if len(wrong) > e {
+		return nil, nil, fmt.Errorf("too many errors to decode")
+	}
+	return p, wrong, nil
+}
+
+// Eval evaluates the polynomial with the given coefficients, the constant one first, at z
+func (f *Field) Eval(coeffs []uint64, z uint64) uint64 {
+	result := uint64(0)
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		result = f.Add(f.Mul(result, z), coeffs[i])
+	}
+	return result
+}
+
+// solve returns a solution of the linear system given by the rows of its augmented matrix with cols unknowns,
+// free unknowns are set to 0
+func (f *Field) solve(system [][]uint64, cols int) ([]uint64, error) {
+	pivots := make([]int, 0, cols)
+	row := 0
+	for col := 0; col < cols && row < len(system); col++ {
+		pivot := -1
+		for i := row; i < len(system); i++ {
+			if system[i][col] != 0 {
+				pivot = i
+				break
+			}
+		}
+		if pivot < 0 {
+			continue
+		}
+		system[row], system[pivot] = system[pivot], system[row]
+
+		inv := f.Inv(system[row][col])
+		for j := col; j <= cols; j++ {
+			system[row][j] = f.Mul(system[row][j], inv)
+		}
+		for i := range system {
+			if i == row || system[i][col] == 0 {
+				continue
+			}
+			factor := system[i][col]
+			for j := col; j <= cols; j++ {
+				system[i][j] = f.Sub(system[i][j], f.Mul(factor, system[row][j]))
+			}
+		}
+		pivots = append(pivots, col)
+		row++
+	}
+
+	// a remaining row 0 = c with c != 0 has no solution
+	for i := row; i < len(system); i++ {
+		if system[i][cols] != 0 {
+			return nil, fmt.Errorf("linear system has no solution")
+		}
+	}
+
+	solution := make([]uint64, cols)
+	for i, col := range pivots {
+		solution[col] = system[i][cols]
+	}
+	return solution, nil
+}
+
+// divide returns the quotient and remainder of the polynomial a divided by the monic polynomial b
+func (f *Field) divide(a, b []uint64) ([]uint64, []uint64) {
+	remainder := append([]uint64{}, a...)
+	d := len(b) - 1
+	if len(a) <= d {
+		return []uint64{}, remainder
+	}
+
+	quotient := make([]uint64, len(a)-d)
+	for i := len(a) - 1; i >= d; i-- {
+		c := remainder[i]
+		quotient[i-d] = c
+		if c == 0 {
+			continue
+		}
+		for j := 0; j <= d; j++ {
+			remainder[i-d+j] = f.Sub(remainder[i-d+j], f.Mul(c, b[j]))
+		}
+	}
+	return quotient, remainder[:d]
+}
@@ -0,0 +1,55 @@
+package field
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestBerlekampWelch(t *testing.T) {
+	for _, q := range []uint64{10631, Mersenne61} {
+		f, err := New(q)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// a polynomial of degree 4 at 13 points corrects up to 4 n_errors
+		coeffs := []uint64{7, 0, 3, q - 1, 12}
+		x := make([]uint64, 13)
+		y := make([]uint64, 13)
+		for i := range x {
+			x[i] = uint64(i + 1)
+			y[i] = f.Eval(coeffs, x[i])
+		}
+
+		r := rand.New(rand.NewSource(1))
+		for n_errors := 0; n_errors <= 4; n_errors++ {
+			corrupted := append([]uint64{}, y...)
+			positions := r.Perm(len(x))[:n_errors]
+			for _, i := range positions {
+				corrupted[i] = f.Add(corrupted[i], 1+uint64(r.Intn(100)))
+			}
+
+			p, wrong, err := f.BerlekampWelch(x, corrupted, len(coeffs))
+			if err != nil {
+				t.Fatalf("q=%d, %d n_errors: %v", q, n_errors, err)
+			}
+			if !reflect.DeepEqual(p, coeffs) {
+				t.Fatalf("q=%d, %d n_errors: expected %v, got %v", q, n_errors, coeffs, p)
+			}
+			if len(wrong) != n_errors {
+				t.Fatalf("q=%d: expected n_errors at %v, got %v", q, positions, wrong)
+			}
+			for _, i := range wrong {
+				if corrupted[i] == y[i] {
+					t.Fatalf("q=%d: point %d is not wrong", q, i)
+				}
+			}
+		}
+
+		_, _, err = f.BerlekampWelch(x[:4], y[:4], len(coeffs))
+		if err == nil {
+			t.Fatalf("expected an error for fewer points than coefficients")
+		}
+	}
+}
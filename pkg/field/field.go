@@ -0,0 +1,176 @@
+package field
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"math/bits"
+)
+
+// Field is the prime field of integers modulo q for any prime q < 2^64, e.g. the Goldilocks prime 2^64-2^32+1
+// or the Mersenne prime 2^61-1. Elements are canonical uint64 values in [0, q), products are reduced with
+// Montgomery reduction on the full 128-bit product and therefore never overflow. The protocol packages store
+// elements as int and are restricted to q < 2^63, e.g. 2^61-1 but not the Goldilocks prime, see NewInt.
+//
+// q: the prime modulus
+// q_inv: -q^-1 mod 2^64, used by Montgomery reduction
+// r2: 2^128 mod q, converts a Montgomery reduced product back to canonical form
+
+type Field struct {
+	q     uint64
+	q_inv uint64
+	r2    uint64
+}
+
+// Goldilocks is the prime 2^64-2^32+1
+const Goldilocks uint64 = 0xffffffff00000001
+
+// Mersenne61 is the prime 2^61-1
+const Mersenne61 uint64 = 1<<61 - 1
+
+// New creates the field of integers modulo the prime q
+func New(q uint64) (*Field, error) {
+	if q < 3 || q%2 == 0 {
+		return nil, fmt.Errorf("q must be an odd prime")
+	}
+
+	if !new(big.Int).SetUint64(q).ProbablyPrime(0) {
+		return nil, fmt.Errorf("q must be a prime number")
+	}
+
+	// Newton iteration doubles the number of correct low bits of q^-1 mod 2^64 per step
+	inv := q
+	for i := 0; i < 5; i++ {
+		inv *= 2 - q*inv
+	}
+
+	r := -q % q // 2^64 mod q
+	hi, lo := bits.Mul64(r, r)
+	r2 := bits.Rem64(hi, lo, q)
+
+	return &Field{q: q, q_inv: -inv, r2: r2}, nil
+}
+
+// NewInt creates the field of integers modulo the prime q for elements stored as int, as done by the protocol
+// packages. An int cannot hold q >= 2^63, e.g. the Goldilocks prime, which is refused with an error.
+func NewInt(q int) (*Field, error) {
+	if q <= 0 {
+		return nil, fmt.Errorf("q must be a prime below 2^63, elements are stored as int")
+	}
+	return New(uint64(q))
+}
+
+// Modulus returns q
+func (f *Field) Modulus() uint64 {
+	return f.q
+}
+
+// reduce returns (hi*2^64+lo)/2^64 mod q for hi < q
+func (f *Field) reduce(hi, lo uint64) uint64 {
+	m := lo * f.q_inv
+	mh, ml := bits.Mul64(m, f.q)
+	_, carry := bits.Add64(lo, ml, 0)
+	r, carry := bits.Add64(hi, mh, carry)
+	if carry != 0 || r >= f.q {
+		r -= f.q
+	}
+	return r
+}
+
+// Add returns a+b mod q
+func (f *Field) Add(a, b uint64) uint64 {
+	r, carry := bits.Add64(a, b, 0)
+	if carry != 0 || r >= f.q {
+		r -= f.q
+	}
+	return r
+}
+
+// Sub returns a-b mod q
+func (f *Field) Sub(a, b uint64) uint64 {
+	r, borrow := bits.Sub64(a, b, 0)
+	if borrow != 0 {
+		r += f.q
+	}
+	return r
+}
+
+// Neg returns -a mod q
+func (f *Field) Neg(a uint64) uint64 {
+	if a == 0 {
+		return 0
+	}
+	return f.q - a
+}
+
+// Mul returns a*b mod q
+func (f *Field) Mul(a, b uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	hi, lo = bits.Mul64(f.reduce(hi, lo), f.r2)
+	return f.reduce(hi, lo)
+}
+
+// Exp returns a^e mod q
+func (f *Field) Exp(a, e uint64) uint64 {
+	result := uint64(1)
+	for ; e > 0; e >>= 1 {
+		if e&1 == 1 {
+			result = f.Mul(result, a)
+		}
+		a = f.Mul(a, a)
+	}
+	return result
+}
+
+// Inv returns a^-1 mod q, the inverse of 0 is 0
+func (f *Field) Inv(a uint64) uint64 {
+	return f.Exp(a, f.q-2)
+}
+
+// Rand returns a uniformly random field element read from r: 8 little-endian bytes are masked to the bit length
+// of q-1 until the value is below q, so that a deterministic reader gives the same elements in any implementation
+func (f *Field) Rand(r io.Reader) (uint64, error) {
+	mask := uint64(1)<<bits.Len64(f.q-1) - 1
+	var buf [8]byte
+	for {
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return 0, err
+		}
+		if v := binary.LittleEndian.Uint64(buf[:]) & mask; v < f.q {
+			return v, nil
+		}
+	}
+}
+
+// Reduce maps an integer, which may be negative or larger than q, into the field
+func (f *Field) Reduce(a int) uint64 {
+	if a >= 0 && uint64(a) < f.q {
+		return uint64(a)
+	}
+	if a >= 0 {
+		return uint64(a) % f.q
+	}
+	// -(a+1) does not overflow for the smallest int
+	return f.Neg((uint64(-(a+1))%f.q + 1) % f.q)
+}
+
+// AddInt, SubInt and MulInt operate on field elements stored as int, as done by the protocol packages,
+// which restricts them to q < 2^63, see NewInt. Every argument is reduced into the field first.
+
+func (f *Field) AddInt(a, b int) int {
+	return int(f.Add(f.Reduce(a), f.Reduce(b)))
+}
+
+func (f *Field) SubInt(a, b int) int {
+	return int(f.Sub(f.Reduce(a), f.Reduce(b)))
+}
+
+func (f *Field) MulInt(a, b int) int {
+	return int(f.Mul(f.Reduce(a), f.Reduce(b)))
+}
+
+// InvInt returns the inverse of a field element stored as int
+func (f *Field) InvInt(a int) int {
+	return int(f.Inv(f.Reduce(a)))
+}
@@ -0,0 +1,128 @@
+package field
+
+import (
+	"bytes"
+	"math/big"
+	"math/rand"
+	"testing"
+)
+
+var primes = []uint64{3, 41, 10631, 41543, 2147483647, Mersenne61, Goldilocks, 18446744073709551557}
+
+func TestArithmetic(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, q := range primes {
+		f, err := New(q)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		bq := new(big.Int).SetUint64(q)
+		for i := 0; i < 1000; i++ {
+			a, b := rng.Uint64()%q, rng.Uint64()%q
+			// the largest elements are the most likely to overflow
+			if i == 0 {
+				a, b = q-1, q-1
+			}
+			ba, bb := new(big.Int).SetUint64(a), new(big.Int).SetUint64(b)
+
+			expected := map[string]*big.Int{
+				"add": new(big.Int).Mod(new(big.Int).Add(ba, bb), bq),
+				"sub": new(big.Int).Mod(new(big.Int).Sub(ba, bb), bq),
+				"mul": new(big.Int).Mod(new(big.Int).Mul(ba, bb), bq),
+				"exp": new(big.Int).Exp(ba, bb, bq),
+			}
+			got := map[string]uint64{"add": f.Add(a, b), "sub": f.Sub(a, b), "mul": f.Mul(a, b), "exp": f.Exp(a, b)}
+
+			for op, value := range got {
+				if expected[op].Uint64() != value {
+					t.Fatalf("q=%d: %s(%d, %d) expected %v, but got %d", q, op, a, b, expected[op], value)
+				}
+			}
+
+			if a != 0 && f.Mul(a, f.Inv(a)) != 1 {
+				t.Fatalf("q=%d: %d * %d^-1 is not 1", q, a, a)
+			}
+		}
+	}
+}
+
+func TestReduce(t *testing.T) {
+	f, err := New(Mersenne61)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	tests := []struct {
+		input    int
+		expected uint64
+	}{
+		{0, 0},
+		{-1, Mersenne61 - 1},
+		{int(Mersenne61), 0},
+		{-int(Mersenne61) - 2, Mersenne61 - 2},
+		{-1 << 63, Mersenne61 - 4},
+	}
+
+	for _, test := range tests {
+		if result := f.Reduce(test.input); result != test.expected {
+			t.Errorf("Reduce(%d): expected %d, but got %d", test.input, test.expected, result)
+		}
+	}
+
+	// products of int elements do not overflow
+	if f.MulInt(int(Mersenne61-1), int(Mersenne61-1)) != 1 {
+		t.Errorf("(q-1)^2 is not 1")
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, q := range []uint64{0, 1, 2, 9, 1 << 61, 18446744073709551615} {
+		if _, err := New(q); err == nil {
+			t.Errorf("expected error for q=%d", q)
+		}
+	}
+}
+
+func TestNewInt(t *testing.T) {
+	if _, err := NewInt(int(Mersenne61)); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	// the Goldilocks prime does not fit into an int
+	goldilocks := Goldilocks
+	if _, err := NewInt(int(goldilocks)); err == nil {
+		t.Fatalf("expected error for q=%d stored as int", goldilocks)
+	}
+}
+
+func TestRand(t *testing.T) {
+	f, _ := New(10631)
+
+	// 0xffff... is rejected, 10630 = 0x2986 accepted
+	data := []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x86, 0x29, 0, 0, 0, 0, 0, 0}
+	r := bytes.NewReader(data)
+	v, err := f.Rand(r)
+	if err != nil || v != 10630 {
+		t.Fatalf("expected 10630, got %d (%v)", v, err)
+	}
+	if _, err := f.Rand(r); err == nil {
+		t.Fatalf("expected error for an exhausted reader")
+	}
+
+	g, _ := New(Goldilocks)
+	src := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		v, err := g.Rand(src)
+		if err != nil || v >= Goldilocks {
+			t.Fatalf("expected an element of the field, got %d (%v)", v, err)
+		}
+	}
+}
+
+func BenchmarkMul(b *testing.B) {
+	f, _ := New(Goldilocks)
+	x := uint64(12345678901234567)
+	for i := 0; i < b.N; i++ {
+		x = f.Mul(x, x+1)
+	}
+}
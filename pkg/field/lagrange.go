@@ -0,0 +1,66 @@
+package field
+
+// Lagrange interpolation on fixed points: the weights of the points are computed once, the basis
+// polynomials at a point then take a single inversion and O(n) multiplications.
+
+// BatchInv replaces the nonzero elements of a by their inverses with a single inversion
+func (f *Field) BatchInv(a []uint64) {
+	if len(a) == 0 {
+		return
+	}
+
+	// prefix[i] is the product of a[0], ..., a[i-1]
+	prefix := make([]uint64, len(a))
+	acc := uint64(1)
+	for i, x := range a {
+		prefix[i] = acc
+		acc = f.Mul(acc, x)
+	}
+
+	inv := f.Inv(acc)
+	for i := len(a) - 1; i >= 0; i-- {
+		a[i], inv = f.Mul(inv, prefix[i]), f.Mul(inv, a[i])
+	}
+}
+
+// LagrangeWeights returns the barycentric weights 1/prod_{j != i}(x_i-x_j) of the distinct points x
+func (f *Field) LagrangeWeights(x []uint64) []uint64 {
+	w := make([]uint64, len(x))
+	for i := range x {
+		w[i] = 1
+		for j := range x {
+			if j != i {
+				w[i] = f.Mul(w[i], f.Sub(x[i], x[j]))
+			}
+		}
+	}
+	f.BatchInv(w)
+	return w
+}
+
+// LagrangeBasis returns the values at z of the lagrange basis polynomials of the points x with weights w,
+// the i-th unit vector for z = x_i
+func (f *Field) LagrangeBasis(x, w []uint64, z uint64) []uint64 {
+	basis := make([]uint64, len(x))
+	for i := range x {
+		basis[i] = f.Sub(z, x[i])
+		if basis[i] == 0 {
+			for j := range basis {
+				basis[j] = 0
+			}
+			basis[i] = 1
+			return basis
+		}
+	}
+
+	// l(z) = prod (z-x_i), the i-th basis polynomial is w_i * l(z)/(z-x_i)
+	l := uint64(1)
+	for _, d := range basis {
+		l = f.Mul(l, d)
+	}
+	f.BatchInv(basis)
+	for i := range basis {
+		basis[i] = f.Mul(f.Mul(w[i], l), basis[i])
+	}
+	return basis
+}
@@ -0,0 +1,40 @@
+package field
+
+import (
+	"testing"
+)
+
+func TestLagrangeBasis(t *testing.T) {
+	for _, q := range []uint64{10631, Mersenne61} {
+		f, err := New(q)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		a := []uint64{3, 1, q - 1, 7, 12345 % q}
+		inv := append([]uint64{}, a...)
+		f.BatchInv(inv)
+		for i := range a {
+			if f.Mul(a[i], inv[i]) != 1 {
+				t.Fatalf("q=%d: %d * %d is not 1", q, a[i], inv[i])
+			}
+		}
+
+		// p(x) = 5x^2 + 3x + 2 through three points, evaluated elsewhere
+		p := func(x uint64) uint64 {
+			return f.Add(f.Add(f.Mul(5, f.Mul(x, x)), f.Mul(3, x)), 2)
+		}
+		x := []uint64{1, 2, q - 1}
+		w := f.LagrangeWeights(x)
+		for _, z := range []uint64{0, 2, 9, q - 5} {
+			basis := f.LagrangeBasis(x, w, z)
+			y := uint64(0)
+			for i := range x {
+				y = f.Add(y, f.Mul(basis[i], p(x[i])))
+			}
+			if y != p(z) {
+				t.Fatalf("q=%d: interpolation at %d gives %d, expected %d", q, z, y, p(z))
+			}
+		}
+	}
+}
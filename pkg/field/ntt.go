@@ -0,0 +1,81 @@
+package field
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// NTT-friendly primes have a large power of two dividing q-1, e.g. 998244353 = 119*2^23+1
+// or 2013265921 = 15*2^27+1, so that polynomials can be evaluated and interpolated on
+// subgroups of 2^k-th roots of unity with the number theoretic transform.
+
+// TwoAdicity returns the largest s such that 2^s divides q-1
+func (f *Field) TwoAdicity() int {
+	return bits.TrailingZeros64(f.q - 1)
+}
+
+// NonResidue returns the smallest quadratic non-residue. Its multiplicative order is divisible by 2^s,
+// so it lies outside every subgroup of 2^k-th roots of unity with k < s.
+func (f *Field) NonResidue() uint64 {
+	for z := uint64(2); ; z++ {
+		if f.Exp(z, (f.q-1)/2) == f.q-1 {
+			return z
+		}
+	}
+}
+
+// RootOfUnity returns a primitive n-th root of unity for a power of two n dividing q-1
+func (f *Field) RootOfUnity(n int) (uint64, error) {
+	if n <= 0 || n&(n-1) != 0 {
+		return 0, fmt.Errorf("size %d of the root of unity is not a power of two", n)
+	}
+	s := f.TwoAdicity()
+	if bits.TrailingZeros64(uint64(n)) > s {
+		return 0, fmt.Errorf("q-1 is not divisible by %d", n)
+	}
+
+	// a non-residue raised to (q-1)/2^s generates the subgroup of 2^s-th roots of unity
+	root := f.Exp(f.NonResidue(), (f.q-1)>>s)
+	for size := uint64(1) << s; size > uint64(n); size >>= 1 {
+		root = f.Mul(root, root)
+	}
+	return root, nil
+}
+
+// NTT replaces the coefficients a of a polynomial by its values at omega^0, ..., omega^(n-1),
+// n = len(a) is a power of two and omega a primitive n-th root of unity
+func (f *Field) NTT(a []uint64, omega uint64) {
+	n := len(a)
+	shift := 64 - bits.TrailingZeros(uint(n))
+	for i := 1; i < n; i++ {
+		j := int(bits.Reverse64(uint64(i)) >> shift)
+		if i < j {
+			a[i], a[j] = a[j], a[i]
+		}
+	}
+
+	for size := 2; size <= n; size <<= 1 {
+		w_size := f.Exp(omega, uint64(n/size))
+		half := size / 2
+		for start := 0; start < n; start += size {
+			w := uint64(1)
+			for k := 0; k < half; k++ {
+				u := a[start+k]
+				v := f.Mul(a[start+k+half], w)
+				a[start+k] = f.Add(u, v)
+				a[start+k+half] = f.Sub(u, v)
+				w = f.Mul(w, w_size)
+			}
+		}
+	}
+}
+
+// InverseNTT replaces the values a at omega^0, ..., omega^(n-1) by the coefficients of the
+// polynomial of degree less than n through them
+func (f *Field) InverseNTT(a []uint64, omega uint64) {
+	f.NTT(a, f.Inv(omega))
+	n_inv := f.Inv(uint64(len(a)) % f.q)
+	for i := range a {
+		a[i] = f.Mul(a[i], n_inv)
+	}
+}
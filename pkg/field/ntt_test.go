@@ -0,0 +1,58 @@
+package field
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestNTT(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, q := range []uint64{998244353, 2013265921, Goldilocks} {
+		f, err := New(q)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		for _, n := range []int{1, 2, 8, 64} {
+			omega, err := f.RootOfUnity(n)
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			if f.Exp(omega, uint64(n)) != 1 || (n > 1 && f.Exp(omega, uint64(n/2)) == 1) {
+				t.Fatalf("q=%d: %d is not a primitive %d-th root of unity", q, omega, n)
+			}
+
+			coeffs := make([]uint64, n)
+			for i := range coeffs {
+				coeffs[i] = rng.Uint64() % q
+			}
+			values := append([]uint64(nil), coeffs...)
+			f.NTT(values, omega)
+
+			// compare against Horner evaluation at every root
+			x := uint64(1)
+			for i := 0; i < n; i++ {
+				y := uint64(0)
+				for j := n - 1; j >= 0; j-- {
+					y = f.Add(f.Mul(y, x), coeffs[j])
+				}
+				if values[i] != y {
+					t.Fatalf("q=%d n=%d: value %d expected %d, but got %d", q, n, i, y, values[i])
+				}
+				x = f.Mul(x, omega)
+			}
+
+			f.InverseNTT(values, omega)
+			for i := range coeffs {
+				if values[i] != coeffs[i] {
+					t.Fatalf("q=%d n=%d: inverse NTT does not recover the coefficients", q, n)
+				}
+			}
+		}
+	}
+
+	f, _ := New(41543)
+	if _, err := f.RootOfUnity(4); err == nil {
+		t.Fatalf("41543-1 is not divisible by 4")
+	}
+}
@@ -0,0 +1,390 @@
+package ligero
+
+import (
+	"errors"
+	"testing"
+)
+
+// A cheating prover either runs the prover on a witness or shares it altered, or alters an honest proof.
+// Every strategy has to be rejected with ErrInvalidProof, without the verifier failing otherwise.
+
+type cheat struct {
+	name  string
+	proof func(t *testing.T, zk *LigeroZK) Proof
+}
+
+// adversary_zk has a large field and many opened columns, so that no cheat passes by chance
+func adversary_zk(t testing.TB) *LigeroZK {
+	pred, err := ParsePredicate("bit+atmost:3")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	zk, err := NewLigeroZK(6, 2, 4, 1, 2147483647, 8, pred)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	return zk
+}
+
+var adversary_secrets = []int{1, 0, 1, 0, 0, 1}
+
+// honest_proof returns the proof of server 0 for the honest secrets, a copy of its own for every call
+func honest_proof(t testing.TB, zk *LigeroZK) Proof {
+	proofs, err := zk.GenerateProof(ctx, adversary_secrets)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	return *proofs[0]
+}
+
+// cheating_proof runs the prover on the claims and shares of the secrets after alter changed them
+func cheating_proof(t *testing.T, zk *LigeroZK, secrets []int, alter func(claims []Claim, party_sh []Shares)) Proof {
+	claims, party_sh, err := zk.preprocess(secrets)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	alter(claims, party_sh)
+	proofs, err := zk.prove(ctx, claims, party_sh, LeafVersion)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	return *proofs[0]
+}
+
+// tampered returns an honest proof after alter changed it
+func tampered(alter func(p *Proof)) func(t *testing.T, zk *LigeroZK) Proof {
+	return func(t *testing.T, zk *LigeroZK) Proof {
+		p := honest_proof(t, zk)
+		alter(&p)
+		return p
+	}
+}
+
+// forged_proof runs the prover on the secrets and lets forge change the quadratic test before it is sent and
+// open change the opened columns, both know the test coefficients h1 of the transcript
+func forged_proof(t testing.TB, zk *LigeroZK, secrets []int, forge func(h1 []byte, q_quadra []int), open func(h1 []byte, cols []OpenedColumn)) Proof {
+	claims, party_sh, err := zk.preprocess(secrets)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	c, err := zk.commit(claims, LeafVersion)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	root := c.tree.Root()
+
+	ts := zk.NewTranscript(ctx)
+	ts.AbsorbBytes("merkle_root", root)
+	h1 := ts.Challenge("test_coefficients")
+	q_code, q_quadra, q_linear, q_sum, err := zk.generate_tests(c, h1)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	forge(h1, q_quadra)
+
+	fst_tree, fst_leaves, err := zk.generate_fst_merkletree(party_sh, c.seeds, LeafVersion)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	fst_root := fst_tree.Root()
+	fst_proof, err := fst_tree.GenerateProof(fst_leaves[0])
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	h2 := zk.column_challenge(ts, fst_root, q_code, q_quadra, q_linear, q_sum)
+	r4 := RandVector(h2, zk.n_open_col, zk.n_encode)
+	cols, multiproof, err := zk.open_columns(c, r4, LeafVersion)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	open(h1, cols)
+
+	return *newProof(root, cols, multiproof, q_code, q_quadra, q_linear, q_sum, party_sh[0], c.seeds, fst_root, fst_proof.Hashes, LeafVersion)
+}
+
+// adaptive_quadratic_masks commits to secrets that are not bits and sends a quadratic test of zeros, which vanishes
+// at the secret slots. The opened columns follow from the transcript, so the prover knows them before opening and
+// sets their quadratic masks to cancel the quadratic constraints, which the commitment to the masks prevents.
+func adaptive_quadratic_masks(t *testing.T, zk *LigeroZK) Proof {
+	return forged_proof(t, zk, []int{2, 0, 1, 0, 0, 0}, func(h1 []byte, q_quadra []int) {
+		for i := range q_quadra {
+			q_quadra[i] = 0
+		}
+	}, func(h1 []byte, cols []OpenedColumn) {
+		// the quadratic test of the single repetition evaluates to zero at the opened columns
+		len1, len2, len3, len4 := zk.challenge_lengths()
+		r2 := RandVector(h1, len1+len2+len3+len4, zk.q)[len1 : len1+len2]
+		for i := range cols {
+			cols[i].Quadra_mask = zk.field.SubInt(0, zk.eval_quadratic(cols[i].List, r2))
+		}
+	})
+}
+
+var cheats = []cheat{
+	{"secret that is not a bit", func(t *testing.T, zk *LigeroZK) Proof {
+		return cheating_proof(t, zk, []int{2, 0, 1, 0, 0, 0}, func([]Claim, []Shares) {})
+	}},
+	{"quadratic masks chosen after the opened columns", adaptive_quadratic_masks},
+	{"sum of secrets above the bound", func(t *testing.T, zk *LigeroZK) Proof {
+		return cheating_proof(t, zk, []int{1, 1, 1, 1, 0, 0}, func([]Claim, []Shares) {})
+	}},
+	{"shares that do not add up to the secret", func(t *testing.T, zk *LigeroZK) Proof {
+		return cheating_proof(t, zk, adversary_secrets, func(claims []Claim, party_sh []Shares) {
+			// the servers receive the altered share, consistently with the witness
+			claims[0].Shares[0] = zk.field.AddInt(claims[0].Shares[0], 1)
+			for _, sh := range party_sh {
+				for k, index := range sh.Index {
+					if index == 0 {
+						sh.Values[0][k] = claims[0].Shares[0]
+					}
+				}
+			}
+		})
+	}},
+	{"wrong shares sent to the server", func(t *testing.T, zk *LigeroZK) Proof {
+		return cheating_proof(t, zk, adversary_secrets, func(claims []Claim, party_sh []Shares) {
+			party_sh[0].Values[0][0] = zk.field.AddInt(party_sh[0].Values[0][0], 1)
+		})
+	}},
+	{"shares of another proof", func(t *testing.T, zk *LigeroZK) Proof {
+		p := honest_proof(t, zk)
+		p.Shares = honest_proof(t, zk).Shares
+		return p
+	}},
+	{"wrong shares in Shares.Values", tampered(func(p *Proof) {
+		p.Shares.Values[0][0]++
+	})},
+	{"inconsistent FST root", func(t *testing.T, zk *LigeroZK) Proof {
+		p := honest_proof(t, zk)
+		p.FST_root = honest_proof(t, zk).FST_root
+		return p
+	}},
+	{"FST root and path of another proof", func(t *testing.T, zk *LigeroZK) Proof {
+		p := honest_proof(t, zk)
+		other := honest_proof(t, zk)
+		p.FST_root, p.FST_authpath = other.FST_root, other.FST_authpath
+		return p
+	}},
+	{"relabeled PartyIndex", tampered(func(p *Proof) { p.Shares.PartyIndex = 1 })},
+	{"negative PartyIndex", tampered(func(p *Proof) { p.Shares.PartyIndex = -1 })},
+	{"share index out of range", tampered(func(p *Proof) { p.Shares.Index[0] = 1 << 40 })},
+	{"negative share index", tampered(func(p *Proof) { p.Shares.Index[0] = -1 })},
+	{"missing shares", tampered(func(p *Proof) { p.Shares.Values = p.Shares.Values[1:] })},
+	{"missing seed", tampered(func(p *Proof) { p.Seeds = p.Seeds[1:] })},
+	{"tampered opened column", tampered(func(p *Proof) { p.ColumnTest[0].List[0]++ })},
+	{"opened column too short", tampered(func(p *Proof) { p.ColumnTest[0].List = p.ColumnTest[0].List[:1] })},
+	{"tampered code mask", tampered(func(p *Proof) { p.ColumnTest[0].Code_mask++ })},
+	{"tampered quadratic mask", tampered(func(p *Proof) { p.ColumnTest[0].Quadra_mask++ })},
+	{"tampered linear mask", tampered(func(p *Proof) { p.ColumnTest[0].Linear_mask++ })},
+	{"tampered sum mask", tampered(func(p *Proof) { p.ColumnTest[0].Sum_mask++ })},
+	{"tampered Merkle nonce", tampered(func(p *Proof) { p.ColumnTest[0].Merkle_nonce++ })},
+	{"reused column index", tampered(func(p *Proof) { p.ColumnTest[1] = p.ColumnTest[0] })},
+	{"column index out of range", tampered(func(p *Proof) { p.ColumnTest[0].Index = 1 << 40 })},
+	{"missing opened column", tampered(func(p *Proof) { p.ColumnTest = p.ColumnTest[1:] })},
+	{"tampered multi-proof", tampered(func(p *Proof) { p.Multiproof[0][0] ^= 1 })},
+	{"missing multi-proof", tampered(func(p *Proof) { p.Multiproof = nil })},
+	{"tampered Merkle root", tampered(func(p *Proof) { p.MerkleRoot[0] ^= 1 })},
+	{"zeroed code test", tampered(func(p *Proof) {
+		for i := range p.CodeTest {
+			p.CodeTest[i] = 0
+		}
+	})},
+	{"tampered quadratic test", tampered(func(p *Proof) { p.QuadraTest[0]++ })},
+	{"tampered linear test", tampered(func(p *Proof) { p.LinearTest[0]++ })},
+	{"tampered sum test", tampered(func(p *Proof) { p.SumTest[0]++ })},
+	{"truncated quadratic test", tampered(func(p *Proof) { p.QuadraTest = p.QuadraTest[1:] })},
+	{"missing sum test", tampered(func(p *Proof) { p.SumTest = nil })},
+	{"unknown leaf version", tampered(func(p *Proof) { p.Leaf_version = 7 })},
+	{"empty proof", func(t *testing.T, zk *LigeroZK) Proof { return Proof{} }},
+}
+
+func TestCheatingProvers(t *testing.T) {
+	zk := adversary_zk(t)
+
+	honest := honest_proof(t, zk)
+	verify, err := zk.VerifyProof(ctx, honest)
+	if !verify {
+		t.Fatalf("verification of the honest proof failed: %v", err)
+	}
+
+	proofs := make([]Proof, len(cheats))
+	ctxs := make([]Context, len(cheats))
+	for i, c := range cheats {
+		proofs[i], ctxs[i] = c.proof(t, zk), ctx
+		verify, err := zk.VerifyProof(ctx, proofs[i])
+		if verify {
+			t.Fatalf("%s: verification succeeded", c.name)
+		}
+		if !errors.Is(err, ErrInvalidProof) {
+			t.Fatalf("%s: expected ErrInvalidProof, got %v", c.name, err)
+		}
+	}
+
+	// cheats are rejected in a batch as well, the honest proof still verifies
+	valid, errs := zk.VerifyBatch(append(ctxs, ctx), append(proofs, honest))
+	for i, c := range cheats {
+		if valid[i] || !errors.Is(errs[i], ErrInvalidProof) {
+			t.Fatalf("%s: expected ErrInvalidProof in a batch, got %v", c.name, errs[i])
+		}
+	}
+	if !valid[len(cheats)] {
+		t.Fatalf("verification of the honest proof in a batch failed: %v", errs[len(cheats)])
+	}
+}
+
+func TestHighDegreeTests(t *testing.T) {
+	for _, q := range []int{2147483647, 998244353} {
+		zk, err := NewLigeroZK(6, 2, 4, 1, q, 8, BitPredicate())
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		honest := honest_proof(t, zk)
+		tests, err := zk.verify_columns(ctx, honest)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// adding Z(x)*x^n_coeffs, Z vanishing at the secret slots, keeps the slots and raises the degree of a test
+		degree := zk.npss.Degree()
+		raised := func(test []int, n_coeffs int) []int {
+			result := make([]int, len(test))
+			for c := range test {
+				x := zk.npss.SharePoint(c)
+				delta := int(zk.field.Exp(zk.field.Reduce(x), uint64(n_coeffs)))
+				for j := 0; j < zk.l; j++ {
+					delta = zk.field.MulInt(delta, zk.field.SubInt(x, zk.npss.SecretPoint(j)))
+				}
+				result[c] = zk.field.AddInt(test[c], delta)
+			}
+			return result
+		}
+
+		verified, err := zk.verify_slots(tests.quadra[0], tests.linear[0], nil, 0)
+		if !verified {
+			t.Fatalf("q %d: verification of the honest tests failed: %v", q, err)
+		}
+		verified, _ = zk.verify_slots(raised(tests.quadra[0], 2*degree-1), tests.linear[0], nil, 0)
+		if verified {
+			t.Fatalf("q %d: verification of a quadratic test of high degree succeeded", q)
+		}
+		verified, _ = zk.verify_slots(tests.quadra[0], raised(tests.linear[0], degree), nil, 0)
+		if verified {
+			t.Fatalf("q %d: verification of a linear test of high degree succeeded", q)
+		}
+	}
+}
+
+func FuzzUnmarshalProof(f *testing.F) {
+	zk := adversary_zk(f)
+	honest := honest_proof(f, zk)
+	data, err := honest.MarshalBinary(zk.q)
+	if err != nil {
+		f.Fatalf("err: %v", err)
+	}
+	f.Add(data)
+	f.Add(data[:len(data)/2])
+	f.Add([]byte{CodecVersion, 64})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var p Proof
+		if err := p.UnmarshalBinary(data, zk.q); err != nil {
+			if !errors.Is(err, ErrInvalidEncoding) {
+				t.Fatalf("expected ErrInvalidEncoding, got %v", err)
+			}
+			return
+		}
+		// a decoded proof encodes again
+		if _, err := p.MarshalBinary(zk.q); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	})
+}
+
+func FuzzVerifyProof(f *testing.F) {
+	zk := adversary_zk(f)
+	honest := honest_proof(f, zk)
+	data, err := honest.MarshalBinary(zk.q)
+	if err != nil {
+		f.Fatalf("err: %v", err)
+	}
+	f.Add(data, 0, byte(0))
+	f.Add(data, len(data)/2, byte(1))
+	f.Add(data, len(data)-1, byte(0x80))
+
+	// the honest encoding with one byte flipped is decoded and verified, only the honest proof may pass
+	f.Fuzz(func(t *testing.T, data []byte, pos int, flip byte) {
+		data = append([]byte{}, data...)
+		if len(data) > 0 {
+			pos = mod(pos, len(data))
+			data[pos] ^= flip
+		}
+		var p Proof
+		if err := p.UnmarshalBinary(data, zk.q); err != nil {
+			return
+		}
+		verify, err := zk.VerifyProof(ctx, p)
+		if !verify && !errors.Is(err, ErrInvalidProof) {
+			t.Fatalf("expected ErrInvalidProof, got %v", err)
+		}
+	})
+}
+
+// guessed_quadratic_test commits to secrets that are not bits and sends a quadratic test that vanishes at the
+// secret slots and agrees with the test of the committed witness at the guessed columns, with less than 2k-1
+// coefficients. The proof passes if every opened column is one of the guessed ones.
+func guessed_quadratic_test(t testing.TB, zk *LigeroZK, guessed []int) Proof {
+	return forged_proof(t, zk, []int{2, 0, 1, 0, 0, 0}, func(h1 []byte, q_quadra []int) {
+		x := make([]int, 0, len(guessed)+zk.l)
+		y := make([]int, 0, len(guessed)+zk.l)
+		for _, c := range guessed {
+			x, y = append(x, zk.npss.SharePoint(c)), append(y, q_quadra[c])
+		}
+		for j := 0; j < zk.l; j++ {
+			x, y = append(x, zk.npss.SecretPoint(j)), append(y, 0)
+		}
+		for c := range q_quadra {
+			value, err := zk.interpolate(nil, x, y, zk.npss.SharePoint(c))
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			q_quadra[c] = value
+		}
+	}, func([]byte, []OpenedColumn) {})
+}
+
+func TestSoundnessOfGuessedTests(t *testing.T) {
+	p := Params{N_secret: 6, M: 2, N_server: 4, T: 1, Q: 998244353, N_open: 2}
+	zk, err := NewLigeroZKFromParams(p, BitPredicate())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	s, err := ComputeSoundness(p)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// the test is free at 2k-1-l columns, the forgery passes if all opened columns are among them
+	guessed := make([]int, 2*zk.npss.Degree()-1-zk.l)
+	for i := range guessed {
+		guessed[i] = i
+	}
+	chance := 1.0
+	for i := 0; i < p.N_open; i++ {
+		chance *= float64(len(guessed)-i) / float64(zk.n_encode-i)
+	}
+	if s.Error < chance {
+		t.Fatalf("soundness error %g is below the chance %g of the forgery", s.Error, chance)
+	}
+
+	trials, passed := 400, 0
+	for i := 0; i < trials; i++ {
+		verify, _ := zk.VerifyProof(ctx, guessed_quadratic_test(t, zk, guessed))
+		if verify {
+			passed++
+		}
+	}
+	if passed == 0 || float64(passed)/float64(trials) > s.Error {
+		t.Fatalf("forgery passed %d of %d times, expected about %.1f and at most %.1f", passed, trials, chance*float64(trials), s.Error*float64(trials))
+	}
+}
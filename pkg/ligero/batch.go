@@ -0,0 +1,110 @@
+package ligero
+
+import (
+	crypto_rand "crypto/rand"
+	"fmt"
+	"math"
+)
+
+// VerifyBatch verifies the proofs of many clients for the same experiment, proofs[i] against ctxs[i], and
+// returns the result of every proof as VerifyProof does. The checks at the opened columns are run per proof
+// with the interpolation constants of zk shared by all of them. The checks at the secret slots, interpolations
+// over all columns, are linear in the tests: they are run on random combinations of the tests of all proofs,
+// and proof by proof only if a combination fails.
+func (zk *LigeroZK) VerifyBatch(ctxs []Context, proofs []Proof) ([]bool, []error) {
+	verified := make([]bool, len(proofs))
+	errs := make([]error, len(proofs))
+	if len(ctxs) != len(proofs) {
+		for i := range errs {
+			errs[i] = fmt.Errorf("%w: batch has %d contexts for %d proofs", ErrDimensionMismatch, len(ctxs), len(proofs))
+		}
+		return verified, errs
+	}
+
+	tests := make([]*slot_tests, len(proofs))
+	var pending []int
+	for i := range proofs {
+		tests[i], errs[i] = zk.verify_columns(ctxs[i], proofs[i])
+		if errs[i] == nil {
+			pending = append(pending, i)
+		} else {
+			errs[i] = invalid_proof(errs[i])
+		}
+	}
+
+	// combining pays off once there are more tests than combinations
+	if len(pending)*zk.repetitions > zk.batch_combinations() {
+		combined, _ := zk.verify_combined_slots(tests, pending)
+		if combined {
+			for _, i := range pending {
+				verified[i] = true
+			}
+			return verified, errs
+		}
+	}
+
+	for _, i := range pending {
+		verified[i] = true
+		for rep := range tests[i].quadra {
+			verified[i], errs[i] = zk.verify_slots(tests[i].quadra[rep], tests[i].linear[rep], tests[i].sum[rep], tests[i].expected[rep])
+			if !verified[i] {
+				errs[i] = invalid_proof(errs[i])
+				break
+			}
+		}
+	}
+	return verified, errs
+}
+
+// batch_combinations returns the number of random combinations that let a failed check of a proof pass
+// with probability at most 2^-RecommendedSoundnessBits, each with probability at most 1/q
+func (zk *LigeroZK) batch_combinations() int {
+	return int(math.Ceil(RecommendedSoundnessBits / math.Log2(float64(zk.q))))
+}
+
+// verify_combined_slots checks batch_combinations random combinations of the tests of the pending proofs
+// at the secret slots, with coefficients the prover cannot know
+func (zk *LigeroZK) verify_combined_slots(tests []*slot_tests, pending []int) (bool, error) {
+	with_sum := len(zk.sums) > 0
+	for c := 0; c < zk.batch_combinations(); c++ {
+		// the coefficients are drawn from crypto/rand, not from the reader of the prover, which may be deterministic
+		coeffs, err := generate_seeds(crypto_rand.Reader, zk.field, len(pending)*zk.repetitions)
+		if err != nil {
+			return false, err
+		}
+
+		quadra := make([]int, zk.n_encode)
+		linear := make([]int, zk.n_encode)
+		var sum []int
+		if with_sum {
+			sum = make([]int, zk.n_encode)
+		}
+		expected := 0
+
+		k := 0
+		for _, i := range pending {
+			for rep := range tests[i].quadra {
+				zk.add_scaled(quadra, tests[i].quadra[rep], coeffs[k])
+				zk.add_scaled(linear, tests[i].linear[rep], coeffs[k])
+				if with_sum {
+					zk.add_scaled(sum, tests[i].sum[rep], coeffs[k])
+					expected = zk.field.AddInt(expected, zk.field.MulInt(coeffs[k], tests[i].expected[rep]))
+				}
+				k++
+			}
+		}
+
+		verified, err := zk.verify_slots(quadra, linear, sum, expected)
+		if !verified {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// add_scaled adds c*values to acc
+func (zk *LigeroZK) add_scaled(acc []int, values []int, c int) {
+	for j, v := range values {
+		acc[j] = zk.field.AddInt(acc[j], zk.field.MulInt(c, v))
+	}
+}
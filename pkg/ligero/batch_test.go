@@ -0,0 +1,96 @@
+package ligero
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestVerifyBatch(t *testing.T) {
+	pred := And(RangePredicate(4), SumAtMostPredicate(20))
+	for _, q := range []int{10631, 998244353} {
+		zk, err := NewLigeroZK(6, 2, 4, 1, q, 3, pred)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// valid proofs of 12 clients, the invalid ones are replaced below
+		var ctxs []Context
+		var proofs []Proof
+		for i := 0; i < 12; i++ {
+			c := Context{Exp_ID: "exp1", Client_ID: fmt.Sprintf("c%d", i)}
+			proof, err := zk.GenerateProof(c, []int{15, 5, 0, 0, 0, i % 2})
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			ctxs = append(ctxs, c)
+			proofs = append(proofs, *proof[i%4])
+		}
+
+		valid, errs := zk.VerifyBatch(ctxs, proofs)
+		for i := range proofs {
+			if i%2 == 0 && !valid[i] {
+				t.Fatalf("q %d: verification of proof %d failed: %v", q, i, errs[i])
+			}
+			// sums of 21 exceed the bound, only the slots of the sum test reveal it
+			if i%2 == 1 && valid[i] {
+				t.Fatalf("q %d: verification of proof %d with a sum above the bound succeeded", q, i)
+			}
+		}
+
+		// a replayed proof fails at the opened columns, the others still verify together
+		ctxs = []Context{ctxs[0], ctxs[2], ctxs[4], ctxs[0]}
+		proofs = []Proof{proofs[0], proofs[2], proofs[4], proofs[2]}
+		valid, errs = zk.VerifyBatch(ctxs, proofs)
+		for i, expected := range []bool{true, true, true, false} {
+			if valid[i] != expected {
+				t.Fatalf("q %d: expected %v for proof %d, got %v (%v)", q, expected, i, valid[i], errs[i])
+			}
+		}
+
+		valid, errs = zk.VerifyBatch(ctxs[:1], proofs)
+		if valid[0] || errs[0] == nil {
+			t.Fatalf("q %d: expected an error for a batch with too few contexts", q)
+		}
+	}
+}
+
+func BenchmarkVerifyBatch(b *testing.B) {
+	zk, err := NewLigeroZK(100, 4, 4, 1, 10631, 240, BitPredicate())
+	if err != nil {
+		b.Fatalf("err: %v", err)
+	}
+
+	secrets := make([]int, 100)
+	var ctxs []Context
+	var proofs []Proof
+	for i := 0; i < 32; i++ {
+		c := Context{Exp_ID: "exp1", Client_ID: fmt.Sprintf("c%d", i)}
+		proof, err := zk.GenerateProof(c, secrets)
+		if err != nil {
+			b.Fatalf("err: %v", err)
+		}
+		ctxs = append(ctxs, c)
+		proofs = append(proofs, *proof[0])
+	}
+
+	b.Run("single", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for j := range proofs {
+				verify, err := zk.VerifyProof(ctxs[j], proofs[j])
+				if !verify {
+					b.Fatalf("verification failed: %v", err)
+				}
+			}
+		}
+	})
+	b.Run("batch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			valid, errs := zk.VerifyBatch(ctxs, proofs)
+			for j := range valid {
+				if !valid[j] {
+					b.Fatalf("verification failed: %v", errs[j])
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,387 @@
+package ligero
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+)
+
+// CodecVersion is the first byte of every binary encoded Proof, Shares and OpenedColumn.
+// Version 2 adds the leaf version of proofs, version 1 proofs are decoded with LeafString leaves.
+// Version 3 adds the multi-proof of the opened columns.
+const CodecVersion = 3
+
+// ContentTypeBinary is the Content-Type of binary encoded client requests
+const ContentTypeBinary = "application/x-ligero-proof"
+
+// The binary encoding starts with the codec version and the bit width w of field elements.
+// Lengths, indices and byte strings are uvarint length-prefixed, vectors of field elements are
+// packed at w bits per element, w = ceil(log2 q) for proofs generated with modulus q. Every element is
+// in [0, q), the decoder refuses larger ones.
+
+type encoder struct {
+	buf   []byte
+	width int
+}
+
+type decoder struct {
+	data    []byte
+	pos     int
+	width   int
+	version int
+	q       int
+	err     error
+}
+
+// field_width returns the number of bits ceil(log2 q) of the elements of the field with modulus q
+func field_width(q int) (int, error) {
+	if q < 2 || q > 1<<62 {
+		return 0, fmt.Errorf("%w: cannot encode elements of the field with modulus %d", ErrInvalidEncoding, q)
+	}
+	return bits.Len(uint(q - 1)), nil
+}
+
+// check_field fails unless all values of the vectors are elements of the field with modulus q
+func check_field(q int, vectors ...[]int) error {
+	for _, vector := range vectors {
+		for _, value := range vector {
+			if value < 0 || value >= q {
+				return fmt.Errorf("%w: cannot encode %d as an element of the field with modulus %d", ErrInvalidEncoding, value, q)
+			}
+		}
+	}
+	return nil
+}
+
+// newEncoder returns an encoder of the elements of the field with modulus q
+func newEncoder(q int) (*encoder, error) {
+	width, err := field_width(q)
+	if err != nil {
+		return nil, err
+	}
+	return &encoder{width: width}, nil
+}
+
+func (e *encoder) header() {
+	e.buf = append(e.buf, CodecVersion, byte(e.width))
+}
+
+func (e *encoder) uvarint(value int) {
+	e.buf = binary.AppendUvarint(e.buf, uint64(value))
+}
+
+func (e *encoder) bytes(data []byte) {
+	e.uvarint(len(data))
+	e.buf = append(e.buf, data...)
+}
+
+func (e *encoder) bytes_list(list [][]byte) {
+	e.uvarint(len(list))
+	for _, data := range list {
+		e.bytes(data)
+	}
+}
+
+func (e *encoder) uvarints(list []int) {
+	e.uvarint(len(list))
+	for _, value := range list {
+		e.uvarint(value)
+	}
+}
+
+func (e *encoder) field(vector []int) {
+	e.uvarint(len(vector))
+	packed := make([]byte, (len(vector)*e.width+7)/8)
+	pos := 0
+	for _, value := range vector {
+		for b := 0; b < e.width; b++ {
+			if (value>>b)&1 == 1 {
+				packed[pos/8] |= 1 << (pos % 8)
+			}
+			pos++
+		}
+	}
+	e.buf = append(e.buf, packed...)
+}
+
+// newDecoder returns a decoder of the elements of the field with modulus q
+func newDecoder(data []byte, q int) *decoder {
+	d := &decoder{data: data, q: q}
+	width, err := field_width(q)
+	if err != nil {
+		d.err = err
+		return d
+	}
+	if len(data) < 2 {
+		d.err = fmt.Errorf("binary encoding is too short")
+		return d
+	}
+	if data[0] == 0 || data[0] > CodecVersion {
+		d.err = fmt.Errorf("unsupported codec version %d", data[0])
+		return d
+	}
+	if int(data[1]) != width {
+		d.err = fmt.Errorf("invalid field element width %d for modulus %d", data[1], q)
+		return d
+	}
+	d.version = int(data[0])
+	d.width = int(data[1])
+	d.pos = 2
+	return d
+}
+
+func (d *decoder) uvarint() int {
+	if d.err != nil {
+		return 0
+	}
+	value, n := binary.Uvarint(d.data[d.pos:])
+	if n <= 0 || value > 1<<31 {
+		d.err = fmt.Errorf("invalid length or index at byte %d", d.pos)
+		return 0
+	}
+	d.pos += n
+	return int(value)
+}
+
+// length decodes the number of following elements, each taking at least min_bits bits
+func (d *decoder) length(min_bits int) int {
+	n := d.uvarint()
+	if d.err == nil && n*min_bits > (len(d.data)-d.pos)*8 {
+		d.err = fmt.Errorf("length %d exceeds the encoding at byte %d", n, d.pos)
+		return 0
+	}
+	return n
+}
+
+func (d *decoder) bytes() []byte {
+	n := d.length(8)
+	if d.err != nil || n == 0 {
+		return nil
+	}
+	data := make([]byte, n)
+	copy(data, d.data[d.pos:d.pos+n])
+	d.pos += n
+	return data
+}
+
+func (d *decoder) bytes_list() [][]byte {
+	n := d.length(8)
+	if d.err != nil || n == 0 {
+		return nil
+	}
+	list := make([][]byte, n)
+	for i := range list {
+		list[i] = d.bytes()
+	}
+	return list
+}
+
+func (d *decoder) uvarints() []int {
+	n := d.length(8)
+	list := make([]int, n)
+	for i := range list {
+		list[i] = d.uvarint()
+	}
+	return list
+}
+
+func (d *decoder) field() []int {
+	n := d.length(d.width)
+	if d.err != nil {
+		return nil
+	}
+	vector := make([]int, n)
+	pos := 0
+	for i := range vector {
+		for b := 0; b < d.width; b++ {
+			if (d.data[d.pos+pos/8]>>(pos%8))&1 == 1 {
+				vector[i] |= 1 << b
+			}
+			pos++
+		}
+		if vector[i] >= d.q {
+			d.err = fmt.Errorf("field element %d at byte %d is not below the modulus %d", vector[i], d.pos+pos/8, d.q)
+			return nil
+		}
+	}
+	d.pos += (n*d.width + 7) / 8
+	return vector
+}
+
+func (d *decoder) finish() error {
+	if d.err == nil && d.pos != len(d.data) {
+		d.err = fmt.Errorf("%d trailing bytes", len(d.data)-d.pos)
+	}
+	if d.err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidEncoding, d.err)
+	}
+	return nil
+}
+
+func (s *Shares) encode(e *encoder) {
+	e.uvarint(s.PartyIndex)
+	e.uvarints(s.Index)
+	e.uvarint(len(s.Values))
+	for _, values := range s.Values {
+		e.field(values)
+	}
+}
+
+func (s *Shares) decode(d *decoder) {
+	s.PartyIndex = d.uvarint()
+	s.Index = d.uvarints()
+	s.Values = make([][]int, d.length(8))
+	for i := range s.Values {
+		s.Values[i] = d.field()
+	}
+}
+
+func (s *Shares) check_field(q int) error {
+	return check_field(q, s.Values...)
+}
+
+// masks returns the nonce and the masks of the first repetition followed by the masks of the others
+func (col *OpenedColumn) masks() []int {
+	return append([]int{col.Merkle_nonce, col.Code_mask, col.Linear_mask, col.Quadra_mask, col.Sum_mask}, col.Repeated_masks...)
+}
+
+func (col *OpenedColumn) encode(e *encoder) {
+	e.uvarint(col.Index)
+	e.field(col.List)
+	e.field(col.masks())
+	e.bytes_list(col.Authpath)
+}
+
+func (col *OpenedColumn) decode(d *decoder) {
+	col.Index = d.uvarint()
+	col.List = d.field()
+	masks := d.field()
+	if d.err == nil && (len(masks) < 5 || (len(masks)-5)%4 != 0) {
+		d.err = fmt.Errorf("opened column %d has %d masks", col.Index, len(masks))
+		return
+	}
+	if d.err == nil {
+		col.Merkle_nonce, col.Code_mask, col.Linear_mask, col.Quadra_mask, col.Sum_mask = masks[0], masks[1], masks[2], masks[3], masks[4]
+		if len(masks) > 5 {
+			col.Repeated_masks = masks[5:]
+		}
+	}
+	col.Authpath = d.bytes_list()
+}
+
+func (col *OpenedColumn) check_field(q int) error {
+	return check_field(q, col.List, col.masks())
+}
+
+// MarshalBinary encodes the shares of a party for the field with modulus q
+func (s Shares) MarshalBinary(q int) ([]byte, error) {
+	e, err := newEncoder(q)
+	if err != nil {
+		return nil, err
+	}
+	err = s.check_field(q)
+	if err != nil {
+		return nil, err
+	}
+	e.header()
+	s.encode(e)
+	return e.buf, nil
+}
+
+// UnmarshalBinary decodes shares encoded by MarshalBinary for the field with modulus q
+func (s *Shares) UnmarshalBinary(data []byte, q int) error {
+	d := newDecoder(data, q)
+	s.decode(d)
+	return d.finish()
+}
+
+// MarshalBinary encodes an opened column for the field with modulus q
+func (col OpenedColumn) MarshalBinary(q int) ([]byte, error) {
+	e, err := newEncoder(q)
+	if err != nil {
+		return nil, err
+	}
+	err = col.check_field(q)
+	if err != nil {
+		return nil, err
+	}
+	e.header()
+	col.encode(e)
+	return e.buf, nil
+}
+
+// UnmarshalBinary decodes an opened column encoded by MarshalBinary for the field with modulus q
+func (col *OpenedColumn) UnmarshalBinary(data []byte, q int) error {
+	d := newDecoder(data, q)
+	col.decode(d)
+	return d.finish()
+}
+
+// MarshalBinary encodes a proof for the field with modulus q, all field elements are packed at ceil(log2 q) bits
+func (p Proof) MarshalBinary(q int) ([]byte, error) {
+	e, err := newEncoder(q)
+	if err != nil {
+		return nil, err
+	}
+	err = check_field(q, p.CodeTest, p.QuadraTest, p.LinearTest, p.SumTest, p.Seeds)
+	if err != nil {
+		return nil, err
+	}
+	err = p.Shares.check_field(q)
+	if err != nil {
+		return nil, err
+	}
+	for i := range p.ColumnTest {
+		err = p.ColumnTest[i].check_field(q)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	e.header()
+	e.uvarint(p.Leaf_version)
+	e.bytes(p.MerkleRoot)
+	e.uvarint(len(p.ColumnTest))
+	for i := range p.ColumnTest {
+		p.ColumnTest[i].encode(e)
+	}
+	e.field(p.CodeTest)
+	e.field(p.QuadraTest)
+	e.field(p.LinearTest)
+	e.field(p.SumTest)
+	p.Shares.encode(e)
+	e.field(p.Seeds)
+	e.bytes(p.FST_root)
+	e.bytes_list(p.FST_authpath)
+	e.bytes_list(p.Multiproof)
+	return e.buf, nil
+}
+
+// UnmarshalBinary decodes a proof encoded by MarshalBinary for the field with modulus q
+func (p *Proof) UnmarshalBinary(data []byte, q int) error {
+	d := newDecoder(data, q)
+	p.Leaf_version = LeafString
+	if d.version >= 2 {
+		p.Leaf_version = d.uvarint()
+	}
+	p.MerkleRoot = d.bytes()
+	p.ColumnTest = make([]OpenedColumn, d.length(8))
+	for i := range p.ColumnTest {
+		p.ColumnTest[i].decode(d)
+	}
+	p.CodeTest = d.field()
+	p.QuadraTest = d.field()
+	p.LinearTest = d.field()
+	p.SumTest = d.field()
+	if len(p.SumTest) == 0 {
+		p.SumTest = nil
+	}
+	p.Shares.decode(d)
+	p.Seeds = d.field()
+	p.FST_root = d.bytes()
+	p.FST_authpath = d.bytes_list()
+	if d.version >= 3 {
+		p.Multiproof = d.bytes_list()
+	}
+	return d.finish()
+}
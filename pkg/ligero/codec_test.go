@@ -0,0 +1,133 @@
+package ligero
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestProofBinaryRoundTrip(t *testing.T) {
+	for _, pred := range []Predicate{BitPredicate(), OneHotPredicate()} {
+		zk, err := NewLigeroZK(6, 2, 4, 1, 10631, 3, pred)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		proofs, err := zk.GenerateProof(ctx, []int{0, 0, 1, 0, 0, 0})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		data, err := proofs[1].MarshalBinary(zk.q)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		var decoded Proof
+		err = decoded.UnmarshalBinary(data, zk.q)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		if !reflect.DeepEqual(*proofs[1], decoded) {
+			t.Fatalf("decoded proof differs from the encoded one")
+		}
+
+		verify, err := zk.VerifyProof(ctx, decoded)
+		if !verify {
+			t.Fatalf("verification of decoded proof failed: %v", err)
+		}
+
+		json_data, _ := json.Marshal(proofs[1])
+		if len(data) >= len(json_data)/2 {
+			t.Errorf("binary proof of %d bytes is not much smaller than json proof of %d bytes", len(data), len(json_data))
+		}
+
+		// truncated or extended encodings are rejected
+		for _, invalid := range [][]byte{data[:len(data)-1], append(append([]byte{}, data...), 0), data[:1]} {
+			if decoded.UnmarshalBinary(invalid, zk.q) == nil {
+				t.Fatalf("decoding of invalid proof with %d bytes succeeded", len(invalid))
+			}
+		}
+	}
+}
+
+func TestProofBinaryRoundTripRepetitions(t *testing.T) {
+	zk, err := NewLigeroZKFromParams(Params{N_secret: 6, M: 2, N_server: 4, T: 1, Q: 10631, N_open: 3, Repetitions: 2}, OneHotPredicate())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	proofs, err := zk.GenerateProof(ctx, []int{0, 0, 1, 0, 0, 0})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	data, err := proofs[0].MarshalBinary(zk.q)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	var decoded Proof
+	err = decoded.UnmarshalBinary(data, zk.q)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(*proofs[0], decoded) {
+		t.Fatalf("decoded proof with repeated masks differs from the encoded one")
+	}
+
+	verify, err := zk.VerifyProof(ctx, decoded)
+	if !verify {
+		t.Fatalf("verification of decoded proof failed: %v", err)
+	}
+}
+
+func TestSharesBinaryRoundTrip(t *testing.T) {
+	shares := Shares{Index: []int{0, 2, 3}, Values: [][]int{{5, 0, 41}, {1, 2, 3}}, PartyIndex: 2}
+	data, err := shares.MarshalBinary(61)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	var decoded Shares
+	err = decoded.UnmarshalBinary(data, 61)
+	if err != nil || !reflect.DeepEqual(shares, decoded) {
+		t.Fatalf("expected %v, but got %v (%v)", shares, decoded, err)
+	}
+
+	// field elements are packed at ceil(log2 q) bits, not at the width of the largest element
+	if data[1] != 6 {
+		t.Fatalf("expected width 6, but got %d", data[1])
+	}
+	data, err = Shares{Values: [][]int{{1}}}.MarshalBinary(10631)
+	if err != nil || data[1] != 14 {
+		t.Fatalf("expected width 14, but got %v (%v)", data, err)
+	}
+
+	// elements of another field are refused
+	data, _ = shares.MarshalBinary(61)
+	if decoded.UnmarshalBinary(data, 10631) == nil {
+		t.Fatalf("decoding at the width of another modulus succeeded")
+	}
+	if decoded.UnmarshalBinary(data, 41) == nil {
+		t.Fatalf("decoding of an element above the modulus succeeded")
+	}
+	data, _ = Shares{Values: [][]int{{63}}}.MarshalBinary(64)
+	if err := decoded.UnmarshalBinary(data, 61); !errors.Is(err, ErrInvalidEncoding) {
+		t.Fatalf("expected ErrInvalidEncoding for an element above the modulus, got %v", err)
+	}
+
+	data[0] = CodecVersion + 1
+	if decoded.UnmarshalBinary(data, 64) == nil {
+		t.Fatalf("decoding of unknown version succeeded")
+	}
+
+	for _, values := range [][]int{{-1}, {61}} {
+		_, err = Shares{Values: [][]int{values}}.MarshalBinary(61)
+		if !errors.Is(err, ErrInvalidEncoding) {
+			t.Fatalf("expected ErrInvalidEncoding for encoding %v, got %v", values, err)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package ligero
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors of the package wrap one of the following, callers tell them apart with errors.Is, e.g. to reject
+// the request of a client with a malformed proof instead of stopping the server
+var (
+	// ErrInvalidParams is returned for parameters a LigeroZK or a predicate cannot be created with
+	ErrInvalidParams = errors.New("invalid parameters")
+	// ErrDimensionMismatch is returned for inputs whose lengths do not fit the parameters
+	ErrDimensionMismatch = errors.New("dimension mismatch")
+	// ErrInvalidProof is returned for proofs that fail verification
+	ErrInvalidProof = errors.New("invalid proof")
+	// ErrInvalidEncoding is returned for binary encodings that cannot be decoded
+	ErrInvalidEncoding = errors.New("invalid encoding")
+)
+
+// invalid_proof wraps the error of a failed verification in ErrInvalidProof
+func invalid_proof(err error) error {
+	if err == nil {
+		return ErrInvalidProof
+	}
+	if errors.Is(err, ErrInvalidProof) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrInvalidProof, err)
+}
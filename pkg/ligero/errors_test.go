@@ -0,0 +1,68 @@
+package ligero
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestErrors(t *testing.T) {
+	_, err := NewLigeroZK(3, 0, 4, 1, 10631, 3, BitPredicate())
+	if !errors.Is(err, ErrInvalidParams) {
+		t.Fatalf("expected ErrInvalidParams for m = 0, got %v", err)
+	}
+	_, err = NewLigeroZK(3, 2, 4, 1, 10631, 3, BitPredicate())
+	if !errors.Is(err, ErrInvalidParams) {
+		t.Fatalf("expected ErrInvalidParams for n_secrets not a multiple of m, got %v", err)
+	}
+	_, err = NewLigeroZK(3, 1, 4, 1, 10630, 3, BitPredicate())
+	if !errors.Is(err, ErrInvalidParams) {
+		t.Fatalf("expected ErrInvalidParams for q not a prime, got %v", err)
+	}
+	_, err = ParsePredicate("range:x")
+	if !errors.Is(err, ErrInvalidParams) {
+		t.Fatalf("expected ErrInvalidParams for an invalid predicate, got %v", err)
+	}
+
+	zk, err := NewLigeroZK(3, 1, 4, 1, 10631, 3, BitPredicate())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// the prover returns an error instead of exiting
+	_, err = zk.GenerateProof(ctx, []int{1, 0})
+	if !errors.Is(err, ErrDimensionMismatch) {
+		t.Fatalf("expected ErrDimensionMismatch for too few secrets, got %v", err)
+	}
+
+	proof, err := zk.GenerateProof(ctx, []int{1, 0, 1})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// malformed and tampered proofs fail with ErrInvalidProof
+	truncated := *proof[0]
+	truncated.ColumnTest = truncated.ColumnTest[1:]
+	tampered := *proof[0]
+	tampered.Shares.Values = nil
+	for _, p := range []Proof{truncated, tampered, {}} {
+		verify, err := zk.VerifyProof(ctx, p)
+		if verify || !errors.Is(err, ErrInvalidProof) {
+			t.Fatalf("expected ErrInvalidProof, got %v", err)
+		}
+	}
+
+	valid, errs := zk.VerifyBatch([]Context{ctx, ctx}, []Proof{*proof[0], tampered})
+	if !valid[0] || valid[1] || !errors.Is(errs[1], ErrInvalidProof) {
+		t.Fatalf("expected ErrInvalidProof for the tampered proof of the batch, got %v", errs)
+	}
+
+	data, err := proof[0].MarshalBinary(zk.q)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var decoded Proof
+	err = decoded.UnmarshalBinary(data[:len(data)/2], zk.q)
+	if !errors.Is(err, ErrInvalidEncoding) {
+		t.Fatalf("expected ErrInvalidEncoding for a truncated encoding, got %v", err)
+	}
+}
@@ -0,0 +1,38 @@
+package ligero
+
+import (
+	"fmt"
+	"math"
+)
+
+// EncodeFixedPoint scales real values by 2^frac_bits and rounds them to the nearest integer,
+// so that they can be shared and proven as signed secrets, e.g. with a norm predicate
+func EncodeFixedPoint(values []float64, frac_bits int) ([]int, error) {
+	if frac_bits < 0 || frac_bits > 52 {
+		return nil, fmt.Errorf("%w: frac_bits has to lie in [0, 52]", ErrInvalidParams)
+	}
+
+	secrets := make([]int, len(values))
+	for i, value := range values {
+		scaled := math.Round(math.Ldexp(value, frac_bits))
+		if math.IsNaN(scaled) || math.Abs(scaled) >= math.Ldexp(1, 62) {
+			return nil, fmt.Errorf("%w: value %v cannot be encoded with %d fractional bits", ErrInvalidParams, value, frac_bits)
+		}
+		secrets[i] = int(scaled)
+	}
+	return secrets, nil
+}
+
+// DecodeFixedPoint maps (aggregated) fixed-point values modulo q back to real values,
+// values above q/2 are the negative ones
+func DecodeFixedPoint(values []int, frac_bits int, q int) []float64 {
+	result := make([]float64, len(values))
+	for i, value := range values {
+		value = mod(value, q)
+		if value > q/2 {
+			value -= q
+		}
+		result[i] = math.Ldexp(float64(value), -frac_bits)
+	}
+	return result
+}
@@ -0,0 +1,54 @@
+package ligero
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFixedPoint(t *testing.T) {
+	q := 10631
+	values := []float64{-1.5, 0.25, 3.1, 0, -0.01}
+
+	secrets, err := EncodeFixedPoint(values, 4)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	expected := []int{-24, 4, 50, 0, 0}
+	for i := range expected {
+		if secrets[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected, secrets)
+		}
+	}
+
+	// values are decoded from their representatives modulo q
+	for i := range secrets {
+		secrets[i] = mod(secrets[i], q)
+	}
+	decoded := DecodeFixedPoint(secrets, 4, q)
+	for i, value := range values {
+		if math.Abs(decoded[i]-value) > 1.0/32 {
+			t.Fatalf("expected %v, got %v", values, decoded)
+		}
+	}
+
+	// the sum of shared fixed-point values decodes to the sum of the values
+	sum := mod(mod(-24, q)+mod(-40, q), q)
+	if got := DecodeFixedPoint([]int{sum}, 4, q)[0]; got != -4 {
+		t.Fatalf("expected -4, got %v", got)
+	}
+
+	for _, test := range []struct {
+		values    []float64
+		frac_bits int
+	}{
+		{[]float64{math.NaN()}, 4},
+		{[]float64{math.Inf(-1)}, 4},
+		{[]float64{1e30}, 4},
+		{[]float64{1}, -1},
+	} {
+		_, err := EncodeFixedPoint(test.values, test.frac_bits)
+		if err == nil {
+			t.Fatalf("expected error for %v with %d fractional bits", test.values, test.frac_bits)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package ligero
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update_kat = flag.Bool("update-kat", false, "rewrite the known-answer vectors in testdata")
+
+const kat_file = "testdata/kat.json"
+
+// kat_vector is a known-answer vector: the proofs, binary encoded, of the secrets under the parameters when the
+// prover reads its randomness from a CryptoRandSource seeded with Seed, and whether they verify
+type kat_vector struct {
+	Name      string   `json:"Name"`
+	Params    Params   `json:"Params"`
+	Predicate string   `json:"Predicate"`
+	Exp_ID    string   `json:"Exp_ID"`
+	Client_ID string   `json:"Client_ID"`
+	Seed      string   `json:"Seed"`
+	Secrets   []int    `json:"Secrets"`
+	Valid     bool     `json:"Valid"`
+	Proofs    []string `json:"Proofs"`
+}
+
+var kat_inputs = []kat_vector{
+	{Name: "bit", Params: Params{N_secret: 3, M: 1, N_server: 4, T: 1, Q: 10631, N_open: 3}, Predicate: "bit", Secrets: []int{1, 0, 1}, Valid: true},
+	{Name: "bit with a secret out of range", Params: Params{N_secret: 3, M: 1, N_server: 4, T: 1, Q: 10631, N_open: 3}, Predicate: "bit", Secrets: []int{2, 0, 1}, Valid: false},
+	{Name: "onehot repeated", Params: Params{N_secret: 6, M: 2, N_server: 4, T: 1, Q: 10631, N_open: 3, Repetitions: 2}, Predicate: "onehot", Secrets: []int{0, 0, 1, 0, 0, 0}, Valid: true},
+	{Name: "range and sum bound with the NTT", Params: Params{N_secret: 6, M: 2, N_server: 4, T: 1, Q: 998244353, N_open: 3}, Predicate: "range:4+atmost:20", Secrets: []int{15, 5, 0, 0, 0, 0}, Valid: true},
+}
+
+func (v *kat_vector) prover(t *testing.T) (*LigeroZK, Context) {
+	pred, err := ParsePredicate(v.Predicate)
+	if err != nil {
+		t.Fatalf("%s: err: %v", v.Name, err)
+	}
+	crs := NewCryptoRandSource()
+	crs.Seed(v.Seed)
+	p := v.Params
+	p.Rand = &crs
+	zk, err := NewLigeroZKFromParams(p, pred)
+	if err != nil {
+		t.Fatalf("%s: err: %v", v.Name, err)
+	}
+	return zk, Context{Exp_ID: v.Exp_ID, Client_ID: v.Client_ID}
+}
+
+func (v *kat_vector) generate(t *testing.T) []string {
+	zk, c := v.prover(t)
+	proofs, err := zk.GenerateProof(c, v.Secrets)
+	if err != nil {
+		t.Fatalf("%s: err: %v", v.Name, err)
+	}
+	encoded := make([]string, len(proofs))
+	for i, proof := range proofs {
+		data, err := proof.MarshalBinary(v.Params.Q)
+		if err != nil {
+			t.Fatalf("%s: err: %v", v.Name, err)
+		}
+		encoded[i] = hex.EncodeToString(data)
+	}
+	return encoded
+}
+
+func TestKnownAnswers(t *testing.T) {
+	if *update_kat {
+		vectors := make([]kat_vector, len(kat_inputs))
+		for i, v := range kat_inputs {
+			v.Exp_ID, v.Client_ID, v.Seed = "exp1", "c1", "kat "+v.Name
+			v.Proofs = v.generate(t)
+			vectors[i] = v
+		}
+		data, err := json.MarshalIndent(vectors, "", "  ")
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if err := os.MkdirAll(filepath.Dir(kat_file), 0755); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if err := os.WriteFile(kat_file, append(data, '\n'), 0644); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(kat_file)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var vectors []kat_vector
+	if err := json.Unmarshal(data, &vectors); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(vectors) != len(kat_inputs) {
+		t.Fatalf("expected %d vectors, got %d", len(kat_inputs), len(vectors))
+	}
+
+	for _, v := range vectors {
+		// the same seed reproduces the proofs
+		generated := v.generate(t)
+		for i := range generated {
+			if generated[i] != v.Proofs[i] {
+				t.Fatalf("%s: proof of server %d differs from the known answer", v.Name, i)
+			}
+		}
+
+		zk, c := v.prover(t)
+		for i, encoded := range v.Proofs {
+			raw, err := hex.DecodeString(encoded)
+			if err != nil {
+				t.Fatalf("%s: err: %v", v.Name, err)
+			}
+			var proof Proof
+			if err := proof.UnmarshalBinary(raw, v.Params.Q); err != nil {
+				t.Fatalf("%s: err: %v", v.Name, err)
+			}
+			verify, err := zk.VerifyProof(c, proof)
+			if verify != v.Valid {
+				t.Fatalf("%s: expected verification %v for server %d, got %v (%v)", v.Name, v.Valid, i, verify, err)
+			}
+		}
+	}
+}
@@ -1,20 +1,14 @@
 package ligero
 
 import (
-	crypto_rand "crypto/rand"
-	"crypto/sha256"
 	"fmt"
-	"log"
+	"io"
 	"math"
-	"math/big"
 	"sync"
 
-	"strings"
-
+	"example.com/SMC/pkg/field"
 	"example.com/SMC/pkg/packed"
-	"example.com/SMC/pkg/rss"
 	merkletree "github.com/wealdtech/go-merkletree"
-	"gonum.org/v1/gonum/stat/combin"
 )
 
 // n_claim: number of claims
@@ -23,23 +17,93 @@ import (
 // l: columns number of rearranged input vector in the m*l matrix, where n_i = m*l
 // t: the maximum number of shares that may be seen without learning anything about the secret;
 // use in the secret sharing of each input value
-// q: a modulus
+// q: a prime modulus below 2^63
+// sharing: scheme the secrets are shared among the servers with, SharingRSS or SharingShamir
+// n_shares: number of shares a secret splits into
+// field: arithmetic modulo q
 // n_encode:the number of shares that each row of rearranged input vector is split into
 // n_open_col: number of opened columns
+// pred: validity predicate every input vector has to satisfy
+// n_aux: number of auxiliary rows of the predicate appended to each block of the extended witness
+// n_extra: number of rows of the predicate appended after the last block
+// repetitions: number of independent challenges of the code, quadratic, linear and sum tests
+// entry_weights: values at the columns of the lagrange basis polynomials of the entries selected by entry terms
+// rand: source of the randomness of the prover
+//
+// A LigeroZK is immutable after construction, one instance can generate and verify proofs in concurrent goroutines
+// as long as its reader is safe for concurrent use, as crypto/rand is.
 
 type LigeroZK struct {
 	npss                     *packed.PackedSecretSharing
 	glob_constants           GlobConstants
 	glob_constants_code_test GlobConstantsCodeTest
+	glob_constants_quadra    GlobConstantsCodeTest
 	n_secret                 int
+	sharing                  string
 	n_shares                 int
 	m                        int
 	l                        int
 	n_server                 int
 	t                        int
 	q                        int
+	field                    *field.Field
 	n_encode                 int
 	n_open_col               int
+	repetitions              int
+	pred                     Predicate
+	n_aux                    int
+	n_extra                  int
+	quadratic                [][3]int
+	linear                   [][]rowTerm
+	sums                     []sumConstraint
+	entry_weights            map[int][]int
+	rand                     io.Reader
+}
+
+// test_masks are the masks of one repetition of the tests, sum is nil without sum constraints
+type test_masks struct {
+	code   []int
+	quadra []int
+	linear []int
+	sum    []int
+}
+
+// column returns the masks at a column in the order of OpenedColumn: code, linear, quadratic and sum
+func (m test_masks) column(index int) []int {
+	sum := 0
+	if m.sum != nil {
+		sum = m.sum[index]
+	}
+	return []int{m.code[index], m.linear[index], m.quadra[index], sum}
+}
+
+// column_masks returns the masks of all repetitions at a column, in the order of OpenedColumn.leaf_masks
+func column_masks(masks []test_masks, index int) []int {
+	result := make([]int, 0, 4*len(masks))
+	for _, m := range masks {
+		result = append(result, m.column(index)...)
+	}
+	return result
+}
+
+// rowTerm is a row of the extended witness multiplied by a coefficient in [0, q)
+type rowTerm struct {
+	row   int
+	coeff int
+}
+
+// sumConstraint requires the sum of its terms over all entries plus its entry terms to equal value
+type sumConstraint struct {
+	terms   []rowTerm
+	entries []entryTerm
+	value   int
+}
+
+// entryTerm is an entry of a row of the extended witness multiplied by a coefficient in [0, q)
+type entryTerm struct {
+	row   int
+	entry int
+	coeff int
 }
 
 type Claim struct {
@@ -47,123 +111,451 @@ type Claim struct {
 	Secret int
 }
 
-func NewLigeroZK(N_secret, M, N_server, T, Q, N_open int) (*LigeroZK, error) {
+// NewLigeroZK creates a prover/verifier for input vectors satisfying the predicate pred,
+// e.g. BitPredicate() for 0/1 input vectors
+func NewLigeroZK(N_secret, M, N_server, T, Q, N_open int, pred Predicate) (*LigeroZK, error) {
+	return NewLigeroZKFromParams(Params{N_secret: N_secret, M: M, N_server: N_server, T: T, Q: Q, N_open: N_open, Repetitions: 1}, pred)
+}
+
+// NewLigeroZKFromParams creates a prover/verifier with the parameters p, p.Repetitions > 1 repeats the tests
+// with independent challenges to reach a higher soundness in small fields, see ComputeSoundness. The prover
+// draws its randomness from p.Rand, a deterministic reader reproduces its proofs.
+func NewLigeroZKFromParams(p Params, pred Predicate) (*LigeroZK, error) {
+	N_secret, M, N_server, T, Q, N_open := p.N_secret, p.M, p.N_server, p.T, p.Q, p.N_open
+
 	// m has to larger than 0
 	if M <= 0 {
-		return nil, fmt.Errorf("m cannot be less than 1")
+		return nil, fmt.Errorf("%w: m cannot be less than 1", ErrInvalidParams)
 	}
 
 	if M > N_secret {
-		return nil, fmt.Errorf("m cannot be larger than n_secrets")
+		return nil, fmt.Errorf("%w: m cannot be larger than n_secrets", ErrInvalidParams)
+	}
+
+	// the secrets fill m rows of l secrets each
+	if N_secret%M != 0 {
+		return nil, fmt.Errorf("%w: n_secrets has to be a multiple of m", ErrInvalidParams)
 	}
 
 	if 3*T+1 > N_server {
-		return nil, fmt.Errorf("n_server cannot be less than 3t+1")
+		return nil, fmt.Errorf("%w: n_server cannot be less than 3t+1", ErrInvalidParams)
+	}
+
+	if T < 0 {
+		return nil, fmt.Errorf("%w: t cannot be negative", ErrInvalidParams)
 	}
 
 	if N_open <= 0 {
-		return nil, fmt.Errorf("n_open cannot be less than 1")
+		return nil, fmt.Errorf("%w: n_open cannot be less than 1", ErrInvalidParams)
+	}
+
+	R := p.repetitions()
+	if R <= 0 {
+		return nil, fmt.Errorf("%w: repetitions cannot be less than 1", ErrInvalidParams)
+	}
+
+	if pred == nil {
+		return nil, fmt.Errorf("%w: predicate cannot be nil", ErrInvalidParams)
+	}
+
+	// q has to be a prime below 2^63, field elements are stored as int
+	f, err := field.NewInt(Q)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidParams, err)
 	}
 
 	//compute total number of shares a secret splits to
-	N_shares := combin.Binomial(N_server, T)
+	N_shares, err := n_shares(p.Sharing, N_server, T)
+	if err != nil {
+		return nil, err
+	}
+	if p.Sharing == SharingShamir && Q <= N_server {
+		return nil, fmt.Errorf("%w: q has to be larger than n_server for shamir sharing", ErrInvalidParams)
+	}
+
+	L, N_encode, _, ntt := code_size(N_secret, M, N_open, Q)
+
+	err = pred.Validate(N_secret, L, pred.MaxValue(), Q)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidParams, err)
+	}
 
+	var pss *packed.PackedSecretSharing
+	if ntt {
+		pss, err = packed.NewNTTPackedSecretSharingWithReader(N_encode, N_open, L, Q, p.rand())
+	} else {
+		pss, err = packed.NewPackedSecretSharingWithReader(N_encode, N_open, L, Q, p.rand())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidParams, err)
+	}
+
+	zk := &LigeroZK{n_secret: N_secret, sharing: p.Sharing, n_shares: N_shares, m: M, l: L, n_server: N_server, t: T, q: Q, field: f, n_encode: N_encode, n_open_col: N_open, repetitions: R, pred: pred, n_aux: pred.AuxRows(), n_extra: pred.ExtraRows(), npss: pss, rand: p.rand()}
+	err = zk.instantiate_constraints()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidParams, err)
+	}
+	zk.precompute_lagrange()
+
+	return zk, nil
+}
+
+// precompute_lagrange computes the basis polynomials the verifier of the lagrange encoding interpolates with,
+// a LigeroZK is not modified afterwards and can be shared by goroutines. With the NTT none are needed.
+func (zk *LigeroZK) precompute_lagrange() {
+	if zk.npss.NTT() {
+		return
+	}
+
+	columns := make([]int, zk.n_encode)
+	for i := range columns {
+		columns[i] = zk.npss.SharePoint(i)
+	}
+	slots := make([]int, zk.l)
+	for j := range slots {
+		slots[j] = zk.npss.SecretPoint(j)
+	}
+	zk.glob_constants = GlobConstants{basis: lagrange_table(zk.field, columns, slots)}
+
+	// the code test is interpolated from the first Degree() columns and evaluated at the others
+	degree := zk.npss.Degree()
+	zk.glob_constants_code_test = GlobConstantsCodeTest{basis: lagrange_table(zk.field, columns[:degree], columns[degree:])}
+
+	// the quadratic test has twice the degree, it is interpolated from the first 2*Degree()-1 columns
+	zk.glob_constants_quadra = GlobConstantsCodeTest{basis: lagrange_table(zk.field, columns[:2*degree-1], columns[2*degree-1:])}
+}
+
+// code_size returns l, the number of columns n_encode of the encoded extended witness, the number of
+// coefficients of an encoded row and whether rows are encoded with the NTT. For NTT-friendly q rows are
+// encoded on 8d columns, d the power of two above n_open+l, which keeps the rate of the code below 1/6.
+func code_size(N_secret, M, N_open, Q int) (int, int, int, bool) {
 	// Calculate l as the upper ceiling of len(slice) divided by m
 	L := int(math.Ceil(float64(N_secret) / float64(M)))
 
-	N_encode := 6*N_open + 6*L + 1
+	d := 1
+	for d < N_open+L {
+		d *= 2
+	}
+	if packed.NTTFriendly(Q, 8*d) {
+		return L, 8 * d, d, true
+	}
+	return L, 6*N_open + 6*L + 1, N_open + L, false
+}
 
-	pss, err := packed.NewPackedSecretSharing(N_encode, N_open, L, Q)
-	if err != nil {
-		log.Fatal(err)
+// code_stride returns the distance between the columns sent in the code test: with the NTT every
+// stride-th column so that the verifier interpolates on a subgroup, otherwise the first n_open+l columns
+func (zk *LigeroZK) code_stride() int {
+	if zk.npss.NTT() {
+		return zk.n_encode / zk.npss.Degree()
 	}
+	return 1
+}
 
-	gc := GlobConstants{flag_num: make([]bool, Q), values_num: make([][]int, Q), flag_denom: false, values_denom: make([]int, Q)}
-	gc_codetest := GlobConstantsCodeTest{flag_num: make([]bool, Q), values_num: make([][]int, Q), flag_denom: false, values_denom: make([]int, Q)}
+// block_size returns the number of rows of the extended witness that belong to one row of secrets:
+// the secret row, its shares and the auxiliary rows of the predicate
+func (zk *LigeroZK) block_size() int {
+	return 1 + zk.n_shares + zk.n_aux
+}
 
-	return &LigeroZK{n_secret: N_secret, n_shares: N_shares, m: M, l: L, n_server: N_server, t: T, q: Q, n_encode: N_encode, n_open_col: N_open, npss: pss, glob_constants: gc, glob_constants_code_test: gc_codetest}, nil
+// n_rows returns the number of rows of the extended witness
+func (zk *LigeroZK) n_rows() int {
+	return zk.m*zk.block_size() + zk.n_extra
 }
 
-func (zk *LigeroZK) GenerateProof(secrets []int) ([]*Proof, error) {
-	claims, party_sh, err := zk.preprocess(secrets)
-	if err != nil {
-		log.Fatal(err)
+// row_index returns the row of the extended witness a predicate row refers to in the block starting at row block
+func (zk *LigeroZK) row_index(row Row, block int) (int, error) {
+	switch {
+	case row.Kind == RowSecret && row.Index == 0:
+		return block, nil
+	case row.Kind == RowAux && row.Index >= 0 && row.Index < zk.n_aux:
+		return block + 1 + zk.n_shares + row.Index, nil
+	case row.Kind == RowExtra && row.Index >= 0 && row.Index < zk.n_extra:
+		return zk.m*zk.block_size() + row.Index, nil
 	}
+	return 0, fmt.Errorf("predicate refers to invalid row %v", row)
+}
 
-	extended_witness, err := zk.prepare_extended_witness(claims)
-	if err != nil {
-		log.Fatal(err)
+// blocks_of returns the first rows of the blocks a constraint on the given rows applies to:
+// every block, or a single block if the constraint only refers to extra rows
+func (zk *LigeroZK) blocks_of(rows []Row) ([]int, error) {
+	extra := 0
+	for _, row := range rows {
+		if row.Kind == RowExtra {
+			extra++
+		}
 	}
 
-	seed0 := generate_seeds(zk.n_shares+1, zk.q)
-	encoded_witness, err := zk.encode_extended_witness(extended_witness, seed0)
-	if err != nil {
-		log.Fatal(err)
+	if extra == len(rows) {
+		return []int{0}, nil
+	}
+	if extra > 0 {
+		return nil, fmt.Errorf("constraint cannot mix extra rows with rows of a block")
 	}
 
-	encoded_witeness_columnwise, err := ConvertToColumnwise(encoded_witness)
-	if err != nil {
-		log.Fatal(err)
+	blocks := make([]int, zk.m)
+	for i := range blocks {
+		blocks[i] = i * zk.block_size()
 	}
+	return blocks, nil
+}
 
-	//commit to the Extended Witness via Merkle Tree
-	tree, leaves, nonces, err := zk.generate_merkletree(encoded_witeness_columnwise)
-	if err != nil {
-		log.Fatal(err)
+func (zk *LigeroZK) instantiate_terms(terms []Term, block int) ([]rowTerm, error) {
+	result := make([]rowTerm, len(terms))
+	for i, term := range terms {
+		row, err := zk.row_index(term.Row, block)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = rowTerm{row: row, coeff: mod(term.Coeff, zk.q)}
 	}
-	root := tree.Root()
+	return result, nil
+}
 
-	//generate a vector of random numbers using the hash of merkle tree root as seed
-	len1 := zk.m * (1 + zk.n_shares)
-	len2 := zk.m
-	len3 := zk.m
-	h1 := zk.generate_hash([][]byte{root})
-	random_vector := RandVector(h1, len1+len2+len3, zk.q)
-
-	//generate code test
-	seed1 := generate_seeds(zk.l, zk.q)
-	code_mask := zk.generate_mask(seed1)
-	r1 := random_vector[:len1]
-
-	q_code, err := zk.generate_code_proof(encoded_witness, r1, code_mask)
+// instantiate_constraints maps the constraints of the predicate onto the rows of the extended witness
+func (zk *LigeroZK) instantiate_constraints() error {
+	for _, c := range zk.pred.Quadratic() {
+		blocks, err := zk.blocks_of([]Row{c.A, c.B, c.C})
+		if err != nil {
+			return err
+		}
+		for _, block := range blocks {
+			var rows [3]int
+			for i, row := range []Row{c.A, c.B, c.C} {
+				rows[i], err = zk.row_index(row, block)
+				if err != nil {
+					return err
+				}
+			}
+			zk.quadratic = append(zk.quadratic, rows)
+		}
+	}
+
+	//the shares of every secret have to be consistent with it
+	for block := 0; block < zk.m*zk.block_size(); block = block + zk.block_size() {
+		zk.linear = append(zk.linear, zk.share_constraints(block)...)
+	}
+
+	for _, c := range zk.pred.Linear() {
+		rows := make([]Row, len(c.Terms))
+		for i, term := range c.Terms {
+			rows[i] = term.Row
+		}
+		blocks, err := zk.blocks_of(rows)
+		if err != nil {
+			return err
+		}
+		for _, block := range blocks {
+			terms, err := zk.instantiate_terms(c.Terms, block)
+			if err != nil {
+				return err
+			}
+			zk.linear = append(zk.linear, terms)
+		}
+	}
+
+	//terms of block rows are summed over all blocks, terms of extra rows once
+	for _, c := range zk.pred.Sums() {
+		s := sumConstraint{value: mod(c.Value, zk.q)}
+		for _, term := range c.Terms {
+			blocks, err := zk.blocks_of([]Row{term.Row})
+			if err != nil {
+				return err
+			}
+			for _, block := range blocks {
+				terms, err := zk.instantiate_terms([]Term{term}, block)
+				if err != nil {
+					return err
+				}
+				s.terms = append(s.terms, terms...)
+			}
+		}
+		for _, e := range c.Entries {
+			term, err := zk.instantiate_entry(e)
+			if err != nil {
+				return err
+			}
+			s.entries = append(s.entries, term)
+		}
+		zk.sums = append(zk.sums, s)
+	}
+	zk.precompute_entry_weights()
+
+	return nil
+}
+
+// instantiate_entry maps an entry term of the predicate onto a row and an entry of the extended witness,
+// secret i lies in entry i%l of block i/l
+func (zk *LigeroZK) instantiate_entry(e EntryTerm) (entryTerm, error) {
+	block, entry := 0, e.Index
+	if e.Row.Kind == RowExtra {
+		if e.Index < 0 || e.Index >= zk.l {
+			return entryTerm{}, fmt.Errorf("predicate refers to invalid entry %d of row %v", e.Index, e.Row)
+		}
+	} else {
+		if e.Index < 0 || e.Index >= zk.n_secret {
+			return entryTerm{}, fmt.Errorf("predicate refers to invalid secret %d", e.Index)
+		}
+		block, entry = e.Index/zk.l*zk.block_size(), e.Index%zk.l
+	}
+
+	row, err := zk.row_index(e.Row, block)
 	if err != nil {
-		log.Fatal(err)
+		return entryTerm{}, err
 	}
+	return entryTerm{row: row, entry: entry, coeff: mod(e.Coeff, zk.q)}, nil
+}
 
-	//generate quadratic test
-	seed2 := make([]int, zk.l)
-	quadra_mask := zk.generate_mask(seed2)
-	r2 := random_vector[len1 : len1+len2]
+// precompute_entry_weights computes the values at the columns of the lagrange basis polynomials of the entries
+// selected by entry terms. An entry term multiplies its row by the polynomial that is 1 at the slot of its entry
+// and 0 at the other slots, which raises the degree of the sum test by l-1.
+func (zk *LigeroZK) precompute_entry_weights() {
+	var entries []int
+	for _, s := range zk.sums {
+		for _, e := range s.entries {
+			entries = append(entries, e.entry)
+		}
+	}
+	if len(entries) == 0 {
+		return
+	}
+
+	columns := make([]int, zk.n_encode)
+	for i := range columns {
+		columns[i] = zk.npss.SharePoint(i)
+	}
+	slots := make([]int, zk.l)
+	for j := range slots {
+		slots[j] = zk.npss.SecretPoint(j)
+	}
+	table := lagrange_table(zk.field, slots, columns)
+
+	zk.entry_weights = make(map[int][]int)
+	for _, entry := range entries {
+		if _, ok := zk.entry_weights[entry]; ok {
+			continue
+		}
+		weights := make([]int, zk.n_encode)
+		for c, x := range columns {
+			weights[c] = table[x][entry]
+		}
+		zk.entry_weights[entry] = weights
+	}
+}
+
+// sum_coeffs returns the number of coefficients of the sum test
+func (zk *LigeroZK) sum_coeffs() int {
+	if zk.entry_weights == nil {
+		return zk.npss.Degree()
+	}
+	return 2*zk.npss.Degree() - 1
+}
+
+// Predicate returns the validity predicate proven by the prover/verifier
+func (zk *LigeroZK) Predicate() Predicate {
+	return zk.pred
+}
+
+// eval_quadratic evaluates the random combination of the quadratic constraints at a column of the extended witness
+func (zk *LigeroZK) eval_quadratic(column []int, randomness []int) int {
+	result := 0
+	for i, c := range zk.quadratic {
+		temp := zk.field.SubInt(zk.field.MulInt(column[c[0]], column[c[1]]), column[c[2]])
+		result = zk.field.AddInt(result, zk.field.MulInt(randomness[i], temp))
+	}
+	return result
+}
+
+// eval_linear evaluates the random combination of the linear constraints at a column of the extended witness
+func (zk *LigeroZK) eval_linear(column []int, randomness []int) int {
+	result := 0
+	for i, terms := range zk.linear {
+		temp := 0
+		for _, term := range terms {
+			temp = zk.field.AddInt(temp, zk.field.MulInt(term.coeff, column[term.row]))
+		}
+		result = zk.field.AddInt(result, zk.field.MulInt(randomness[i], temp))
+	}
+	return result
+}
+
+// eval_sum evaluates the random combination of the sum constraints at the column with index col of the extended witness
+func (zk *LigeroZK) eval_sum(column []int, col int, randomness []int) int {
+	result := 0
+	for i, s := range zk.sums {
+		temp := 0
+		for _, term := range s.terms {
+			temp = zk.field.AddInt(temp, zk.field.MulInt(term.coeff, column[term.row]))
+		}
+		for _, e := range s.entries {
+			weight := zk.field.MulInt(e.coeff, zk.entry_weights[e.entry][col])
+			temp = zk.field.AddInt(temp, zk.field.MulInt(weight, column[e.row]))
+		}
+		result = zk.field.AddInt(result, zk.field.MulInt(randomness[i], temp))
+	}
+	return result
+}
+
+// challenge_lengths returns the number of random coefficients of the code, quadratic, linear and sum test
+func (zk *LigeroZK) challenge_lengths() (int, int, int, int) {
+	return zk.n_rows(), len(zk.quadratic), len(zk.linear), len(zk.sums)
+}
+
+// GenerateProof proves that secrets satisfy the predicate, the proofs of all servers are bound to ctx
+func (zk *LigeroZK) GenerateProof(ctx Context, secrets []int) ([]*Proof, error) {
+	return zk.generate_proof(ctx, secrets, LeafVersion)
+}
 
-	q_quadra, err := zk.generate_quadratic_proof(encoded_witness, r2, quadra_mask)
+// generate_proof generates the proofs committing to the columns and shares with the given leaf version
+func (zk *LigeroZK) generate_proof(ctx Context, secrets []int, version int) ([]*Proof, error) {
+	claims, party_sh, err := zk.preprocess(secrets)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
+	return zk.prove(ctx, claims, party_sh, version)
+}
+
+// commitment is the state of the prover at the Merkle root: the encoded extended witness, the masks of every
+// repetition of the tests and the tree committing to both column by column
+type commitment struct {
+	seeds   []int
+	encoded [][]int
+	columns [][]int
+	masks   []test_masks
+	tree    *column_tree
+	nonces  []int
+}
 
-	//generate linear test
-	seed3 := make([]int, zk.l)
-	linear_mask := zk.generate_mask(seed3)
-	r3 := random_vector[len1+len2:]
+// prove generates the proofs of the claims, the witness of the prover, and the shares sent to the servers
+func (zk *LigeroZK) prove(ctx Context, claims []Claim, party_sh []Shares, version int) ([]*Proof, error) {
+	c, err := zk.commit(claims, version)
+	if err != nil {
+		return nil, err
+	}
+	root := c.tree.Root()
 
-	q_linear, err := zk.generate_linear_proof(encoded_witness, r3, linear_mask)
+	//generate a vector of random numbers using the hash of merkle tree root as seed
+	ts := zk.NewTranscript(ctx)
+	ts.AbsorbBytes("merkle_root", root)
+	h1 := ts.Challenge("test_coefficients")
+	q_code, q_quadra, q_linear, q_sum, err := zk.generate_tests(c, h1)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	//generate FST root
-	fst_tree, fst_leaves, err := zk.generate_fst_merkletree(party_sh, seed0)
+	fst_tree, fst_leaves, err := zk.generate_fst_merkletree(party_sh, c.seeds, version)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	fst_root := fst_tree.Root()
 
-	h2 := zk.generate_hash([][]byte{h1, fst_root, ConvertToByteArray(q_code), ConvertToByteArray(q_quadra), ConvertToByteArray(q_linear)})
+	h2 := zk.column_challenge(ts, fst_root, q_code, q_quadra, q_linear, q_sum)
 
 	//generate column check
-	r4 := RandVector(h2, zk.n_open_col, len(leaves))
-	column_check, err := zk.generate_column_check(tree, leaves, r4, nonces, code_mask, quadra_mask, linear_mask, encoded_witeness_columnwise)
+	r4 := RandVector(h2, zk.n_open_col, zk.n_encode)
+	column_check, multiproof, err := zk.open_columns(c, r4, version)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	//generate proof for each party
@@ -172,25 +564,184 @@ func (zk *LigeroZK) GenerateProof(secrets []int) ([]*Proof, error) {
 
 		fst_proof, err := fst_tree.GenerateProof(fst_leaves[i])
 		if err != nil {
-			log.Fatal("could not generate fst authentication path")
+			return nil, fmt.Errorf("could not generate fst authentication path: %w", err)
 		}
 
-		proofs[i] = newProof(root, column_check, q_code, q_quadra, q_linear, party_sh[i], seed0, fst_root, fst_proof.Hashes)
+		proofs[i] = newProof(root, column_check, multiproof, q_code, q_quadra, q_linear, q_sum, party_sh[i], c.seeds, fst_root, fst_proof.Hashes, version)
 	}
 
 	return proofs, nil
 
 }
 
+// commit encodes the extended witness of the claims, draws the masks of all repetitions and commits to the
+// columns with their masks, a mask left out of the commitment could be chosen after the opened columns are known
+func (zk *LigeroZK) commit(claims []Claim, version int) (*commitment, error) {
+	extended_witness, err := zk.prepare_extended_witness(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	// seeds of the secret and share rows are revealed to the servers, seeds of the predicate rows are not
+	seed0, err := generate_seeds(zk.rand, zk.field, zk.n_shares+1)
+	if err != nil {
+		return nil, err
+	}
+	predicate_seeds, err := generate_seeds(zk.rand, zk.field, zk.n_aux+zk.n_extra)
+	if err != nil {
+		return nil, err
+	}
+	key := append(append([]int{}, seed0...), predicate_seeds...)
+	encoded_witness, err := zk.encode_extended_witness(extended_witness, key)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded_witeness_columnwise, err := ConvertToColumnwise(encoded_witness)
+	if err != nil {
+		return nil, err
+	}
+
+	masks, err := zk.generate_masks()
+	if err != nil {
+		return nil, err
+	}
+
+	//commit to the Extended Witness and the masks via Merkle Tree
+	tree, _, nonces, err := zk.generate_merkletree(encoded_witeness_columnwise, masks, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return &commitment{seeds: seed0, encoded: encoded_witness, columns: encoded_witeness_columnwise, masks: masks, tree: tree, nonces: nonces}, nil
+}
+
+// generate_masks draws the masks of every repetition of the tests
+func (zk *LigeroZK) generate_masks() ([]test_masks, error) {
+	masks := make([]test_masks, zk.repetitions)
+	for rep := range masks {
+		//mask of the code test
+		seed1, err := generate_seeds(zk.rand, zk.field, zk.l)
+		if err != nil {
+			return nil, err
+		}
+		masks[rep].code, err = zk.generate_mask(seed1)
+		if err != nil {
+			return nil, err
+		}
+
+		//masks of the quadratic and linear test
+		seed2 := make([]int, zk.l)
+		masks[rep].quadra, err = zk.generate_mask(seed2)
+		if err != nil {
+			return nil, err
+		}
+		seed3 := make([]int, zk.l)
+		masks[rep].linear, err = zk.generate_mask(seed3)
+		if err != nil {
+			return nil, err
+		}
+
+		//mask of the sum test encodes a random vector summing up to 0
+		if len(zk.sums) > 0 {
+			seed4, err := generate_seeds(zk.rand, zk.field, zk.l)
+			if err != nil {
+				return nil, err
+			}
+			seed4[zk.l-1] = 0
+			for i := 0; i < zk.l-1; i++ {
+				seed4[zk.l-1] = zk.field.SubInt(seed4[zk.l-1], seed4[i])
+			}
+			masks[rep].sum, err = zk.generate_mask(seed4)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return masks, nil
+}
+
+// generate_tests computes the tests of every repetition with the challenges of h1, the tests are concatenated
+func (zk *LigeroZK) generate_tests(c *commitment, h1 []byte) ([]int, []int, []int, []int, error) {
+	len1, len2, len3, len4 := zk.challenge_lengths()
+	n_challenges := len1 + len2 + len3 + len4
+	random_vector := RandVector(h1, zk.repetitions*n_challenges, zk.q)
+
+	// every repetition of the tests has its own challenges and masks
+	var q_code, q_quadra, q_linear, q_sum []int
+	for rep, m := range c.masks {
+		random := random_vector[rep*n_challenges : (rep+1)*n_challenges]
+
+		//generate code test
+		r1 := random[:len1]
+		code, err := zk.generate_code_proof(c.encoded, r1, m.code)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		q_code = append(q_code, code...)
+
+		//generate quadratic test
+		r2 := random[len1 : len1+len2]
+		quadra, err := zk.generate_quadratic_proof(c.encoded, r2, m.quadra)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		q_quadra = append(q_quadra, quadra...)
+
+		//generate linear test
+		r3 := random[len1+len2 : len1+len2+len3]
+		linear, err := zk.generate_linear_proof(c.encoded, r3, m.linear)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+		q_linear = append(q_linear, linear...)
+
+		//generate sum test
+		if len(zk.sums) > 0 {
+			r5 := random[len1+len2+len3:]
+			sum, err := zk.generate_sum_proof(c.encoded, r5, m.sum)
+			if err != nil {
+				return nil, nil, nil, nil, err
+			}
+			q_sum = append(q_sum, sum...)
+		}
+	}
+	return q_code, q_quadra, q_linear, q_sum, nil
+}
+
+// open_columns opens the columns r4 of the commitment with their masks and a multi-proof, proofs with string
+// leaves predate multi-proofs and carry an authentication path per opened column instead
+func (zk *LigeroZK) open_columns(c *commitment, r4 []int, version int) ([]OpenedColumn, [][]byte, error) {
+	legacy := version == LeafString
+	m := c.masks[0]
+	column_check, err := zk.generate_column_check(c.tree, legacy, r4, c.nonces, m.code, m.quadra, m.linear, m.sum, c.columns)
+	if err != nil {
+		return nil, nil, err
+	}
+	var multiproof [][]byte
+	if !legacy {
+		multiproof, err = c.tree.Multiproof(r4)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	for i := range column_check {
+		for _, m := range c.masks[1:] {
+			column_check[i].Repeated_masks = append(column_check[i].Repeated_masks, m.column(column_check[i].Index)...)
+		}
+	}
+	return column_check, multiproof, nil
+}
+
 func (zk *LigeroZK) preprocess(secrets []int) ([]Claim, []Shares, error) {
 	n_secret := len(secrets)
 	if n_secret == 0 || n_secret != zk.n_secret {
-		return nil, nil, fmt.Errorf("Invalid input when generating proof: wrong number of secrets")
+		return nil, nil, fmt.Errorf("%w: got %d secrets, expected %d", ErrDimensionMismatch, n_secret, zk.n_secret)
 	}
 
-	nrss, err := rss.NewReplicatedSecretSharing(zk.n_server, zk.t, zk.q)
+	nrss, err := NewSecretSharing(zk.sharing, zk.n_server, zk.t, zk.q, zk.rand)
 	if err != nil {
-		log.Fatalf("err: %v", err)
+		return nil, nil, err
 	}
 
 	claims := make([]Claim, n_secret)
@@ -199,7 +750,7 @@ func (zk *LigeroZK) preprocess(secrets []int) ([]Claim, []Shares, error) {
 	for i := 0; i < n_secret; i++ {
 		share_list, party, err := nrss.Split(secrets[i])
 		if err != nil {
-			log.Fatalf("err: %v", err)
+			return nil, nil, err
 		}
 
 		claims[i] = Claim{Secret: secrets[i], Shares: share_list}
@@ -224,31 +775,37 @@ func (zk *LigeroZK) preprocess(secrets []int) ([]Claim, []Shares, error) {
 // parameter input: client's input vector
 func (zk *LigeroZK) prepare_extended_witness(claims []Claim) ([][]int, error) {
 	if len(claims) == 0 {
-		return nil, fmt.Errorf("Invalid claims: claims are empty")
+		return nil, fmt.Errorf("%w: claims are empty", ErrDimensionMismatch)
 	}
 
 	if len(claims[0].Shares) != zk.n_shares {
-		return nil, fmt.Errorf("Invalid input: Number of shares of each claim is not correct")
+		return nil, fmt.Errorf("%w: number of shares of each claim is not correct", ErrDimensionMismatch)
 	}
 
 	if zk.m > len(claims) {
-		return nil, fmt.Errorf("Invalid input: Number of claims must equal or larger than m")
+		return nil, fmt.Errorf("%w: number of claims must equal or larger than m", ErrDimensionMismatch)
 	}
 
+	secrets := make([]int, len(claims))
+	for i, claim := range claims {
+		secrets[i] = claim.Secret
+	}
+	aux, extra := zk.pred.Witness(secrets, zk.l)
+
 	secrets_num := 1
-	rows := zk.m * (secrets_num + zk.n_shares)
-	matrix := make([][]int, rows)
+	rows := zk.m * zk.block_size()
+	matrix := make([][]int, zk.n_rows())
 	for i := range matrix {
 		matrix[i] = make([]int, zk.l)
 	}
 
 	index := 0
-	for i := 0; i < rows; i = i + secrets_num + zk.n_shares {
+	for i := 0; i < rows; i = i + zk.block_size() {
 		for j := 0; j < zk.l; j++ {
 
 			k := 0
 			for k < secrets_num {
-				matrix[i+k][j] = claims[index].Secret
+				matrix[i+k][j] = mod(claims[index].Secret, zk.q)
 				k++
 			}
 			h := 0
@@ -256,35 +813,44 @@ func (zk *LigeroZK) prepare_extended_witness(claims []Claim) ([][]int, error) {
 				matrix[i+k+h][j] = claims[index].Shares[h]
 				h++
 			}
+			for b := 0; b < zk.n_aux; b++ {
+				matrix[i+k+h+b][j] = mod(aux[index][b], zk.q)
+			}
 			index++
 		}
 	}
 
+	for b := 0; b < zk.n_extra; b++ {
+		for j := 0; j < zk.l; j++ {
+			matrix[rows+b][j] = mod(extra[b][j], zk.q)
+		}
+	}
+
 	return matrix, nil
 
 }
 
 func (zk *LigeroZK) encode_extended_witness(input [][]int, key []int) ([][]int, error) {
 	if len(input) == 0 {
-		return nil, fmt.Errorf("Invalid input: Input is empty")
+		return nil, fmt.Errorf("%w: input is empty", ErrDimensionMismatch)
+	}
+
+	if len(input) != zk.n_rows() || len(input[0]) != zk.l {
+		return nil, fmt.Errorf("%w: input does not fit the parameters", ErrDimensionMismatch)
 	}
 
-	if len(input) != zk.m*(1+zk.n_shares) || len(input[0]) != zk.l {
-		return nil, fmt.Errorf("Invalid input")
+	if len(key) != zk.block_size()+zk.n_extra {
+		return nil, fmt.Errorf("%w: number of keys is not correct", ErrDimensionMismatch)
 	}
 	matrix := make([][]int, len(input))
 	crs1 := NewCryptoRandSource()
 
 	rand_values := make([]int, len(input))
 	for i := 0; i < len(input); i++ {
-		nonce := i / (1 + zk.n_shares)
-		crs1.Seed(key[i%(1+zk.n_shares)], nonce)
-		rand_values[i] = int(crs1.Int63(int64(zk.q)))
+		rand_values[i] = zk.row_seed(&crs1, key, i)
 		matrix[i] = make([]int, zk.n_encode)
 	}
 
-	zk.npss.Split(input[0], rand_values[0])
-
 	// Create channels for concurrent processing
 	resultChan := make(chan struct {
 		row   []int
@@ -337,14 +903,30 @@ func (zk *LigeroZK) encode_extended_witness(input [][]int, key []int) ([][]int,
 	return matrix, nil
 }
 
-func (zk *LigeroZK) generate_merkletree(input [][]int) (*merkletree.MerkleTree, [][]byte, []int, error) {
+// row_seed returns the seed of the randomness of row i of the encoded extended witness, derived from the key of
+// its position in a block, or of the extra row, and the block as nonce
+func (zk *LigeroZK) row_seed(crs *CryptoRandSource, key []int, i int) int {
+	nonce := i / zk.block_size()
+	if i < zk.m*zk.block_size() {
+		crs.Seed(key[i%zk.block_size()], nonce)
+	} else {
+		crs.Seed(key[zk.block_size()+i-zk.m*zk.block_size()], nonce)
+	}
+	return int(crs.Int63(int64(zk.q)))
+}
+
+// generate_merkletree commits to the columns of input and the masks of the tests at every column
+func (zk *LigeroZK) generate_merkletree(input [][]int, masks []test_masks, version int) (*column_tree, [][]byte, []int, error) {
 	length := len(input)
 	if length == 0 {
-		return nil, nil, nil, fmt.Errorf("Invalid input: Input is empty")
+		return nil, nil, nil, fmt.Errorf("%w: input is empty", ErrDimensionMismatch)
 	}
 
 	// generate a list of nonces
-	nonces := generate_seeds(length, zk.q)
+	nonces, err := generate_seeds(zk.rand, zk.field, length)
+	if err != nil {
+		return nil, nil, nil, err
+	}
 
 	// Create channels for concurrent hashing
 	hashedColumns := make(chan struct {
@@ -356,10 +938,7 @@ func (zk *LigeroZK) generate_merkletree(input [][]int) (*merkletree.MerkleTree,
 	// Hash each column concurrently
 	for i := 0; i < length; i++ {
 		go func(i int) {
-			list := make([]int, len(input[0])+1)
-			list = append(list, input[i]...)
-			list = append(list, nonces[i])
-			concatenated, err := ConvertColumnToString(list)
+			leaf, err := column_leaf(input[i], column_masks(masks, i), nonces[i], version)
 			if err != nil {
 				errChan <- err
 				return
@@ -367,7 +946,7 @@ func (zk *LigeroZK) generate_merkletree(input [][]int) (*merkletree.MerkleTree,
 			hashedColumns <- struct {
 				leaf  []byte
 				index int
-			}{index: i, leaf: []byte(concatenated)}
+			}{index: i, leaf: leaf}
 		}(i)
 	}
 
@@ -383,7 +962,7 @@ func (zk *LigeroZK) generate_merkletree(input [][]int) (*merkletree.MerkleTree,
 	}
 
 	// Create a new Merkle Tree from hashed columns
-	tree, err := merkletree.New(leaves)
+	tree, err := new_column_tree(leaves)
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -391,32 +970,19 @@ func (zk *LigeroZK) generate_merkletree(input [][]int) (*merkletree.MerkleTree,
 	return tree, leaves, nonces, nil
 }
 
-func (zk *LigeroZK) generate_fst_merkletree(party_sh []Shares, seeds []int) (*merkletree.MerkleTree, [][]byte, error) {
+func (zk *LigeroZK) generate_fst_merkletree(party_sh []Shares, seeds []int, version int) (*merkletree.MerkleTree, [][]byte, error) {
 	// generate and hash each party's shares
 	l1 := len(party_sh)
-	l2 := len(party_sh[0].Values)
-	l3 := len(party_sh[0].Index)
-
-	if l1 == 0 || l2 == 0 || l3 == 0 {
-		log.Fatal("party_sh is invalid")
+	if l1 == 0 || len(party_sh[0].Values) == 0 || len(party_sh[0].Index) == 0 {
+		return nil, nil, fmt.Errorf("%w: party_sh is invalid", ErrDimensionMismatch)
 	}
 	leaves := make([][]byte, l1)
 	for i := 0; i < l1; i++ {
-		list := make([]string, l2*l3+l3)
-		index := 0
-		for j := 0; j < l2; j++ {
-			for n := 0; n < l3; n++ {
-				list[index] = fmt.Sprintf("%064b", party_sh[i].Values[j][n])
-				index++
-			}
-		}
-
-		for m := 0; m < l3; m++ {
-			list[index] = fmt.Sprintf("%064b", seeds[party_sh[i].Index[m]])
-			index++
+		leaf, err := fst_leaf(party_sh[i], seeds, version)
+		if err != nil {
+			return nil, nil, err
 		}
-		concat := strings.Join(list, "")
-		leaves[i] = []byte(concat)
+		leaves[i] = leaf
 	}
 
 	//Create a new Merkle Tree
@@ -429,7 +995,8 @@ func (zk *LigeroZK) generate_fst_merkletree(party_sh []Shares, seeds []int) (*me
 
 }
 
-func (zk *LigeroZK) generate_column_check(tree *merkletree.MerkleTree, leaves [][]byte, cols []int, m_nonce []int, c_mask []int, q_mask []int, l_mask []int, input [][]int) ([]OpenedColumn, error) {
+// generate_column_check opens the columns cols, with an authentication path each if authpaths is set
+func (zk *LigeroZK) generate_column_check(tree *column_tree, authpaths bool, cols []int, m_nonce []int, c_mask []int, q_mask []int, l_mask []int, s_mask []int, input [][]int) ([]OpenedColumn, error) {
 	column_check := make([]OpenedColumn, len(cols)) // Adjusted length here
 
 	// Create channels for concurrent processing
@@ -448,11 +1015,6 @@ func (zk *LigeroZK) generate_column_check(tree *merkletree.MerkleTree, leaves []
 		go func(i int) {
 			defer wg.Done()
 			index := cols[i]
-			proof, err := tree.GenerateProof(leaves[index])
-			if err != nil {
-				errChan <- err
-				return
-			}
 			openedCol := OpenedColumn{
 				List:         input[index],
 				Index:        index,
@@ -460,7 +1022,17 @@ func (zk *LigeroZK) generate_column_check(tree *merkletree.MerkleTree, leaves []
 				Code_mask:    c_mask[index],
 				Quadra_mask:  q_mask[index],
 				Linear_mask:  l_mask[index],
-				Authpath:     proof.Hashes,
+			}
+			if authpaths {
+				var err error
+				openedCol.Authpath, err = tree.Authpath(index)
+				if err != nil {
+					errChan <- err
+					return
+				}
+			}
+			if s_mask != nil {
+				openedCol.Sum_mask = s_mask[index]
 			}
 			resultChan <- struct {
 				col   OpenedColumn
@@ -493,11 +1065,11 @@ func (zk *LigeroZK) generate_column_check(tree *merkletree.MerkleTree, leaves []
 // generate proof that is used to check if encoded extended witness is encoded correctly
 func (zk *LigeroZK) generate_code_proof(input [][]int, randomness []int, mask []int) ([]int, error) {
 	if len(input) == 0 {
-		return nil, fmt.Errorf("Invalid input: Input is empty")
+		return nil, fmt.Errorf("%w: input is empty", ErrDimensionMismatch)
 	}
 
-	if len(input) != zk.m*(1+zk.n_shares) || len(input[0]) != zk.n_encode {
-		return nil, fmt.Errorf("Invalid input")
+	if len(input) != zk.n_rows() || len(input[0]) != zk.n_encode {
+		return nil, fmt.Errorf("%w: input does not fit the parameters", ErrDimensionMismatch)
 	}
 
 	//compute q_code
@@ -506,140 +1078,148 @@ func (zk *LigeroZK) generate_code_proof(input [][]int, randomness []int, mask []
 	mask_matrix := make([][]int, 1)
 	mask_matrix[0] = mask
 
-	temp_matrix, err := MulMatrix(r_matrix, input, zk.q)
+	temp_matrix, err := mul_matrix(zk.field, r_matrix, input)
+	if err != nil {
+		return nil, err
+	}
+	q_code, err := add_matrix(zk.field, temp_matrix, mask_matrix, zk.field.AddInt)
 	if err != nil {
 		return nil, err
 	}
-	q_code := AddMatrix(temp_matrix, mask_matrix, zk.q)
 	if len(q_code) != 1 {
-		return nil, fmt.Errorf("Invalid q_code")
+		return nil, fmt.Errorf("%w: invalid q_code", ErrDimensionMismatch)
 	}
 
-	proof := make([]int, zk.n_open_col+zk.l)
-	for i := 0; i < zk.n_open_col+zk.l; i++ {
-		proof[i] = q_code[0][i]
+	stride := zk.code_stride()
+	proof := make([]int, zk.npss.Degree())
+	for i := range proof {
+		proof[i] = q_code[0][i*stride]
 	}
 
 	return proof, nil
 
 }
 
-// generate proof that is used to check if input is a vector of 0/1
+// generate proof that is used to check the quadratic constraints of the predicate,
+// e.g. that input is a vector of 0/1
 func (zk *LigeroZK) generate_quadratic_proof(input [][]int, randomness []int, mask []int) ([]int, error) {
-	//fmt.Printf("input:%v\n", input)
 	if len(input) == 0 {
-		return nil, fmt.Errorf("Invalid input: Input is empty")
+		return nil, fmt.Errorf("%w: input is empty", ErrDimensionMismatch)
 	}
 
-	if len(input) != zk.m*(1+zk.n_shares) || len(input[0]) != zk.n_encode {
-		return nil, fmt.Errorf("Invalid input")
+	if len(input) != zk.n_rows() || len(input[0]) != zk.n_encode {
+		return nil, fmt.Errorf("%w: input does not fit the parameters", ErrDimensionMismatch)
 	}
 
 	//generate q_quadra
 	result := make([]int, zk.n_encode)
-
-	index := 0
-	for row := 0; row < len(input); row = row + zk.n_shares + 1 {
-		for col := 0; col < len(input[0]); col++ {
-			result[col] += randomness[index] * input[row][col] * (1 - input[row][col])
-			//result[col] = mod(result[col], zk.q)
+	column := make([]int, len(input))
+	for col := 0; col < zk.n_encode; col++ {
+		for row := range input {
+			column[row] = input[row][col]
 		}
-		index += 1
-	}
-
-	//fmt.Printf("input:%v\n", result)
-	for i := 0; i < len(result); i++ {
-		result[i] = result[i] + mask[i]
-		result[i] = mod(result[i], zk.q)
+		result[col] = zk.field.AddInt(zk.eval_quadratic(column, randomness), mask[col])
 	}
 
 	return result, nil
 
 }
 
+// generate proof that is used to check the shares of each secret and the linear constraints of the predicate
 func (zk *LigeroZK) generate_linear_proof(input [][]int, randomness []int, mask []int) ([]int, error) {
 	if len(input) == 0 {
-		return nil, fmt.Errorf("Invalid input: Input is empty")
+		return nil, fmt.Errorf("%w: input is empty", ErrDimensionMismatch)
 	}
 
-	if len(input) != zk.m*(1+zk.n_shares) || len(input[0]) != zk.n_encode {
-		return nil, fmt.Errorf("Invalid input")
+	if len(input) != zk.n_rows() || len(input[0]) != zk.n_encode {
+		return nil, fmt.Errorf("%w: input does not fit the parameters", ErrDimensionMismatch)
 	}
 
 	//generate q_linear
 	result := make([]int, zk.n_encode)
-
-	index := 0
-	for row := 0; row < len(input); row = row + zk.n_shares + 1 {
-		for col := 0; col < len(input[0]); col++ {
-			//result[col] = result[col]+input[row][col]
-			temp := input[row][col]
-			for j := 1; j < zk.n_shares+1; j++ {
-				temp = temp - input[row+j][col]
-			}
-			result[col] = result[col] + temp*randomness[index]
+	column := make([]int, len(input))
+	for col := 0; col < zk.n_encode; col++ {
+		for row := range input {
+			column[row] = input[row][col]
 		}
-		index += 1
-	}
-
-	for i := 0; i < len(result); i++ {
-		result[i] = mod(result[i]+mask[i], zk.q)
+		result[col] = zk.field.AddInt(zk.eval_linear(column, randomness), mask[col])
 	}
 
 	return result, nil
 
 }
 
-func (zk *LigeroZK) generate_mask(seeds []int) []int {
-
-	mask := make([]int, zk.n_encode)
+// generate proof that is used to check the sum constraints of the predicate, e.g. the sum of all secrets against a bound k
+func (zk *LigeroZK) generate_sum_proof(input [][]int, randomness []int, mask []int) ([]int, error) {
+	if len(input) == 0 {
+		return nil, fmt.Errorf("%w: input is empty", ErrDimensionMismatch)
+	}
 
-	shares, err := zk.npss.Split(seeds, 1)
-	if err != nil {
-		log.Fatal(err)
+	if len(input) != zk.n_rows() || len(input[0]) != zk.n_encode {
+		return nil, fmt.Errorf("%w: input does not fit the parameters", ErrDimensionMismatch)
 	}
 
-	for j := 0; j < zk.n_encode; j++ {
-		mask[j] = shares[j].Value
+	//generate q_sum
+	result := make([]int, zk.n_encode)
+	column := make([]int, len(input))
+	for col := 0; col < zk.n_encode; col++ {
+		for row := range input {
+			column[row] = input[row][col]
+		}
+		result[col] = zk.field.AddInt(zk.eval_sum(column, col, randomness), mask[col])
 	}
 
-	return mask
+	return result, nil
 }
 
-func (zk *LigeroZK) generate_hash(input [][]byte) []byte {
-	if len(input) == 0 {
-		log.Fatal("input of hash function could not be empty")
-	}
-	size := 0
-	for _, d := range input {
-		size += len(d)
+// column_challenge derives the seed of the opened column indices from the tests sent by the prover
+func (zk *LigeroZK) column_challenge(ts *Transcript, fst_root []byte, q_code []int, q_quadra []int, q_linear []int, q_sum []int) []byte {
+	ts.AbsorbBytes("fst_root", fst_root)
+	ts.AbsorbInts("code_test", q_code)
+	ts.AbsorbInts("quadratic_test", q_quadra)
+	ts.AbsorbInts("linear_test", q_linear)
+	if len(zk.sums) > 0 {
+		ts.AbsorbInts("sum_test", q_sum)
 	}
+	return ts.Challenge("column_indices")
+}
+
+func (zk *LigeroZK) generate_mask(seeds []int) ([]int, error) {
 
-	concat, i := make([]byte, size), 0
-	for _, d := range input {
-		i += copy(concat[i:], d)
+	mask := make([]int, zk.n_encode)
+
+	// the randomness of the mask is drawn from the reader of the prover, masks of different tests and
+	// repetitions are independent
+	shares, err := zk.npss.SplitRandom(seeds)
+	if err != nil {
+		return nil, err
 	}
 
-	hash := sha256.Sum256(concat)
+	for j := 0; j < zk.n_encode; j++ {
+		mask[j] = shares[j].Value
+	}
 
-	return hash[:]
+	return mask, nil
 }
 
-func generate_seeds(size int, q int) []int {
+// generate_seeds returns size distinct random field elements read from r
+func generate_seeds(r io.Reader, f *field.Field, size int) ([]int, error) {
 	seeds := make([]int, size)
-	//rand.Seed(time.Now().UnixNano())
 	checkMap := map[int]bool{}
 	for i := 0; i < size; i++ {
 		for {
-			value, err := crypto_rand.Int(crypto_rand.Reader, big.NewInt(int64(q)))
-			if err == nil && !checkMap[int(value.Int64())] {
-				checkMap[int(value.Int64())] = true
-				seeds[i] = int(value.Int64())
+			value, err := f.Rand(r)
+			if err != nil {
+				return nil, err
+			}
+			if !checkMap[int(value)] {
+				checkMap[int(value)] = true
+				seeds[i] = int(value)
 				break
 			}
 
 		}
 	}
 
-	return seeds
+	return seeds, nil
 }
@@ -1,13 +1,23 @@
 package ligero
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
 
+	"example.com/SMC/pkg/field"
+	"example.com/SMC/pkg/rss"
 	merkletree "github.com/wealdtech/go-merkletree"
 )
 
+var ctx = Context{Exp_ID: "exp1", Client_ID: "c1"}
+
 /**
 func TestRearrange_Extended_Witness(t *testing.T) {
 	tests := []struct {
@@ -118,7 +128,7 @@ func TestGenerate_MerkleTree(t *testing.T) {
 		{7, 8, 9},
 	}
 
-	zk, err := NewLigeroZK(3, 1, 6, 1, 41, 3)
+	zk, err := NewLigeroZK(3, 1, 6, 1, 41, 3, BitPredicate())
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -128,22 +138,23 @@ func TestGenerate_MerkleTree(t *testing.T) {
 		log.Fatal(err)
 	}
 
-	//commit to the Extended Witness via Merkle Tree
-	tree, leaves, _, err := zk.generate_merkletree(encoded_witeness_columnwise)
+	//commit to the Extended Witness and the masks of one repetition via Merkle Tree
+	masks := []test_masks{{code: []int{1, 2, 3}, quadra: []int{4, 5, 6}, linear: []int{7, 8, 9}}}
+	tree, leaves, nonces, err := zk.generate_merkletree(encoded_witeness_columnwise, masks, LeafVersion)
 	if err != nil {
 		log.Fatal(err)
 	}
 	//get root of merkletree
 	root := tree.Root()
 
-	for _, leaf := range leaves {
-		proof, err := tree.GenerateProof(leaf)
+	for i, leaf := range leaves {
+		authpath, err := tree.Authpath(i)
 		if err != nil {
 			panic(err)
 		}
 
-		// Verify the proof for each leaf
-		verified, err := merkletree.VerifyProof(leaf, proof, root)
+		// Verify the proof for each leaf, authentication paths are the ones of go-merkletree
+		verified, err := merkletree.VerifyProof(leaf, &merkletree.Proof{Hashes: authpath, Index: uint64(i)}, root)
 		if err != nil {
 			panic(err)
 		}
@@ -152,24 +163,33 @@ func TestGenerate_MerkleTree(t *testing.T) {
 		}
 	}
 
+	// the leaves commit to the masks as well
+	masks[0].quadra[0]++
+	leaf, err := column_leaf(encoded_witeness_columnwise[0], column_masks(masks, 0), nonces[0], LeafVersion)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if bytes.Equal(leaf, leaves[0]) {
+		t.Fatalf("leaf does not commit to the quadratic mask")
+	}
 }
 
 func TestGenerate(t *testing.T) {
-	zk, err := NewLigeroZK(3, 1, 6, 1, 10631, 3)
+	zk, err := NewLigeroZK(3, 1, 6, 1, 10631, 3, BitPredicate())
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
 	secrets := []int{1, 0, 1}
 
-	proof, err := zk.GenerateProof(secrets)
+	proof, err := zk.GenerateProof(ctx, secrets)
 
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	for i := 0; i < len(proof); i++ {
-		verify, err := zk.VerifyProof(*proof[i])
+		verify, err := zk.VerifyProof(ctx, *proof[i])
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -177,20 +197,299 @@ func TestGenerate(t *testing.T) {
 			fmt.Println("Verification failed for party!")
 		}
 		fmt.Println("Verification succeed for party !")
+
+		// a party holds the shares of the combinations of t parties it is not part of
+		if indices := ShareIndices(SharingRSS, 6, 1, i); !reflect.DeepEqual(proof[i].Shares.Index, indices) {
+			t.Fatalf("party %d holds shares %v, expected %v", i, proof[i].Shares.Index, indices)
+		}
+	}
+
+}
+
+func TestReplayProof(t *testing.T) {
+	zk, err := NewLigeroZK(3, 1, 4, 1, 10631, 3, BitPredicate())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	proof, err := zk.GenerateProof(ctx, []int{1, 0, 1})
+	if err != nil {
+		t.Fatalf("err: %v", err)
 	}
 
+	verify, err := zk.VerifyProof(ctx, *proof[0])
+	if !verify {
+		t.Fatalf("verification failed: %v", err)
+	}
+
+	// a proof cannot be replayed by another client or into another experiment
+	for _, other := range []Context{{Exp_ID: "exp1", Client_ID: "c2"}, {Exp_ID: "exp2", Client_ID: "c1"}} {
+		verify, _ = zk.VerifyProof(other, *proof[0])
+		if verify {
+			t.Fatalf("verification succeeded for replayed proof in context %v", other)
+		}
+	}
+}
+
+func TestGenerateRange(t *testing.T) {
+	zk, err := NewLigeroZK(4, 2, 4, 1, 10631, 3, RangePredicate(4))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	secrets := []int{3, 15, 0, 9}
+
+	proof, err := zk.GenerateProof(ctx, secrets)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	for i := 0; i < len(proof); i++ {
+		verify, err := zk.VerifyProof(ctx, *proof[i])
+		if !verify {
+			t.Fatalf("verification failed for party %d: %v", i, err)
+		}
+	}
+
+	// 16 does not fit into 4 bits
+	proof, err = zk.GenerateProof(ctx, []int{3, 16, 0, 9})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	verify, _ := zk.VerifyProof(ctx, *proof[0])
+	if verify {
+		t.Fatalf("verification succeeded for a secret out of range")
+	}
+
+	_, err = NewLigeroZK(4, 2, 4, 1, 10631, 3, RangePredicate(14))
+	if err == nil {
+		t.Fatalf("expected error when 2^n_bits exceeds q")
+	}
+}
+
+func TestGenerateSum(t *testing.T) {
+	tests := []struct {
+		pred    Predicate
+		secrets []int
+		valid   bool
+	}{
+		// one-hot vector
+		{OneHotPredicate(), []int{0, 1, 0, 0, 0, 0}, true},
+		// multi-vote submission
+		{OneHotPredicate(), []int{0, 1, 0, 1, 0, 0}, false},
+		// empty submission
+		{OneHotPredicate(), []int{0, 0, 0, 0, 0, 0}, false},
+		// at most 2 ones
+		{And(BitPredicate(), SumAtMostPredicate(2)), []int{1, 0, 0, 0, 1, 0}, true},
+		{And(BitPredicate(), SumAtMostPredicate(2)), []int{0, 0, 0, 0, 0, 0}, true},
+		{And(BitPredicate(), SumAtMostPredicate(2)), []int{1, 1, 0, 0, 1, 0}, false},
+		// values in [0, 16) summing up to at most 20
+		{And(RangePredicate(4), SumAtMostPredicate(20)), []int{15, 5, 0, 0, 0, 0}, true},
+		{And(RangePredicate(4), SumAtMostPredicate(20)), []int{15, 5, 1, 0, 0, 0}, false},
+		// two sum constraints at once
+		{And(OneHotPredicate(), SumAtMostPredicate(1)), []int{0, 0, 0, 0, 0, 1}, true},
+	}
+
+	for _, test := range tests {
+		zk, err := NewLigeroZK(6, 2, 4, 1, 10631, 3, test.pred)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		proof, err := zk.GenerateProof(ctx, test.secrets)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		for i := 0; i < len(proof); i++ {
+			verify, err := zk.VerifyProof(ctx, *proof[i])
+			if verify != test.valid {
+				t.Fatalf("secrets %v with predicate %s: expected %v, got %v (%v)", test.secrets, test.pred, test.valid, verify, err)
+			}
+		}
+	}
+
+	// a sum bound without a range predicate cannot be enforced
+	_, err := NewLigeroZK(6, 2, 4, 1, 10631, 3, SumEqualPredicate(1))
+	if err == nil {
+		t.Fatalf("expected error for a sum predicate without range")
+	}
+}
+
+func TestGenerateNorm(t *testing.T) {
+	tests := []struct {
+		secrets []int
+		valid   bool
+	}{
+		// squared norm 87
+		{[]int{-3, 4, 0, 5, -6, 1}, true},
+		// squared norm exactly 100
+		{[]int{-10, 0, 0, 0, 0, 0}, true},
+		// squared norm 127
+		{[]int{-7, 4, 0, 5, -6, 1}, false},
+		// -17 does not fit into 5 signed bits
+		{[]int{-17, 0, 0, 0, 0, 0}, false},
+	}
+
+	zk, err := NewLigeroZK(6, 2, 4, 1, 10631, 3, NormPredicate(5, 100))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	for _, test := range tests {
+		proof, err := zk.GenerateProof(ctx, test.secrets)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		for i := 0; i < len(proof); i++ {
+			verify, err := zk.VerifyProof(ctx, *proof[i])
+			if verify != test.valid {
+				t.Fatalf("secrets %v: expected %v, got %v (%v)", test.secrets, test.valid, verify, err)
+			}
+		}
+	}
+
+	// the squares of 8 bit values can wrap around q
+	_, err = NewLigeroZK(6, 2, 4, 1, 10631, 3, NormPredicate(8, 100))
+	if err == nil {
+		t.Fatalf("expected error when the norm can wrap around q")
+	}
+}
+
+// lyingProductPredicate copies the product of the factors into the extra rows whatever the product secret is
+type lyingProductPredicate struct {
+	*productPredicate
+}
+
+func (p lyingProductPredicate) Witness(secrets []int, l int) ([][]int, [][]int) {
+	aux, extra := p.productPredicate.Witness(secrets, l)
+	extra[2][0] = secrets[p.a] * secrets[p.b]
+	return aux, extra
+}
+
+func TestGenerateProduct(t *testing.T) {
+	// the factors and products lie in different blocks and entries
+	pred := And(BitPredicate(), ProductPredicate(0, 1, 4), ProductPredicate(0, 3, 5), ProductPredicate(2, 3, 6), ProductPredicate(1, 2, 7))
+	attributes := []int{1, 1, 0, 1}
+	secrets := FillProducts(pred, attributes)
+	if !reflect.DeepEqual(secrets, []int{1, 1, 0, 1, 1, 1, 0, 0}) {
+		t.Fatalf("unexpected products %v", secrets)
+	}
+	wrong := append([]int{}, secrets...)
+	wrong[6] = 1
+
+	for _, q := range []int{10631, 2013265921} {
+		zk, err := NewLigeroZK(8, 2, 4, 1, q, 3, pred)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		for _, test := range []struct {
+			secrets []int
+			valid   bool
+		}{{secrets, true}, {wrong, false}} {
+			proof, err := zk.GenerateProof(ctx, test.secrets)
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			for i := 0; i < len(proof); i++ {
+				verify, err := zk.VerifyProof(ctx, *proof[i])
+				if verify != test.valid {
+					t.Fatalf("q=%d, secrets %v: expected %v, got %v (%v)", q, test.secrets, test.valid, verify, err)
+				}
+			}
+		}
+	}
+
+	// a product copied wrongly into the extra rows fails the sum test
+	liar := And(BitPredicate(), lyingProductPredicate{&productPredicate{a: 0, b: 1, c: 4}})
+	prover, err := NewLigeroZK(8, 2, 4, 1, 10631, 3, liar)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	verifier, err := NewLigeroZK(8, 2, 4, 1, 10631, 3, And(BitPredicate(), ProductPredicate(0, 1, 4)))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	proof, err := prover.GenerateProof(ctx, []int{1, 1, 0, 1, 0, 0, 0, 0})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	verify, err := verifier.VerifyProof(ctx, *proof[0])
+	if verify || err == nil {
+		t.Fatalf("expected wrongly copied product to be rejected")
+	}
+
+	// a product cannot overwrite its factor
+	_, err = NewLigeroZK(8, 2, 4, 1, 10631, 3, ProductPredicate(0, 1, 1))
+	if !errors.Is(err, ErrInvalidParams) {
+		t.Fatalf("expected ErrInvalidParams, got %v", err)
+	}
+}
+
+func TestGenerateShamir(t *testing.T) {
+	zk, err := NewLigeroZKFromParams(Params{N_secret: 6, M: 2, N_server: 16, T: 5, Q: 2147483647, N_open: 8, Sharing: SharingShamir}, BitPredicate())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	secrets := []int{1, 0, 1, 1, 0, 1}
+	proof, err := zk.GenerateProof(ctx, secrets)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// every server holds a single share of each secret, any t+1 of them reconstruct it
+	sss, err := NewSecretSharing(SharingShamir, 16, 5, 2147483647, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	parties := make([][]rss.Share, 0, 6)
+	for i := 0; i < len(proof); i++ {
+		verify, err := zk.VerifyProof(ctx, *proof[i])
+		if !verify {
+			t.Fatalf("verification failed for party %d: %v", i, err)
+		}
+		if indices := ShareIndices(SharingShamir, 16, 5, i); !reflect.DeepEqual(proof[i].Shares.Index, indices) {
+			t.Fatalf("party %d holds shares %v", i, proof[i].Shares.Index)
+		}
+		if i%3 == 0 {
+			parties = append(parties, []rss.Share{{Index: i, Value: proof[i].Shares.Values[2][0]}})
+		}
+	}
+	secret, err := sss.Reconstruct(parties)
+	if err != nil || secret != secrets[2] {
+		t.Fatalf("expected secret %d, got %d (%v)", secrets[2], secret, err)
+	}
+
+	// shares that do not lie on a polynomial of degree t are rejected
+	cheat := cheating_proof(t, zk, secrets, func(claims []Claim, party_sh []Shares) {
+		claims[0].Shares[10] = zk.field.AddInt(claims[0].Shares[10], 1)
+		party_sh[10].Values[0][0] = claims[0].Shares[10]
+	})
+	verify, err := zk.VerifyProof(ctx, cheat)
+	if verify || !errors.Is(err, ErrInvalidProof) {
+		t.Fatalf("verification succeeded for inconsistent shares: %v", err)
+	}
+
+	_, err = NewLigeroZKFromParams(Params{N_secret: 6, M: 2, N_server: 4, T: 1, Q: 10631, N_open: 3, Sharing: "additive"}, BitPredicate())
+	if !errors.Is(err, ErrInvalidParams) {
+		t.Fatalf("expected ErrInvalidParams for an unknown sharing, got %v", err)
+	}
 }
 
 func BenchmarkGenerateProof(b *testing.B) {
 	for i := 0; i < b.N; i++ {
-		zk, err := NewLigeroZK(100, 4, 4, 1, 10631, 240)
+		zk, err := NewLigeroZK(100, 4, 4, 1, 10631, 240, BitPredicate())
 		if err != nil {
 			log.Fatalf("err: %v", err)
 		}
 
 		secrets := []int{0, 1, 1, 1, 1, 1, 1, 0, 1, 1, 0, 1, 1, 1, 0, 0, 0, 1, 1, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 1, 0, 0, 1, 1, 1, 1, 0, 1, 1, 0, 0, 0, 1, 1, 0, 1, 0, 0, 0, 1, 0, 1, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 1, 1, 1, 1, 1, 0, 0, 0, 1, 0, 1, 1, 0, 1, 0, 0, 0, 1, 1, 1, 1, 0, 1, 0, 0, 0, 0, 1, 0, 1, 0, 0, 1, 0, 0, 1, 0, 1}
 
-		proof, err := zk.GenerateProof(secrets)
+		proof, err := zk.GenerateProof(ctx, secrets)
 
 		if err != nil {
 			log.Fatal(err)
@@ -200,3 +499,358 @@ func BenchmarkGenerateProof(b *testing.B) {
 		//fmt.Printf("%d\n", zk.GetProofSize(*proof[0]))
 	}
 }
+
+func TestGenerateLargeModulus(t *testing.T) {
+	// 2^61-1, caches are keyed by evaluation point and do not grow with q
+	q := 1<<61 - 1
+	pred := And(RangePredicate(20), SumAtMostPredicate(1<<40))
+	zk, err := NewLigeroZK(6, 2, 4, 1, q, 3, pred)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	secrets := []int{1<<20 - 1, 0, 1 << 19, 12345, 1, 1<<20 - 2}
+	proof, err := zk.GenerateProof(ctx, secrets)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	for _, p := range proof {
+		verify, err := zk.VerifyProof(ctx, *p)
+		if !verify {
+			t.Fatalf("verification failed: %v", err)
+		}
+	}
+}
+
+func TestGoldilocksModulus(t *testing.T) {
+	// field elements are stored as int, q has to be below 2^63
+	q := field.Goldilocks
+	_, err := NewLigeroZK(6, 2, 4, 1, int(q), 3, BitPredicate())
+	if !errors.Is(err, ErrInvalidParams) || !strings.Contains(err.Error(), "2^63") {
+		t.Fatalf("expected ErrInvalidParams for q below 2^63, got %v", err)
+	}
+}
+
+func TestGenerateNTT(t *testing.T) {
+	// 998244353 = 119*2^23+1 selects the NTT encoding
+	for _, pred := range []Predicate{BitPredicate(), RangePredicate(4), And(RangePredicate(4), SumAtMostPredicate(20))} {
+		zk, err := NewLigeroZK(6, 2, 4, 1, 998244353, 3, pred)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if !zk.npss.NTT() || zk.n_encode != 64 {
+			t.Fatalf("expected the NTT encoding on 64 columns, got %d columns", zk.n_encode)
+		}
+
+		proof, err := zk.GenerateProof(ctx, []int{1, 0, 1, 1, 0, 1})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		verify, err := zk.VerifyProof(ctx, *proof[0])
+		if !verify {
+			t.Fatalf("%s: verification failed: %v", pred, err)
+		}
+
+		// changing one value of a test raises the degree of its polynomial
+		tampered := *proof[0]
+		tampered.LinearTest = append([]int{}, tampered.LinearTest...)
+		tampered.LinearTest[0] = (tampered.LinearTest[0] + 1) % zk.q
+		verify, _ = zk.VerifyProof(ctx, tampered)
+		if verify {
+			t.Fatalf("%s: verification succeeded for a tampered linear test", pred)
+		}
+	}
+}
+
+func TestLegacyLeafProof(t *testing.T) {
+	zk, err := NewLigeroZK(3, 1, 4, 1, 10631, 3, BitPredicate())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	proof, err := zk.generate_proof(ctx, []int{1, 0, 1}, LeafString)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// proofs without leaf version were committed with string leaves
+	data, err := json.Marshal(proof[0])
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var decoded Proof
+	err = json.Unmarshal(data, &decoded)
+	if err != nil || decoded.Leaf_version != LeafString {
+		t.Fatalf("expected legacy leaf version, but got %d (%v)", decoded.Leaf_version, err)
+	}
+
+	verify, err := zk.VerifyProof(ctx, decoded)
+	if !verify {
+		t.Fatalf("verification of legacy proof failed: %v", err)
+	}
+
+	// the leaf version is not interchangeable
+	decoded.Leaf_version = LeafBytes
+	verify, _ = zk.VerifyProof(ctx, decoded)
+	if verify {
+		t.Fatalf("verification succeeded with wrong leaf version")
+	}
+}
+
+var leaf_versions = []struct {
+	name    string
+	version int
+}{{"string", LeafString}, {"bytes", LeafBytes}}
+
+func BenchmarkGenerateProofLeaves(b *testing.B) {
+	zk, err := NewLigeroZK(2000, 20, 4, 1, 41543, 40, BitPredicate())
+	if err != nil {
+		b.Fatalf("err: %v", err)
+	}
+	secrets := make([]int, 2000)
+
+	for _, leaf := range leaf_versions {
+		b.Run(leaf.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, err := zk.generate_proof(ctx, secrets, leaf.version)
+				if err != nil {
+					b.Fatalf("err: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkVerifyProofLeaves(b *testing.B) {
+	zk, err := NewLigeroZK(2000, 20, 4, 1, 41543, 40, BitPredicate())
+	if err != nil {
+		b.Fatalf("err: %v", err)
+	}
+	secrets := make([]int, 2000)
+
+	for _, leaf := range leaf_versions {
+		proof, err := zk.generate_proof(ctx, secrets, leaf.version)
+		if err != nil {
+			b.Fatalf("err: %v", err)
+		}
+
+		b.Run(leaf.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				verify, err := zk.VerifyProof(ctx, *proof[0])
+				if !verify {
+					b.Fatalf("verification failed: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkCommitColumns(b *testing.B) {
+	zk, err := NewLigeroZK(2000, 20, 4, 1, 41543, 40, BitPredicate())
+	if err != nil {
+		b.Fatalf("err: %v", err)
+	}
+
+	// columns of the encoded extended witness of 20 blocks
+	columns := make([][]int, zk.n_encode)
+	for i := range columns {
+		columns[i] = RandVector([]byte{byte(i), byte(i >> 8)}, zk.n_rows(), zk.q)
+	}
+
+	masks, err := zk.generate_masks()
+	if err != nil {
+		b.Fatalf("err: %v", err)
+	}
+
+	for _, leaf := range leaf_versions {
+		b.Run(leaf.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, _, _, err := zk.generate_merkletree(columns, masks, leaf.version)
+				if err != nil {
+					b.Fatalf("err: %v", err)
+				}
+			}
+		})
+	}
+}
+
+var encodings = []struct {
+	name string
+	q    int
+}{{"lagrange-41543", 41543}, {"ntt-998244353", 998244353}}
+
+func BenchmarkEncodeRow(b *testing.B) {
+	for _, encoding := range encodings {
+		zk, err := NewLigeroZK(2000, 20, 4, 1, encoding.q, 40, BitPredicate())
+		if err != nil {
+			b.Fatalf("err: %v", err)
+		}
+		row := make([]int, zk.l)
+
+		b.Run(encoding.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, err := zk.npss.Split(row, i)
+				if err != nil {
+					b.Fatalf("err: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGenerateProofEncoding(b *testing.B) {
+	secrets := make([]int, 2000)
+	for _, encoding := range encodings {
+		zk, err := NewLigeroZK(2000, 20, 4, 1, encoding.q, 40, BitPredicate())
+		if err != nil {
+			b.Fatalf("err: %v", err)
+		}
+
+		b.Run(encoding.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, err := zk.GenerateProof(ctx, secrets)
+				if err != nil {
+					b.Fatalf("err: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkVerifyProofEncoding(b *testing.B) {
+	secrets := make([]int, 2000)
+	for _, encoding := range encodings {
+		zk, err := NewLigeroZK(2000, 20, 4, 1, encoding.q, 40, BitPredicate())
+		if err != nil {
+			b.Fatalf("err: %v", err)
+		}
+		proof, err := zk.GenerateProof(ctx, secrets)
+		if err != nil {
+			b.Fatalf("err: %v", err)
+		}
+
+		b.Run(encoding.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				// a new verifier every time, the lagrange constants are cached per verifier
+				zk, _ := NewLigeroZK(2000, 20, 4, 1, encoding.q, 40, BitPredicate())
+				verify, err := zk.VerifyProof(ctx, *proof[0])
+				if !verify {
+					b.Fatalf("verification failed: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateRepetitions(t *testing.T) {
+	pred := And(RangePredicate(4), SumAtMostPredicate(20))
+	for _, q := range []int{10631, 998244353} {
+		zk, err := NewLigeroZKFromParams(Params{N_secret: 6, M: 2, N_server: 4, T: 1, Q: q, N_open: 3, Repetitions: 3}, pred)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		proof, err := zk.GenerateProof(ctx, []int{15, 5, 0, 0, 0, 0})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if len(proof[0].QuadraTest) != 3*zk.n_encode || len(proof[0].ColumnTest[0].Repeated_masks) != 8 {
+			t.Fatalf("q %d: expected 3 repetitions of the tests and masks", q)
+		}
+
+		for _, p := range proof {
+			verify, err := zk.VerifyProof(ctx, *p)
+			if !verify {
+				t.Fatalf("q %d: verification failed: %v", q, err)
+			}
+		}
+
+		// every repetition is checked, not only the first
+		tampered := *proof[0]
+		tampered.QuadraTest = append([]int{}, tampered.QuadraTest...)
+		tampered.QuadraTest[2*zk.n_encode] = (tampered.QuadraTest[2*zk.n_encode] + 1) % q
+		verify, _ := zk.VerifyProof(ctx, tampered)
+		if verify {
+			t.Fatalf("q %d: verification succeeded for a tampered third repetition", q)
+		}
+
+		// the masks of the later repetitions are committed with the column
+		tampered = *proof[0]
+		tampered.ColumnTest = append([]OpenedColumn{}, tampered.ColumnTest...)
+		tampered.ColumnTest[0].Repeated_masks = append([]int{}, tampered.ColumnTest[0].Repeated_masks...)
+		tampered.ColumnTest[0].Repeated_masks[6] = (tampered.ColumnTest[0].Repeated_masks[6] + 1) % q
+		verify, _ = zk.VerifyProof(ctx, tampered)
+		if verify {
+			t.Fatalf("q %d: verification succeeded for a tampered mask of the third repetition", q)
+		}
+
+		// a verifier with a single repetition refuses the proof
+		single, err := NewLigeroZK(6, 2, 4, 1, q, 3, pred)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		verify, _ = single.VerifyProof(ctx, *proof[0])
+		if verify {
+			t.Fatalf("q %d: verification succeeded with a different number of repetitions", q)
+		}
+
+		// an invalid input is still rejected
+		proof, err = zk.GenerateProof(ctx, []int{15, 5, 1, 0, 0, 0})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		verify, _ = zk.VerifyProof(ctx, *proof[0])
+		if verify {
+			t.Fatalf("q %d: verification succeeded for a sum above the bound", q)
+		}
+	}
+
+	_, err := NewLigeroZKFromParams(Params{N_secret: 6, M: 2, N_server: 4, T: 1, Q: 10631, N_open: 3, Repetitions: -1}, BitPredicate())
+	if err == nil {
+		t.Fatalf("expected error for a negative number of repetitions")
+	}
+}
+
+func TestConcurrentProofs(t *testing.T) {
+	// one LigeroZK per experiment is shared by all request handlers, run with -race
+	pred := And(RangePredicate(4), SumAtMostPredicate(20))
+	for _, q := range []int{10631, 998244353} {
+		zk, err := NewLigeroZK(6, 2, 4, 1, q, 3, pred)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		var wg sync.WaitGroup
+		errs := make(chan error, 16)
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				c := Context{Exp_ID: "exp1", Client_ID: fmt.Sprintf("c%d", i)}
+				proof, err := zk.GenerateProof(c, []int{15, 5, 0, 0, 0, i % 2})
+				if err != nil {
+					errs <- err
+					return
+				}
+
+				verify, err := zk.VerifyProof(c, *proof[i%4])
+				if verify != (i%2 == 0) {
+					errs <- fmt.Errorf("client %d: expected %v, got %v (%v)", i, i%2 == 0, verify, err)
+				}
+
+				valid, batch_errs := zk.VerifyBatch([]Context{c, c}, []Proof{*proof[0], *proof[1]})
+				if valid[0] != (i%2 == 0) || valid[1] != valid[0] {
+					errs <- fmt.Errorf("client %d: batch verification gives %v (%v)", i, valid, batch_errs)
+				}
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			t.Fatalf("q %d: %v", q, err)
+		}
+	}
+}
@@ -13,18 +13,19 @@ var verify_end time.Duration
 
 func main() {
 
-	zk, err := ligero.NewLigeroZK(100000, 100, 7, 2, 41543, 240)
+	zk, err := ligero.NewLigeroZK(100000, 100, 7, 2, 41543, 240, ligero.BitPredicate())
 	if err != nil {
 		log.Fatalf("err: %v", err)
 	}
 
+	ctx := ligero.Context{Exp_ID: "exp1", Client_ID: "c1"}
 	secrets := make([]int, 100000)
 	for i := 0; i < 100000; i++ {
 		secrets[i] = 1
 	}
 
 	start := time.Now()
-	proof, err := zk.GenerateProof(secrets)
+	proof, err := zk.GenerateProof(ctx, secrets)
 	end := time.Since(start)
 	proof_size, share_size := zk.GetSize(*proof[0])
 	fmt.Printf("share size: %d, proof size: %d\n", share_size, proof_size)
@@ -41,7 +42,7 @@ func main() {
 			verify_start = time.Now()
 		}
 
-		verify, err := zk.VerifyProof(*proof[i])
+		verify, err := zk.VerifyProof(ctx, *proof[i])
 		if i == 0 {
 			verify_end = time.Since(verify_start)
 			fmt.Printf("proof verification end: %v\n", verify_end)
@@ -2,35 +2,67 @@ package ligero
 
 import (
 	"fmt"
+
+	"example.com/SMC/pkg/field"
 )
 
-func AddMatrix(matrix1 [][]int, matrix2 [][]int, q int) [][]int {
-	result := make([][]int, len(matrix1))
-	for i, a := range matrix1 {
-		for j := range a {
-			result[i] = append(result[i], mod(matrix1[i][j]+matrix2[i][j], q))
-		}
+func AddMatrix(matrix1 [][]int, matrix2 [][]int, q int) ([][]int, error) {
+	f, err := modulus(q)
+	if err != nil {
+		return nil, err
 	}
-	return result
+	return add_matrix(f, matrix1, matrix2, f.AddInt)
 }
 
-func SubMatrix(matrix1 [][]int, matrix2 [][]int, q int) [][]int {
+func SubMatrix(matrix1 [][]int, matrix2 [][]int, q int) ([][]int, error) {
+	f, err := modulus(q)
+	if err != nil {
+		return nil, err
+	}
+	return add_matrix(f, matrix1, matrix2, f.SubInt)
+}
+
+func MulMatrix(matrix1, matrix2 [][]int, q int) ([][]int, error) {
+	f, err := modulus(q)
+	if err != nil {
+		return nil, err
+	}
+	return mul_matrix(f, matrix1, matrix2)
+}
+
+func MulList(list1 []int, list2 []int, q int) (int, error) {
+	f, err := modulus(q)
+	if err != nil {
+		return 0, err
+	}
+	return mul_list(f, list1, list2)
+}
+
+// add_matrix applies op to the entries of two matrices of the same dimensions
+func add_matrix(f *field.Field, matrix1, matrix2 [][]int, op func(a, b int) int) ([][]int, error) {
+	if len(matrix1) != len(matrix2) {
+		return nil, fmt.Errorf("%w: matrices have %d and %d rows", ErrDimensionMismatch, len(matrix1), len(matrix2))
+	}
 	result := make([][]int, len(matrix1))
 	for i, a := range matrix1 {
+		if len(a) != len(matrix2[i]) {
+			return nil, fmt.Errorf("%w: rows %d of the matrices have different lengths", ErrDimensionMismatch, i)
+		}
 		for j := range a {
-			result[i] = append(result[i], mod(matrix1[i][j]-matrix2[i][j], q))
+			result[i] = append(result[i], op(matrix1[i][j], matrix2[i][j]))
 		}
 	}
-	return result
+	return result, nil
 }
 
-func MulMatrix(matrix1, matrix2 [][]int, q int) ([][]int, error) {
+// mul_matrix multiplies two matrices over the field f
+func mul_matrix(f *field.Field, matrix1, matrix2 [][]int) ([][]int, error) {
 
 	rows1, cols1 := len(matrix1), len(matrix1[0])
 	rows2, cols2 := len(matrix2), len(matrix2[0])
 
 	if cols1 != rows2 {
-		return nil, fmt.Errorf("Matrix multiplication is not possible. The number of columns in the first matrix must be equal to the number of rows in the second matrix.")
+		return nil, fmt.Errorf("%w: number of columns of the first matrix differs from the number of rows of the second", ErrDimensionMismatch)
 
 	}
 
@@ -42,29 +74,43 @@ func MulMatrix(matrix1, matrix2 [][]int, q int) ([][]int, error) {
 	for i := 0; i < rows1; i++ {
 		for j := 0; j < cols2; j++ {
 			for k := 0; k < cols1; k++ {
-				result[i][j] += matrix1[i][k] * matrix2[k][j]
+				result[i][j] = f.AddInt(result[i][j], f.MulInt(matrix1[i][k], matrix2[k][j]))
 			}
-			result[i][j] = mod(result[i][j], q)
 		}
 	}
 	return result, nil
 }
 
-func MulList(list1 []int, list2 []int, q int) (int, error) {
+// mul_list computes the inner product of two vectors over the field f
+func mul_list(f *field.Field, list1 []int, list2 []int) (int, error) {
 	if len(list1) != len(list2) {
-		return 0, fmt.Errorf("Invalid inputs: inputs length are different so that multiplication cannot be done")
+		return 0, fmt.Errorf("%w: inputs length are different so that multiplication cannot be done", ErrDimensionMismatch)
 	}
 	result := 0
 	for i := 0; i < len(list1); i++ {
-		result += list1[i] * list2[i]
+		result = f.AddInt(result, f.MulInt(list1[i], list2[i]))
 	}
 
-	return mod(result, q), nil
+	return result, nil
 }
 
+// Interpolate_at_Point returns the value at x of the polynomial through the values y_samples at the share points
+// x_samples of all columns, with the basis polynomials of NewLigeroZK for the secret points
 func (zk *LigeroZK) Interpolate_at_Point(x_samples []int, y_samples []int, x int, q int) (int, error) {
+	return zk.interpolate(zk.glob_constants.basis, x_samples, y_samples, x)
+}
+
+// Interpolate_at_Point_Code_Test returns the value at x of the polynomial through the values y_samples at the
+// share points x_samples of the first Degree() columns, with the basis polynomials of NewLigeroZK for the columns
+func (zk *LigeroZK) Interpolate_at_Point_Code_Test(x_samples []int, y_samples []int, x int, q int) (int, error) {
+	return zk.interpolate(zk.glob_constants_code_test.basis, x_samples, y_samples, x)
+}
+
+// interpolate evaluates with the precomputed basis polynomials at x, which are only read so that
+// a LigeroZK can be shared by goroutines, and computes them for other points
+func (zk *LigeroZK) interpolate(table map[int][]int, x_samples []int, y_samples []int, x int) (int, error) {
 	if len(x_samples) != len(y_samples) {
-		return 0, fmt.Errorf("Invalid inputs: x_samples and y_samples length are different")
+		return 0, fmt.Errorf("%w: x_samples and y_samples length are different", ErrDimensionMismatch)
 
 	}
 
@@ -74,86 +120,45 @@ func (zk *LigeroZK) Interpolate_at_Point(x_samples []int, y_samples []int, x int
 		}
 	}
 
-	if !zk.glob_constants.flag_denom {
-		zk.glob_constants.values_denom = make([]int, len(x_samples))
-		zk.glob_constants.values_denom = GenerateLagrangeConstants(x_samples, x, q)
-		zk.glob_constants.flag_denom = true
-	}
-
-	if !zk.glob_constants.flag_num[x-1] {
-		zk.glob_constants.values_num[x-1] = make([]int, len(x_samples))
-
-		num := 1
-		for j := 0; j < len(x_samples); j++ {
-			if x == x_samples[j] {
-				fmt.Println("ERROR: EQUAL. Numerators goes to zero!!")
-			}
-			num = mod(num*(x_samples[j]-x), q)
-		}
-
-		for i := 0; i < len(x_samples); i++ {
-
-			zk.glob_constants.values_num[x-1][i] = mod(inverse(x_samples[i]-x, q)*num, q)
-		}
-		// p.glob_constant_num[x-1] = p.lagrange_constants_for_point(x_samples)
-		zk.glob_constants.flag_num[x-1] = true
-
+	basis, ok := table[x]
+	if !ok || len(basis) != len(x_samples) {
+		basis = lagrange_table(zk.field, x_samples, []int{x})[x]
 	}
 
 	y := 0
 	for i := 0; i < len(y_samples); i++ {
-		y = y + mod(y_samples[i]*zk.glob_constants.values_denom[i]*zk.glob_constants.values_num[x-1][i], q)
+		y = zk.field.AddInt(y, zk.field.MulInt(y_samples[i], basis[i]))
 	}
-	return mod(y, q), nil
+	return y, nil
 }
 
-func (zk *LigeroZK) Interpolate_at_Point_Code_Test(x_samples []int, y_samples []int, x int, q int) (int, error) {
-	if len(x_samples) != len(y_samples) {
-		return 0, fmt.Errorf("Invalid inputs: x_samples and y_samples length are different")
-
-	}
-
-	for index, item := range x_samples {
-		if item == x {
-			return y_samples[index], nil
-		}
-	}
-	if !zk.glob_constants_code_test.flag_denom {
-		zk.glob_constants_code_test.values_denom = make([]int, len(x_samples))
-		zk.glob_constants_code_test.values_denom = GenerateLagrangeConstants(x_samples, x, q)
-		zk.glob_constants_code_test.flag_denom = true
-
+// lagrange_table returns the lagrange basis polynomials of the points x_samples at each of the points
+func lagrange_table(f *field.Field, x_samples []int, points []int) map[int][]int {
+	xs := make([]uint64, len(x_samples))
+	for i, x := range x_samples {
+		xs[i] = f.Reduce(x)
 	}
+	weights := f.LagrangeWeights(xs)
 
-	if !zk.glob_constants_code_test.flag_num[x-1] {
-		zk.glob_constants_code_test.values_num[x-1] = make([]int, len(x_samples))
-
-		num := 1
-		for j := 0; j < len(x_samples); j++ {
-			if x == x_samples[j] {
-				fmt.Println("ERROR: EQUAL. Numerators goes to zero!! IN INTERPOLATE CODE TEST")
-			}
-			num = mod(num*(x_samples[j]-x), q)
+	table := make(map[int][]int, len(points))
+	for _, x := range points {
+		basis := f.LagrangeBasis(xs, weights, f.Reduce(x))
+		table[x] = make([]int, len(basis))
+		for i, b := range basis {
+			table[x][i] = int(b)
 		}
-
-		for i := 0; i < len(x_samples); i++ {
-
-			zk.glob_constants_code_test.values_num[x-1][i] = mod(inverse(x_samples[i]-x, q)*num, q)
-		}
-		// p.glob_constant_num[x-1] = p.lagrange_constants_for_point(x_samples)
-		zk.glob_constants_code_test.flag_num[x-1] = true
-
-	}
-
-	y := 0
-	for i := 0; i < len(y_samples); i++ {
-		y = y + mod(y_samples[i]*zk.glob_constants_code_test.values_denom[i]*zk.glob_constants_code_test.values_num[x-1][i], q)
 	}
-	return mod(y, q), nil
+	return table
 }
 
-// lagrange_constants_for_point returns lagrange constants for the given x
-func GenerateLagrangeConstants(x_samples []int, x int, q int) []int {
+// GenerateLagrangeConstants returns the denominators of the lagrange basis polynomials of the distinct points
+// x_samples, inverted modulo q: constants[i] = 1/prod_{j != i} (x_samples[j] - x_samples[i]). They do not depend on
+// the point the polynomial is evaluated at, x is ignored.
+func GenerateLagrangeConstants(x_samples []int, x int, q int) ([]int, error) {
+	f, err := modulus(q)
+	if err != nil {
+		return nil, err
+	}
 
 	constants := make([]int, len(x_samples))
 	for i := range constants {
@@ -167,60 +172,22 @@ func GenerateLagrangeConstants(x_samples []int, x int, q int) []int {
 			if j != i {
 				xj := x_samples[j]
 
-				denum = mod(denum*(xj-xi), q)
+				denum = f.MulInt(denum, xj-xi)
 			}
 		}
-		constants[i] = mod(inverse(denum, q), q)
+		constants[i] = f.InvInt(denum)
 	}
 
-	return constants
+	return constants, nil
 }
 
-// from http://www.ucl.ac.uk/~ucahcjm/combopt/ext_gcd_python_programs.pdf
-func egcd_binary(a int, b int) int {
-	u, v, s, t, r := 1, 0, 0, 1, 0
-	for (mod(a, 2) == 0) && (mod(b, 2) == 0) {
-		a, b, r = a/2, b/2, r+1
-	}
-
-	alpha, beta := a, b
-
-	for mod(a, 2) == 0 {
-		a = a / 2
-		if (mod(u, 2) == 0) && (mod(v, 2) == 0) {
-			u, v = u/2, v/2
-		} else {
-			u, v = (u+beta)/2, (v-alpha)/2
-		}
-
-	}
-
-	for a != b {
-		if mod(b, 2) == 0 {
-			b = b / 2
-			if (mod(s, 2) == 0) && (mod(t, 2) == 0) {
-				s, t = s/2, t/2
-			} else {
-				s, t = (s+beta)/2, (t-alpha)/2
-			}
-		} else if b < a {
-			a, b, u, v, s, t = b, a, s, t, u, v
-		} else {
-
-			b, s, t = b-a, s-u, t-v
-		}
-
+// modulus returns the field of integers modulo the prime q
+func modulus(q int) (*field.Field, error) {
+	f, err := field.NewInt(q)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidParams, err)
 	}
-
-	return s
-}
-
-// inverse calculates the inverse of a number
-func inverse(a int, q int) int {
-
-	a = (a + q) % q
-	b := egcd_binary(a, q)
-	return b
+	return f, nil
 }
 
 // mod computes a%b and a could be negative number
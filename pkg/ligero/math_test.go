@@ -3,6 +3,7 @@ package ligero
 import (
 	"errors"
 	"fmt"
+	"math/big"
 	"reflect"
 	"testing"
 )
@@ -40,3 +41,47 @@ func TestMulMatrix(t *testing.T) {
 		}
 	}
 }
+
+func TestMulListLargeModulus(t *testing.T) {
+	// 2^61-1, products of two elements overflow int
+	q := 1<<61 - 1
+	list1 := []int{q - 1, q - 2, 1 << 40, 12345}
+	list2 := []int{q - 1, q - 3, 1 << 40, q - 1}
+
+	result, err := MulList(list1, list2, q)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	expected := new(big.Int)
+	for i := range list1 {
+		expected.Add(expected, new(big.Int).Mul(big.NewInt(int64(list1[i])), big.NewInt(int64(list2[i]))))
+	}
+	expected.Mod(expected, big.NewInt(int64(q)))
+	if result != int(expected.Int64()) {
+		t.Fatalf("Expected %v, but got %v", expected, result)
+	}
+}
+
+func TestLagrangeConstantsLargeModulus(t *testing.T) {
+	q := 1<<61 - 1
+	x_samples := []int{q - 1, q - 2, q - 3, 1, 2, 3}
+
+	constants, err := GenerateLagrangeConstants(x_samples, 0, q)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for i, xi := range x_samples {
+		// constants[i] * prod_{j != i} (xj - xi) = 1
+		product := big.NewInt(int64(constants[i]))
+		for j, xj := range x_samples {
+			if j != i {
+				product.Mul(product, big.NewInt(int64(xj-xi)))
+			}
+		}
+		product.Mod(product, big.NewInt(int64(q)))
+		if product.Int64() != 1 {
+			t.Fatalf("constant %d is not the inverse of its denominator", i)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package ligero
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// The opened columns are authenticated by a single Merkle multi-proof: the hashes of the nodes that are
+// needed to recompute the root from the opened leaves and cannot be computed from them, instead of one
+// authentication path per column, which repeat the nodes near the root.
+//
+// column_tree has the layout of go-merkletree, so that roots and legacy authentication paths are unchanged:
+// for b the power of two above the number of leaves, nodes[b+i] is the blake2b hash of leaf i, nodes[i] the
+// hash of nodes[2i] and nodes[2i+1] for i < b, nodes[1] the root, and the nodes of missing leaves are empty.
+
+type column_tree struct {
+	nodes    [][]byte
+	n_leaves int
+}
+
+// tree_width returns the power of two above the number of leaves
+func tree_width(n_leaves int) int {
+	b := 1
+	for b < n_leaves {
+		b *= 2
+	}
+	return b
+}
+
+func merkle_hash(data ...[]byte) []byte {
+	hash := blake2b.Sum256(bytes.Join(data, nil))
+	return hash[:]
+}
+
+func new_column_tree(leaves [][]byte) (*column_tree, error) {
+	if len(leaves) == 0 {
+		return nil, fmt.Errorf("tree must have at least 1 leaf")
+	}
+
+	b := tree_width(len(leaves))
+	nodes := make([][]byte, 2*b)
+	for i, leaf := range leaves {
+		nodes[b+i] = merkle_hash(leaf)
+	}
+	for i := b - 1; i > 0; i-- {
+		nodes[i] = merkle_hash(nodes[2*i], nodes[2*i+1])
+	}
+	return &column_tree{nodes: nodes, n_leaves: len(leaves)}, nil
+}
+
+func (t *column_tree) Root() []byte {
+	return t.nodes[1]
+}
+
+// Authpath returns the authentication path of a leaf as go-merkletree generates it, from the leaf to the root
+func (t *column_tree) Authpath(index int) ([][]byte, error) {
+	return t.Multiproof([]int{index})
+}
+
+// Multiproof returns the hashes that authenticate the leaves at the indices, which may repeat, level by level
+// from the leaves to the root and within a level by position
+func (t *column_tree) Multiproof(indices []int) ([][]byte, error) {
+	level, err := leaf_nodes(t.n_leaves, indices)
+	if err != nil {
+		return nil, err
+	}
+
+	var hashes [][]byte
+	for len(level) > 0 && level[0] > 1 {
+		parents := make([]int, 0, len(level))
+		for i := 0; i < len(level); i++ {
+			node := level[i]
+			if i+1 < len(level) && level[i+1] == node^1 {
+				i++
+			} else {
+				hashes = append(hashes, t.nodes[node^1])
+			}
+			parents = append(parents, node/2)
+		}
+		level = parents
+	}
+	return hashes, nil
+}
+
+// leaf_nodes returns the sorted distinct nodes of the leaves at the indices
+func leaf_nodes(n_leaves int, indices []int) ([]int, error) {
+	b := tree_width(n_leaves)
+	nodes := make([]int, 0, len(indices))
+	for _, index := range indices {
+		if index < 0 || index >= n_leaves {
+			return nil, fmt.Errorf("leaf index %d is out of range", index)
+		}
+		nodes = append(nodes, b+index)
+	}
+	sort.Ints(nodes)
+
+	distinct := nodes[:0]
+	for i, node := range nodes {
+		if i == 0 || node != nodes[i-1] {
+			distinct = append(distinct, node)
+		}
+	}
+	return distinct, nil
+}
+
+// verify_multiproof checks that leaves[i] is the leaf at indices[i] of the tree with n_leaves leaves and the root,
+// given the hashes of Multiproof(indices). A repeated index has to come with the same leaf.
+func verify_multiproof(n_leaves int, indices []int, leaves [][]byte, hashes [][]byte, root []byte) (bool, error) {
+	if len(indices) == 0 || len(indices) != len(leaves) {
+		return false, fmt.Errorf("number of leaves and indices of the multi-proof differ")
+	}
+
+	b := tree_width(n_leaves)
+	known := make(map[int][]byte, len(indices))
+	for i, index := range indices {
+		if index < 0 || index >= n_leaves {
+			return false, fmt.Errorf("leaf index %d is out of range", index)
+		}
+		hash := merkle_hash(leaves[i])
+		if previous, ok := known[b+index]; ok && !bytes.Equal(previous, hash) {
+			return false, fmt.Errorf("leaf %d is opened with different values", index)
+		}
+		known[b+index] = hash
+	}
+
+	level, _ := leaf_nodes(n_leaves, indices)
+	next := 0
+	for len(level) > 0 && level[0] > 1 {
+		parents := make([]int, 0, len(level))
+		for i := 0; i < len(level); i++ {
+			node := level[i]
+			var sibling []byte
+			if i+1 < len(level) && level[i+1] == node^1 {
+				sibling = known[node^1]
+				i++
+			} else {
+				if next == len(hashes) {
+					return false, fmt.Errorf("multi-proof is too short")
+				}
+				sibling = hashes[next]
+				next++
+			}
+
+			if node%2 == 0 {
+				known[node/2] = merkle_hash(known[node], sibling)
+			} else {
+				known[node/2] = merkle_hash(sibling, known[node])
+			}
+			parents = append(parents, node/2)
+		}
+		level = parents
+	}
+
+	if next != len(hashes) {
+		return false, fmt.Errorf("multi-proof has %d unused hashes", len(hashes)-next)
+	}
+	if !bytes.Equal(known[1], root) {
+		return false, fmt.Errorf("failed to verify the multi-proof of the opened columns")
+	}
+	return true, nil
+}
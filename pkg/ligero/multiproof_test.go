@@ -0,0 +1,126 @@
+package ligero
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	merkletree "github.com/wealdtech/go-merkletree"
+)
+
+func TestMultiproof(t *testing.T) {
+	for _, n := range []int{1, 3, 8, 13} {
+		leaves := make([][]byte, n)
+		for i := range leaves {
+			leaves[i] = []byte(fmt.Sprintf("column %d", i))
+		}
+
+		tree, err := new_column_tree(leaves)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		reference, err := merkletree.New(leaves)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if !bytes.Equal(tree.Root(), reference.Root()) {
+			t.Fatalf("%d leaves: root differs from go-merkletree", n)
+		}
+
+		// indices repeat as the opened columns of a proof may
+		indices := []int{n - 1, 0, n / 2, 0}
+		opened := make([][]byte, len(indices))
+		for i, index := range indices {
+			opened[i] = leaves[index]
+		}
+		hashes, err := tree.Multiproof(indices)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		verified, err := verify_multiproof(n, indices, opened, hashes, tree.Root())
+		if !verified {
+			t.Fatalf("%d leaves: verification failed: %v", n, err)
+		}
+		depth := 0
+		for b := 1; b < n; b *= 2 {
+			depth++
+		}
+		if n > 1 && len(hashes) >= len(indices)*depth {
+			t.Fatalf("%d leaves: multi-proof has %d hashes", n, len(hashes))
+		}
+
+		if n == 1 {
+			continue
+		}
+
+		// a changed leaf, a leaf at another index, a repeated index with another leaf or changed hashes fail
+		changed := append([][]byte{}, opened...)
+		changed[2] = []byte("other")
+		repeated := append([][]byte{}, opened...)
+		repeated[3] = leaves[1]
+		moved := append([]int{}, indices...)
+		moved[0] = n - 2
+		invalid := []struct {
+			indices []int
+			leaves  [][]byte
+			hashes  [][]byte
+		}{
+			{indices, changed, hashes},
+			{indices, repeated, hashes},
+			{moved, opened, hashes},
+			{indices, opened, hashes[1:]},
+			{indices, opened, append(append([][]byte{}, hashes...), hashes[0])},
+			{[]int{n}, opened[:1], hashes},
+		}
+		for i, test := range invalid {
+			verified, _ := verify_multiproof(n, test.indices, test.leaves, test.hashes, tree.Root())
+			if verified {
+				t.Fatalf("%d leaves: invalid multi-proof %d verified", n, i)
+			}
+		}
+	}
+}
+
+func TestMultiproofSize(t *testing.T) {
+	zk, err := NewLigeroZK(2000, 20, 4, 1, 41543, 40, BitPredicate())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	secrets := make([]int, 2000)
+
+	// proofs with string leaves carry an authentication path per opened column
+	legacy, err := zk.generate_proof(ctx, secrets, LeafString)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	proof, err := zk.GenerateProof(ctx, secrets)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	for _, p := range []*Proof{legacy[0], proof[0]} {
+		verify, err := zk.VerifyProof(ctx, *p)
+		if !verify {
+			t.Fatalf("verification failed: %v", err)
+		}
+	}
+
+	legacy_size, _ := zk.GetSize(*legacy[0])
+	size, _ := zk.GetSize(*proof[0])
+	paths := hashes_size(proof[0].Multiproof)
+	for _, col := range legacy[0].ColumnTest {
+		paths -= hashes_size(col.Authpath)
+	}
+	if size >= legacy_size || int64(paths) != size-legacy_size {
+		t.Fatalf("proof of %d bytes with a multi-proof, %d bytes with authentication paths", size, legacy_size)
+	}
+	t.Logf("proof of %d bytes with a multi-proof, %d bytes with authentication paths", size, legacy_size)
+
+	// both kinds of authentication cannot be mixed
+	mixed := *proof[0]
+	mixed.ColumnTest = legacy[0].ColumnTest
+	verify, _ := zk.VerifyProof(ctx, mixed)
+	if verify {
+		t.Fatalf("verification succeeded with a multi-proof and authentication paths")
+	}
+}
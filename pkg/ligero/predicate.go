@@ -0,0 +1,740 @@
+package ligero
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"strconv"
+	"strings"
+)
+
+// The extended witness consists of m blocks followed by the extra rows of the predicate.
+// Each block holds a row of l secrets, the rows of their shares and the auxiliary rows of the predicate,
+// so that the j-th entry of an auxiliary row belongs to the j-th secret of the block.
+const (
+	RowSecret = iota // the secret row of a block
+	RowAux           // an auxiliary row of a block
+	RowExtra         // a row appended after the last block
+)
+
+// Row refers to a row of the extended witness
+type Row struct {
+	Kind  int
+	Index int
+}
+
+// Term is a row of the extended witness multiplied by a coefficient
+type Term struct {
+	Row   Row
+	Coeff int
+}
+
+// Quadratic constrains A*B = C entry by entry
+type Quadratic struct {
+	A Row
+	B Row
+	C Row
+}
+
+// Linear constrains the sum of its terms to be 0 entry by entry
+type Linear struct {
+	Terms []Term
+}
+
+// EntryTerm is a single entry of the extended witness multiplied by a coefficient: the entry of the secret with
+// index Index in a secret or auxiliary row, or the entry Index of an extra row
+type EntryTerm struct {
+	Row   Row
+	Index int
+	Coeff int
+}
+
+// Sum constrains the sum of its terms over all entries of the extended witness plus its entry terms to equal Value.
+// A term of a secret or auxiliary row is summed over all blocks. Entry terms relate entries of different secrets,
+// which the entry by entry constraints cannot.
+type Sum struct {
+	Terms   []Term
+	Entries []EntryTerm
+	Value   int
+}
+
+// Predicate is the validity predicate of a client's input vector, expressed as linear and quadratic
+// constraints over the extended witness. Constraints referring to secret and auxiliary rows are
+// enforced in every block, constraints referring only to extra rows are enforced once.
+type Predicate interface {
+	// AuxRows returns the number of auxiliary rows appended to every block
+	AuxRows() int
+	// ExtraRows returns the number of rows appended after the last block
+	ExtraRows() int
+	// MaxValue returns the largest value of a secret satisfying the predicate, or 0 if it is unbounded
+	MaxValue() int
+	// Validate checks the predicate can be enforced soundly for n_secret secrets packed l per row in modulus q,
+	// max_value is the largest secret admitted by the predicates it is combined with
+	Validate(n_secret, l, max_value, q int) error
+	// Witness returns the auxiliary values of each secret and the extra rows of length l
+	Witness(secrets []int, l int) ([][]int, [][]int)
+	Quadratic() []Quadratic
+	Linear() []Linear
+	Sums() []Sum
+	// String returns the predicate specification accepted by ParsePredicate
+	String() string
+}
+
+// BitPredicate accepts 0/1 input vectors
+func BitPredicate() Predicate {
+	return &rangePredicate{n_bits: 1}
+}
+
+// RangePredicate accepts input vectors whose values lie in [0, 2^n_bits).
+// For n_bits > 1 every secret is decomposed into n_bits auxiliary bit rows.
+func RangePredicate(n_bits int) Predicate {
+	return &rangePredicate{n_bits: n_bits}
+}
+
+// SumEqualPredicate accepts input vectors whose values sum up to k
+func SumEqualPredicate(k int) Predicate {
+	return &sumPredicate{k: k, exact: true}
+}
+
+// SumAtMostPredicate accepts input vectors whose values sum up to at most k.
+// The slack k-sum is decomposed into bits stored in the first entry of the extra rows.
+func SumAtMostPredicate(k int) Predicate {
+	n_slack := bits.Len(uint(k))
+	if n_slack == 0 {
+		n_slack = 1
+	}
+	return &sumPredicate{k: k, exact: false, n_slack: n_slack}
+}
+
+// OneHotPredicate accepts 0/1 input vectors with exactly one 1, e.g. a categorical answer
+func OneHotPredicate() Predicate {
+	return And(BitPredicate(), SumEqualPredicate(1))
+}
+
+// NormPredicate accepts input vectors of signed values in [-2^(n_bits-1), 2^(n_bits-1)) whose squared
+// L2 norm is at most bound. Every secret is decomposed into n_bits two's complement bit rows followed by
+// the row of its square, the slack bound-norm is decomposed into bits stored in the first entry of the extra rows.
+func NormPredicate(n_bits int, bound int) Predicate {
+	n_slack := bits.Len(uint(bound))
+	if n_slack == 0 {
+		n_slack = 1
+	}
+	return &normPredicate{n_bits: n_bits, bound: bound, n_slack: n_slack}
+}
+
+// ProductPredicate accepts input vectors whose secret c is the product of the secrets a and b, e.g. the bit of
+// a client having both attributes a and b. The three secrets are copied into the first entry of three extra rows
+// by entry terms, the product is checked on the extra rows.
+func ProductPredicate(a, b, c int) Predicate {
+	return &productPredicate{a: a, b: b, c: c}
+}
+
+// And accepts input vectors satisfying all of the given predicates
+func And(preds ...Predicate) Predicate {
+	return &andPredicate{preds: preds}
+}
+
+// ParsePredicate parses a predicate specification as used in experiment configurations,
+// predicates are joined by "+", e.g. "bit", "range:8", "onehot", "bit+atmost:3", "norm:8:1000" or "bit+product:0:1:2"
+func ParsePredicate(spec string) (Predicate, error) {
+	var preds []Predicate
+	for _, item := range strings.Split(spec, "+") {
+		fields := strings.Split(strings.TrimSpace(item), ":")
+		name := fields[0]
+		args := make([]int, len(fields)-1)
+		for i, arg := range fields[1:] {
+			v, err := strconv.Atoi(arg)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid argument of predicate %s: %s", ErrInvalidParams, name, arg)
+			}
+			args[i] = v
+		}
+
+		switch {
+		case name == "bit" && len(args) == 0:
+			preds = append(preds, BitPredicate())
+		case name == "onehot" && len(args) == 0:
+			preds = append(preds, OneHotPredicate())
+		case name == "range" && len(args) == 1:
+			preds = append(preds, RangePredicate(args[0]))
+		case name == "sum" && len(args) == 1:
+			preds = append(preds, SumEqualPredicate(args[0]))
+		case name == "atmost" && len(args) == 1:
+			preds = append(preds, SumAtMostPredicate(args[0]))
+		case name == "norm" && len(args) == 2:
+			preds = append(preds, NormPredicate(args[0], args[1]))
+		case name == "product" && len(args) == 3:
+			preds = append(preds, ProductPredicate(args[0], args[1], args[2]))
+		default:
+			return nil, fmt.Errorf("%w: unknown predicate %q", ErrInvalidParams, item)
+		}
+	}
+
+	if len(preds) == 1 {
+		return preds[0], nil
+	}
+	return And(preds...), nil
+}
+
+// Norms bounds the input vectors of n_secret secrets satisfying a predicate: it returns the largest absolute value
+// of a secret and the largest L1 and L2 norm of a vector, ok is false if the predicate does not bound the secrets
+func Norms(p Predicate, n_secret int) (max_abs, l1, l2 float64, ok bool) {
+	max_abs, l1, l2 = math.Inf(1), math.Inf(1), math.Inf(1)
+	for _, pred := range flatten(p) {
+		switch pred := pred.(type) {
+		case *rangePredicate:
+			if v := pred.MaxValue(); v > 0 {
+				max_abs = math.Min(max_abs, float64(v))
+			}
+		case *sumPredicate:
+			// sums are combined with a range predicate, the secrets are not negative
+			l1 = math.Min(l1, float64(pred.k))
+			l2 = math.Min(l2, float64(pred.k))
+		case *normPredicate:
+			if pred.n_bits > 0 {
+				max_abs = math.Min(max_abs, math.Ldexp(1, pred.n_bits-1))
+			}
+			norm := math.Sqrt(float64(pred.bound))
+			max_abs = math.Min(max_abs, norm)
+			l1 = math.Min(l1, math.Sqrt(float64(n_secret))*norm)
+			l2 = math.Min(l2, norm)
+		}
+	}
+	if math.IsInf(max_abs, 1) && math.IsInf(l1, 1) {
+		return 0, 0, 0, false
+	}
+
+	max_abs = math.Min(max_abs, l1)
+	l1 = math.Min(l1, float64(n_secret)*max_abs)
+	l2 = math.Min(math.Min(l2, l1), math.Sqrt(float64(n_secret))*max_abs)
+	return max_abs, l1, l2, true
+}
+
+// flatten returns the predicates a predicate is the conjunction of
+func flatten(p Predicate) []Predicate {
+	and, ok := p.(*andPredicate)
+	if !ok {
+		return []Predicate{p}
+	}
+	var preds []Predicate
+	for _, pred := range and.preds {
+		preds = append(preds, flatten(pred)...)
+	}
+	return preds
+}
+
+type rangePredicate struct {
+	n_bits int
+}
+
+func (p *rangePredicate) AuxRows() int {
+	// a single bit is checked directly on the secret row
+	if p.n_bits == 1 {
+		return 0
+	}
+	return p.n_bits
+}
+
+func (p *rangePredicate) ExtraRows() int {
+	return 0
+}
+
+func (p *rangePredicate) MaxValue() int {
+	if p.n_bits <= 0 || p.n_bits >= 62 {
+		return 0
+	}
+	return 1<<p.n_bits - 1
+}
+
+func (p *rangePredicate) Validate(n_secret, l, max_value, q int) error {
+	if p.n_bits <= 0 {
+		return fmt.Errorf("n_bits cannot be less than 1")
+	}
+
+	// 2^n_bits-1 has to be representable in the field
+	if p.n_bits >= 62 || 1<<p.n_bits > q {
+		return fmt.Errorf("n_bits is too large for modulus q")
+	}
+	return nil
+}
+
+func (p *rangePredicate) Witness(secrets []int, l int) ([][]int, [][]int) {
+	aux := make([][]int, len(secrets))
+	for i, secret := range secrets {
+		aux[i] = make([]int, p.AuxRows())
+		for b := 0; b < p.AuxRows(); b++ {
+			aux[i][b] = (secret >> b) & 1
+		}
+	}
+	return aux, nil
+}
+
+func (p *rangePredicate) Quadratic() []Quadratic {
+	if p.n_bits == 1 {
+		secret := Row{Kind: RowSecret}
+		return []Quadratic{{A: secret, B: secret, C: secret}}
+	}
+
+	constraints := make([]Quadratic, p.n_bits)
+	for b := 0; b < p.n_bits; b++ {
+		bit := Row{Kind: RowAux, Index: b}
+		constraints[b] = Quadratic{A: bit, B: bit, C: bit}
+	}
+	return constraints
+}
+
+func (p *rangePredicate) Linear() []Linear {
+	if p.n_bits == 1 {
+		return nil
+	}
+
+	//secret has to equal the sum of its bits
+	terms := []Term{{Row: Row{Kind: RowSecret}, Coeff: 1}}
+	for b := 0; b < p.n_bits; b++ {
+		terms = append(terms, Term{Row: Row{Kind: RowAux, Index: b}, Coeff: -(1 << b)})
+	}
+	return []Linear{{Terms: terms}}
+}
+
+func (p *rangePredicate) Sums() []Sum {
+	return nil
+}
+
+func (p *rangePredicate) String() string {
+	if p.n_bits == 1 {
+		return "bit"
+	}
+	return fmt.Sprintf("range:%d", p.n_bits)
+}
+
+type sumPredicate struct {
+	k       int
+	exact   bool
+	n_slack int
+}
+
+func (p *sumPredicate) AuxRows() int {
+	return 0
+}
+
+func (p *sumPredicate) ExtraRows() int {
+	return p.n_slack
+}
+
+func (p *sumPredicate) MaxValue() int {
+	return 0
+}
+
+func (p *sumPredicate) Validate(n_secret, l, max_value, q int) error {
+	if p.k < 0 || p.k >= q {
+		return fmt.Errorf("sum bound has to lie in [0, q)")
+	}
+
+	if max_value <= 0 {
+		return fmt.Errorf("sum of secrets can only be bounded together with a range predicate")
+	}
+
+	// the sum of secrets and slack must not wrap around q, otherwise a sum larger than k could pass
+	if float64(n_secret)*float64(max_value)+float64(l)*float64(int(1)<<p.n_slack-1) >= float64(q) {
+		return fmt.Errorf("q is too small to bound the sum of %d secrets", n_secret)
+	}
+	return nil
+}
+
+func (p *sumPredicate) Witness(secrets []int, l int) ([][]int, [][]int) {
+	aux := make([][]int, len(secrets))
+	for i := range aux {
+		aux[i] = []int{}
+	}
+
+	//the slack k-sum is stored bitwise in the first entry of the extra rows
+	extra := make([][]int, p.n_slack)
+	slack := p.k
+	for _, secret := range secrets {
+		slack -= secret
+	}
+	for b := 0; b < p.n_slack; b++ {
+		extra[b] = make([]int, l)
+		extra[b][0] = (slack >> b) & 1
+	}
+	return aux, extra
+}
+
+func (p *sumPredicate) Quadratic() []Quadratic {
+	//slack rows have to be vectors of 0/1
+	constraints := make([]Quadratic, p.n_slack)
+	for b := 0; b < p.n_slack; b++ {
+		bit := Row{Kind: RowExtra, Index: b}
+		constraints[b] = Quadratic{A: bit, B: bit, C: bit}
+	}
+	return constraints
+}
+
+func (p *sumPredicate) Linear() []Linear {
+	return nil
+}
+
+func (p *sumPredicate) Sums() []Sum {
+	terms := []Term{{Row: Row{Kind: RowSecret}, Coeff: 1}}
+	for b := 0; b < p.n_slack; b++ {
+		terms = append(terms, Term{Row: Row{Kind: RowExtra, Index: b}, Coeff: 1 << b})
+	}
+	return []Sum{{Terms: terms, Value: p.k}}
+}
+
+func (p *sumPredicate) String() string {
+	if p.exact {
+		return fmt.Sprintf("sum:%d", p.k)
+	}
+	return fmt.Sprintf("atmost:%d", p.k)
+}
+
+type normPredicate struct {
+	n_bits  int
+	bound   int
+	n_slack int
+}
+
+func (p *normPredicate) AuxRows() int {
+	// the bits of a secret followed by its square
+	return p.n_bits + 1
+}
+
+func (p *normPredicate) ExtraRows() int {
+	return p.n_slack
+}
+
+func (p *normPredicate) MaxValue() int {
+	// signed secrets cannot bound a sum of secrets
+	return 0
+}
+
+func (p *normPredicate) Validate(n_secret, l, max_value, q int) error {
+	if p.n_bits <= 0 {
+		return fmt.Errorf("n_bits cannot be less than 1")
+	}
+
+	if p.bound < 0 || p.bound >= q {
+		return fmt.Errorf("norm bound has to lie in [0, q)")
+	}
+
+	// the sum of squares and slack must not wrap around q, otherwise a larger norm could pass
+	max_square := math.Ldexp(1, 2*(p.n_bits-1))
+	if p.n_bits >= 32 || float64(n_secret)*max_square+float64(l)*float64(int(1)<<p.n_slack-1) >= float64(q) {
+		return fmt.Errorf("q is too small to bound the norm of %d secrets of %d bits", n_secret, p.n_bits)
+	}
+	return nil
+}
+
+func (p *normPredicate) Witness(secrets []int, l int) ([][]int, [][]int) {
+	aux := make([][]int, len(secrets))
+	slack := p.bound
+	for i, secret := range secrets {
+		aux[i] = make([]int, p.AuxRows())
+		for b := 0; b < p.n_bits; b++ {
+			aux[i][b] = (secret >> b) & 1
+		}
+		aux[i][p.n_bits] = secret * secret
+		slack -= secret * secret
+	}
+
+	//the slack bound-norm is stored bitwise in the first entry of the extra rows
+	extra := make([][]int, p.n_slack)
+	for b := 0; b < p.n_slack; b++ {
+		extra[b] = make([]int, l)
+		extra[b][0] = (slack >> b) & 1
+	}
+	return aux, extra
+}
+
+func (p *normPredicate) Quadratic() []Quadratic {
+	constraints := make([]Quadratic, 0, p.n_bits+1+p.n_slack)
+	for b := 0; b < p.n_bits; b++ {
+		bit := Row{Kind: RowAux, Index: b}
+		constraints = append(constraints, Quadratic{A: bit, B: bit, C: bit})
+	}
+
+	secret := Row{Kind: RowSecret}
+	constraints = append(constraints, Quadratic{A: secret, B: secret, C: Row{Kind: RowAux, Index: p.n_bits}})
+
+	for b := 0; b < p.n_slack; b++ {
+		bit := Row{Kind: RowExtra, Index: b}
+		constraints = append(constraints, Quadratic{A: bit, B: bit, C: bit})
+	}
+	return constraints
+}
+
+func (p *normPredicate) Linear() []Linear {
+	//secret has to equal its two's complement bits, the top bit weighs -2^(n_bits-1)
+	terms := []Term{{Row: Row{Kind: RowSecret}, Coeff: 1}}
+	for b := 0; b < p.n_bits-1; b++ {
+		terms = append(terms, Term{Row: Row{Kind: RowAux, Index: b}, Coeff: -(1 << b)})
+	}
+	terms = append(terms, Term{Row: Row{Kind: RowAux, Index: p.n_bits - 1}, Coeff: 1 << (p.n_bits - 1)})
+	return []Linear{{Terms: terms}}
+}
+
+func (p *normPredicate) Sums() []Sum {
+	terms := []Term{{Row: Row{Kind: RowAux, Index: p.n_bits}, Coeff: 1}}
+	for b := 0; b < p.n_slack; b++ {
+		terms = append(terms, Term{Row: Row{Kind: RowExtra, Index: b}, Coeff: 1 << b})
+	}
+	return []Sum{{Terms: terms, Value: p.bound}}
+}
+
+func (p *normPredicate) String() string {
+	return fmt.Sprintf("norm:%d:%d", p.n_bits, p.bound)
+}
+
+type productPredicate struct {
+	a int
+	b int
+	c int
+}
+
+func (p *productPredicate) AuxRows() int {
+	return 0
+}
+
+func (p *productPredicate) ExtraRows() int {
+	// copies of the factors and of the product
+	return 3
+}
+
+func (p *productPredicate) MaxValue() int {
+	return 0
+}
+
+func (p *productPredicate) Validate(n_secret, l, max_value, q int) error {
+	for _, index := range []int{p.a, p.b, p.c} {
+		if index < 0 || index >= n_secret {
+			return fmt.Errorf("secret %d of product out of range", index)
+		}
+	}
+
+	if p.c == p.a || p.c == p.b {
+		return fmt.Errorf("product cannot be stored in one of its factors")
+	}
+	return nil
+}
+
+func (p *productPredicate) Witness(secrets []int, l int) ([][]int, [][]int) {
+	aux := make([][]int, len(secrets))
+	for i := range aux {
+		aux[i] = []int{}
+	}
+
+	extra := make([][]int, 3)
+	for i, index := range []int{p.a, p.b, p.c} {
+		extra[i] = make([]int, l)
+		extra[i][0] = secrets[index]
+	}
+	return aux, extra
+}
+
+func (p *productPredicate) Quadratic() []Quadratic {
+	return []Quadratic{{A: Row{Kind: RowExtra, Index: 0}, B: Row{Kind: RowExtra, Index: 1}, C: Row{Kind: RowExtra, Index: 2}}}
+}
+
+func (p *productPredicate) Linear() []Linear {
+	return nil
+}
+
+func (p *productPredicate) Sums() []Sum {
+	//the first entry of each extra row has to equal its secret
+	constraints := make([]Sum, 3)
+	for i, index := range []int{p.a, p.b, p.c} {
+		constraints[i] = Sum{Entries: []EntryTerm{
+			{Row: Row{Kind: RowExtra, Index: i}, Index: 0, Coeff: 1},
+			{Row: Row{Kind: RowSecret}, Index: index, Coeff: -1},
+		}}
+	}
+	return constraints
+}
+
+func (p *productPredicate) String() string {
+	return fmt.Sprintf("product:%d:%d:%d", p.a, p.b, p.c)
+}
+
+// Products returns the secrets a, b and c of every product predicate of pred, c = a*b
+func Products(pred Predicate) [][3]int {
+	switch p := pred.(type) {
+	case *andPredicate:
+		var products [][3]int
+		for _, sub := range p.preds {
+			products = append(products, Products(sub)...)
+		}
+		return products
+	case *productPredicate:
+		return [][3]int{{p.a, p.b, p.c}}
+	}
+	return nil
+}
+
+// FillProducts returns a copy of secrets in which the secret of every product predicate of pred is set to the
+// product of its factors, secrets are extended up to the largest product, e.g. to append the product bits of
+// pairs of attributes to the attributes of a client
+func FillProducts(pred Predicate, secrets []int) []int {
+	result := append([]int{}, secrets...)
+	for _, p := range Products(pred) {
+		a, b, c := p[0], p[1], p[2]
+		if a < 0 || b < 0 || c < 0 || a >= len(result) || b >= len(result) {
+			continue
+		}
+		for len(result) <= c {
+			result = append(result, 0)
+		}
+		result[c] = result[a] * result[b]
+	}
+	return result
+}
+
+type andPredicate struct {
+	preds []Predicate
+}
+
+func (p *andPredicate) AuxRows() int {
+	n := 0
+	for _, pred := range p.preds {
+		n += pred.AuxRows()
+	}
+	return n
+}
+
+func (p *andPredicate) ExtraRows() int {
+	n := 0
+	for _, pred := range p.preds {
+		n += pred.ExtraRows()
+	}
+	return n
+}
+
+func (p *andPredicate) MaxValue() int {
+	max_value := 0
+	for _, pred := range p.preds {
+		v := pred.MaxValue()
+		if v > 0 && (max_value == 0 || v < max_value) {
+			max_value = v
+		}
+	}
+	return max_value
+}
+
+func (p *andPredicate) Validate(n_secret, l, max_value, q int) error {
+	if len(p.preds) == 0 {
+		return fmt.Errorf("predicate cannot be empty")
+	}
+
+	if own := p.MaxValue(); own > 0 && (max_value == 0 || own < max_value) {
+		max_value = own
+	}
+	for _, pred := range p.preds {
+		err := pred.Validate(n_secret, l, max_value, q)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *andPredicate) Witness(secrets []int, l int) ([][]int, [][]int) {
+	aux := make([][]int, len(secrets))
+	for i := range aux {
+		aux[i] = []int{}
+	}
+
+	var extra [][]int
+	for _, pred := range p.preds {
+		a, e := pred.Witness(secrets, l)
+		for i := range aux {
+			aux[i] = append(aux[i], a[i]...)
+		}
+		extra = append(extra, e...)
+	}
+	return aux, extra
+}
+
+// offsets returns the index of the first auxiliary and extra row of each predicate
+func (p *andPredicate) offsets() ([]int, []int) {
+	aux_offsets := make([]int, len(p.preds))
+	extra_offsets := make([]int, len(p.preds))
+	aux, extra := 0, 0
+	for i, pred := range p.preds {
+		aux_offsets[i] = aux
+		extra_offsets[i] = extra
+		aux += pred.AuxRows()
+		extra += pred.ExtraRows()
+	}
+	return aux_offsets, extra_offsets
+}
+
+func shift(row Row, aux int, extra int) Row {
+	switch row.Kind {
+	case RowAux:
+		return Row{Kind: RowAux, Index: row.Index + aux}
+	case RowExtra:
+		return Row{Kind: RowExtra, Index: row.Index + extra}
+	}
+	return row
+}
+
+func shiftEntries(entries []EntryTerm, aux int, extra int) []EntryTerm {
+	result := make([]EntryTerm, len(entries))
+	for i, entry := range entries {
+		result[i] = EntryTerm{Row: shift(entry.Row, aux, extra), Index: entry.Index, Coeff: entry.Coeff}
+	}
+	return result
+}
+
+func shiftTerms(terms []Term, aux int, extra int) []Term {
+	result := make([]Term, len(terms))
+	for i, term := range terms {
+		result[i] = Term{Row: shift(term.Row, aux, extra), Coeff: term.Coeff}
+	}
+	return result
+}
+
+func (p *andPredicate) Quadratic() []Quadratic {
+	aux_offsets, extra_offsets := p.offsets()
+	var constraints []Quadratic
+	for i, pred := range p.preds {
+		for _, c := range pred.Quadratic() {
+			constraints = append(constraints, Quadratic{
+				A: shift(c.A, aux_offsets[i], extra_offsets[i]),
+				B: shift(c.B, aux_offsets[i], extra_offsets[i]),
+				C: shift(c.C, aux_offsets[i], extra_offsets[i]),
+			})
+		}
+	}
+	return constraints
+}
+
+func (p *andPredicate) Linear() []Linear {
+	aux_offsets, extra_offsets := p.offsets()
+	var constraints []Linear
+	for i, pred := range p.preds {
+		for _, c := range pred.Linear() {
+			constraints = append(constraints, Linear{Terms: shiftTerms(c.Terms, aux_offsets[i], extra_offsets[i])})
+		}
+	}
+	return constraints
+}
+
+func (p *andPredicate) Sums() []Sum {
+	aux_offsets, extra_offsets := p.offsets()
+	var constraints []Sum
+	for i, pred := range p.preds {
+		for _, c := range pred.Sums() {
+			constraints = append(constraints, Sum{Terms: shiftTerms(c.Terms, aux_offsets[i], extra_offsets[i]), Entries: shiftEntries(c.Entries, aux_offsets[i], extra_offsets[i]), Value: c.Value})
+		}
+	}
+	return constraints
+}
+
+func (p *andPredicate) String() string {
+	specs := make([]string, len(p.preds))
+	for i, pred := range p.preds {
+		specs[i] = pred.String()
+	}
+	return strings.Join(specs, "+")
+}
@@ -0,0 +1,92 @@
+package ligero
+
+import (
+	"testing"
+)
+
+func TestParsePredicate(t *testing.T) {
+	tests := []struct {
+		spec     string
+		expected string
+		aux      int
+		extra    int
+		wantErr  bool
+	}{
+		{"bit", "bit", 0, 0, false},
+		{"range:8", "range:8", 8, 0, false},
+		{"onehot", "bit+sum:1", 0, 0, false},
+		{"range:4+atmost:20", "range:4+atmost:20", 4, 5, false},
+		{"bit + sum:3", "bit+sum:3", 0, 0, false},
+		{"range", "", 0, 0, true},
+		{"range:x", "", 0, 0, true},
+		{"norm:8:1000", "norm:8:1000", 9, 10, false},
+		{"norm:3", "", 0, 0, true},
+		{"norm:3:x", "", 0, 0, true},
+		{"bit+product:0:1:2", "bit+product:0:1:2", 0, 3, false},
+		{"product:0:1", "", 0, 0, true},
+	}
+
+	for _, test := range tests {
+		pred, err := ParsePredicate(test.spec)
+		if (err != nil) != test.wantErr {
+			t.Errorf("%s: expected error: %v, but got error: %v", test.spec, test.wantErr, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+
+		if pred.String() != test.expected || pred.AuxRows() != test.aux || pred.ExtraRows() != test.extra {
+			t.Errorf("%s: expected %s with %d/%d rows, but got %s with %d/%d rows", test.spec, test.expected, test.aux, test.extra, pred, pred.AuxRows(), pred.ExtraRows())
+		}
+	}
+}
+
+func TestAndPredicateRows(t *testing.T) {
+	pred := And(RangePredicate(2), RangePredicate(3), SumAtMostPredicate(2))
+
+	// the bit rows of the second range predicate follow the ones of the first
+	linear := pred.Linear()
+	if len(linear) != 2 || linear[1].Terms[1].Row != (Row{Kind: RowAux, Index: 2}) {
+		t.Fatalf("unexpected linear constraints %v", linear)
+	}
+
+	aux, extra := pred.Witness([]int{3, 1}, 2)
+	if len(aux[0]) != 5 || aux[0][2] != 1 || aux[0][3] != 1 || aux[0][4] != 0 {
+		t.Fatalf("unexpected auxiliary values %v", aux)
+	}
+
+	// the slack of the sum bound is decomposed into the two extra rows
+	if len(extra) != 2 || len(extra[0]) != 2 || pred.MaxValue() != 3 {
+		t.Fatalf("unexpected extra rows %v", extra)
+	}
+}
+
+func TestNorms(t *testing.T) {
+	tests := []struct {
+		spec      string
+		n_secret  int
+		max_abs   float64
+		l1, l2    float64
+		unbounded bool
+	}{
+		{"bit", 4, 1, 4, 2, false},
+		{"range:4", 4, 15, 60, 30, false},
+		{"onehot", 4, 1, 1, 1, false},
+		{"range:4+atmost:20", 4, 15, 20, 20, false},
+		{"norm:8:100", 4, 10, 20, 10, false},
+		{"onehot+product:0:1:2", 3, 1, 1, 1, false},
+		{"product:0:1:2", 3, 0, 0, 0, true},
+	}
+
+	for _, test := range tests {
+		pred, err := ParsePredicate(test.spec)
+		if err != nil {
+			t.Fatalf("%s: %v", test.spec, err)
+		}
+		max_abs, l1, l2, ok := Norms(pred, test.n_secret)
+		if ok == test.unbounded || max_abs != test.max_abs || l1 != test.l1 || l2 != test.l2 {
+			t.Errorf("%s: expected %v %v %v, but got %v %v %v (bounded %v)", test.spec, test.max_abs, test.l1, test.l2, max_abs, l1, l2, ok)
+		}
+	}
+}
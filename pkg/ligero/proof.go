@@ -2,37 +2,35 @@ package ligero
 
 import (
 	"fmt"
-	"log"
-	"strings"
-	"sync"
 
 	merkletree "github.com/wealdtech/go-merkletree"
 )
 
+// GlobConstants holds the lagrange basis polynomials of the share points of all columns at the l secret points,
+// GlobConstantsCodeTest the ones of the share points of the first Degree() columns, or 2*Degree()-1 columns for
+// the quadratic test, at the other columns.
+// Both are keyed by evaluation point, computed by NewLigeroZK for the lagrange encoding and only read afterwards.
 type GlobConstants struct {
-	flag_num     []bool
-	values_num   [][]int
-	values_denom []int
-	flag_denom   bool
+	basis map[int][]int
 }
 
 type GlobConstantsCodeTest struct {
-	flag_num     []bool
-	values_num   [][]int
-	values_denom []int
-	flag_denom   bool
+	basis map[int][]int
 }
 
 type Proof struct {
 	MerkleRoot   []byte         `json:"MerkleRoot"`
 	ColumnTest   []OpenedColumn `json:"ColumnTest"`
+	Multiproof   [][]byte       `json:"Multiproof,omitempty"`
 	CodeTest     []int          `json:"CodeTest"`
 	QuadraTest   []int          `json:"QuadraTest"`
 	LinearTest   []int          `json:"LinearTest"`
+	SumTest      []int          `json:"SumTest,omitempty"`
 	Shares       Shares         `json:"Shares"`
 	Seeds        []int          `json:"Seeds"`
 	FST_root     []byte         `json:"FST_root"`
 	FST_authpath [][]byte       `json:"FST_authpath"`
+	Leaf_version int            `json:"Leaf_version,omitempty"`
 }
 
 type Shares struct {
@@ -49,108 +47,241 @@ type OpenedColumn struct {
 	Code_mask    int      `json:"Code_mask"`
 	Linear_mask  int      `json:"Linear_mask"`
 	Quadra_mask  int      `json:"Quadra_mask"`
+	Sum_mask     int      `json:"Sum_mask,omitempty"`
+	// code, linear, quadratic and sum masks of every repetition after the first
+	Repeated_masks []int `json:"Repeated_masks,omitempty"`
 }
 
-func newProof(root []byte, column_check []OpenedColumn, q_code []int, q_quadra []int, q_linear []int, shares Shares, seeds []int, fst_root []byte, fst_authpath [][]byte) *Proof {
+// repetition returns the opened column with the masks of the repetition rep
+func (col OpenedColumn) repetition(rep int) (OpenedColumn, error) {
+	if rep == 0 {
+		return col, nil
+	}
+	if len(col.Repeated_masks) < 4*rep {
+		return col, fmt.Errorf("opened column %d has no masks for repetition %d", col.Index, rep)
+	}
+	m := col.Repeated_masks[4*(rep-1) : 4*rep]
+	col.Code_mask, col.Linear_mask, col.Quadra_mask, col.Sum_mask = m[0], m[1], m[2], m[3]
+	return col, nil
+}
+
+// leaf_masks returns the masks of all repetitions at the column, the Merkle leaf of the column commits to them
+func (col OpenedColumn) leaf_masks() []int {
+	return append([]int{col.Code_mask, col.Linear_mask, col.Quadra_mask, col.Sum_mask}, col.Repeated_masks...)
+}
+
+func newProof(root []byte, column_check []OpenedColumn, multiproof [][]byte, q_code []int, q_quadra []int, q_linear []int, q_sum []int, shares Shares, seeds []int, fst_root []byte, fst_authpath [][]byte, leaf_version int) *Proof {
 	return &Proof{
 		MerkleRoot:   root,
 		ColumnTest:   column_check,
+		Multiproof:   multiproof,
 		CodeTest:     q_code,
 		QuadraTest:   q_quadra,
 		LinearTest:   q_linear,
+		SumTest:      q_sum,
 		Shares:       shares,
 		Seeds:        seeds,
 		FST_root:     fst_root,
 		FST_authpath: fst_authpath,
+		Leaf_version: leaf_version,
 	}
 }
 
-func (zk *LigeroZK) VerifyProof(proof Proof) (bool, error) {
+// VerifyProof verifies a proof against the context the client claims, e.g. the experiment and client ID of its request.
+// The error of a proof that fails verification, malformed or not, wraps ErrInvalidProof.
+func (zk *LigeroZK) VerifyProof(ctx Context, proof Proof) (bool, error) {
+	tests, err := zk.verify_columns(ctx, proof)
+	if err != nil {
+		return false, invalid_proof(err)
+	}
+
+	for rep := range tests.quadra {
+		slotTest, err := zk.verify_slots(tests.quadra[rep], tests.linear[rep], tests.sum[rep], tests.expected[rep])
+		if !slotTest {
+			return false, invalid_proof(err)
+		}
+	}
+
+	return true, nil
+}
+
+// slot_tests are the tests of a proof whose values at the secret slots are left to check, one entry per repetition.
+// The checks are linear in the tests, VerifyBatch checks random combinations of the tests of many proofs.
+type slot_tests struct {
+	quadra   [][]int
+	linear   [][]int
+	sum      [][]int
+	expected []int
+}
+
+// verify_columns runs every check of a proof but the ones at the secret slots, which are returned
+func (zk *LigeroZK) verify_columns(ctx Context, proof Proof) (*slot_tests, error) {
+	if len(proof.Seeds) != zk.n_shares+1 {
+		return nil, fmt.Errorf("number of seeds is wrong")
+	}
+
+	if len(zk.sums) > 0 && len(proof.SumTest) != zk.repetitions*zk.n_encode {
+		return nil, fmt.Errorf("sum test is missing")
+	}
+
+	if len(proof.CodeTest) != zk.repetitions*zk.npss.Degree() || len(proof.QuadraTest) != zk.repetitions*zk.n_encode || len(proof.LinearTest) != zk.repetitions*zk.n_encode {
+		return nil, fmt.Errorf("number of repetitions of the tests is wrong")
+	}
+
 	//verify fst auth path
-	fstAuthPathTest, err := zk.verify_fst_authpath(proof.Shares, proof.Seeds, proof.FST_authpath, proof.FST_root)
+	fstAuthPathTest, err := zk.verify_fst_authpath(proof.Shares, proof.Seeds, proof.FST_authpath, proof.FST_root, proof.Leaf_version)
 	if !fstAuthPathTest {
-		return false, err
+		return nil, err
 	}
 
-	h1 := zk.generate_hash([][]byte{proof.MerkleRoot})
-	h2 := zk.generate_hash([][]byte{h1, proof.FST_root, ConvertToByteArray(proof.CodeTest), ConvertToByteArray(proof.QuadraTest), ConvertToByteArray(proof.LinearTest)})
+	ts := zk.NewTranscript(ctx)
+	ts.AbsorbBytes("merkle_root", proof.MerkleRoot)
+	h1 := ts.Challenge("test_coefficients")
+	h2 := zk.column_challenge(ts, proof.FST_root, proof.CodeTest, proof.QuadraTest, proof.LinearTest, proof.SumTest)
 	r4 := RandVector(h2, zk.n_open_col, zk.n_encode)
 
 	size := len(proof.ColumnTest)
 	if len(r4) == size {
 		for i := 0; i < size; i++ {
 			if r4[i] != proof.ColumnTest[i].Index {
-				return false, fmt.Errorf("opened column's index is wrong ")
+				return nil, fmt.Errorf("opened column's index is wrong ")
 			}
 		}
 	} else {
-		return false, fmt.Errorf("opened column's index is wrong ")
+		return nil, fmt.Errorf("opened column's index is wrong ")
 	}
 
 	//verify opened columns are correct
-	openenColumnTest, err := zk.verify_opened_columns(proof.ColumnTest, proof.MerkleRoot)
+	openenColumnTest, err := zk.verify_opened_columns(proof.ColumnTest, proof.Multiproof, proof.MerkleRoot, proof.Leaf_version)
 	if !openenColumnTest {
-		return false, err
+		return nil, err
 	}
 
-	len1 := zk.m * (1 + zk.n_shares)
-	len2 := zk.m
-	len3 := zk.m
+	//verify the shares against the opened columns, the same for every repetition
+	row_test := zk.check_shares_with_opened_column(proof.Shares, proof.Seeds, proof.ColumnTest)
+	if !row_test {
+		return nil, fmt.Errorf("linear test failed: failed to evaluate shares with the opened columns")
+	}
 
-	random_vector := RandVector(h1, len1+len2+len3, zk.q)
+	len1, len2, len3, len4 := zk.challenge_lengths()
+	n_challenges := len1 + len2 + len3 + len4
 
-	//verify code test proof
-	r1 := random_vector[:len1]
-	codeTest, err := zk.verify_code_proof(proof.CodeTest, r1, proof.ColumnTest)
-	if !codeTest {
-		return false, err
+	random_vector := RandVector(h1, zk.repetitions*n_challenges, zk.q)
+
+	// every repetition has its own challenges, tests and masks
+	tests := &slot_tests{
+		quadra:   make([][]int, zk.repetitions),
+		linear:   make([][]int, zk.repetitions),
+		sum:      make([][]int, zk.repetitions),
+		expected: make([]int, zk.repetitions),
 	}
+	code_len := zk.npss.Degree()
+	open_cols := make([]OpenedColumn, len(proof.ColumnTest))
+	for rep := 0; rep < zk.repetitions; rep++ {
+		random := random_vector[rep*n_challenges : (rep+1)*n_challenges]
+		for i, col := range proof.ColumnTest {
+			open_cols[i], err = col.repetition(rep)
+			if err != nil {
+				return nil, err
+			}
+		}
 
-	//verify quadratic test proof
-	r2 := random_vector[len1 : len1+len2]
-	quadraticTest, err := zk.verify_quadratic_constraints(proof.QuadraTest, r2, proof.ColumnTest)
-	if !quadraticTest {
-		return false, err
+		//verify code test proof
+		r1 := random[:len1]
+		codeTest, err := zk.verify_code_proof(proof.CodeTest[rep*code_len:(rep+1)*code_len], r1, open_cols)
+		if !codeTest {
+			return nil, err
+		}
+
+		//verify quadratic test proof at the opened columns
+		r2 := random[len1 : len1+len2]
+		tests.quadra[rep] = proof.QuadraTest[rep*zk.n_encode : (rep+1)*zk.n_encode]
+		if !zk.check_quadra_with_opened_column(tests.quadra[rep], r2, open_cols) {
+			return nil, fmt.Errorf("quadratic test failed: failed to evaluate the opened column")
+		}
+
+		//verify linear test proof at the opened columns
+		r3 := random[len1+len2 : len1+len2+len3]
+		tests.linear[rep] = proof.LinearTest[rep*zk.n_encode : (rep+1)*zk.n_encode]
+		if !zk.check_linear_with_opened_column(tests.linear[rep], r3, open_cols) {
+			return nil, fmt.Errorf("linear test failed: failed to evaluate the opened column")
+		}
+
+		//verify sum test proof at the opened columns
+		if len(zk.sums) > 0 {
+			r5 := random[len1+len2+len3:]
+			tests.sum[rep] = proof.SumTest[rep*zk.n_encode : (rep+1)*zk.n_encode]
+			if !zk.check_sum_with_opened_column(tests.sum[rep], r5, open_cols) {
+				return nil, fmt.Errorf("sum test failed: failed to evaluate the opened column")
+			}
+			for i, c := range zk.sums {
+				tests.expected[rep] = zk.field.AddInt(tests.expected[rep], zk.field.MulInt(r5[i], c.value))
+			}
+		}
 	}
 
-	//verify linear test proof
-	r3 := random_vector[len1+len2 : len1+len2+zk.m]
-	linearTest, err := zk.verify_linear_proof(proof.Shares, proof.Seeds, proof.LinearTest, r3, proof.ColumnTest)
-	if !linearTest {
-		return false, err
+	return tests, nil
+}
+
+// verify_slots checks the tests of a repetition at the secret slots: the quadratic and linear tests vanish
+// and the sum test adds up to the expected sum, q_sum is nil without sum constraints
+func (zk *LigeroZK) verify_slots(q_quadra []int, q_linear []int, q_sum []int, expected int) (bool, error) {
+	// the product of two encoded rows has less than 2d-1 coefficients
+	slots, err := zk.secret_slots(q_quadra, 2*zk.npss.Degree()-1)
+	if err != nil {
+		return false, fmt.Errorf("quadratic test failed: failed to evaluat polynomial")
+	}
+	for _, result := range slots {
+		if result != 0 {
+			return false, fmt.Errorf("quadratic test failed: constraints are not satisfied")
+		}
+	}
+
+	slots, err = zk.secret_slots(q_linear, zk.npss.Degree())
+	if err != nil {
+		return false, fmt.Errorf("linear test failed: failed to evaluat polynomial")
+	}
+	for _, result := range slots {
+		if result != 0 {
+			return false, fmt.Errorf(("linear test failed: shares are not generated correctly"))
+		}
+	}
+
+	if q_sum == nil {
+		return true, nil
+	}
+
+	// entry terms multiply rows by polynomials of degree l-1
+	slots, err = zk.secret_slots(q_sum, zk.sum_coeffs())
+	if err != nil {
+		return false, fmt.Errorf("sum test failed: failed to evaluat polynomial")
+	}
+	sum := 0
+	for _, result := range slots {
+		sum = zk.field.AddInt(sum, result)
+	}
+	if sum != expected {
+		return false, fmt.Errorf("sum test failed: sum of secrets does not satisfy the bound")
 	}
 
 	return true, nil
 }
 
-func (zk *LigeroZK) verify_fst_authpath(shares Shares, seeds []int, authpath [][]byte, root []byte) (bool, error) {
+func (zk *LigeroZK) verify_fst_authpath(shares Shares, seeds []int, authpath [][]byte, root []byte, version int) (bool, error) {
 	if len(authpath) == 0 || len(root) == 0 {
 		return false, fmt.Errorf("fst authpaty or root cannot be empty")
 	}
 
-	l2 := len(shares.Values)
-	l3 := len(shares.Index)
-
-	list := make([]string, l2*l3+l3)
-	index := 0
-	for j := 0; j < l2; j++ {
-		for n := 0; n < l3; n++ {
-			list[index] = fmt.Sprintf("%064b", shares.Values[j][n])
-			index++
-		}
-	}
-
-	for m := 0; m < l3; m++ {
-		list[index] = fmt.Sprintf("%064b", seeds[shares.Index[m]])
-		index++
+	leaf, err := fst_leaf(shares, seeds, version)
+	if err != nil {
+		return false, err
 	}
-	concat := strings.Join(list, "")
 
 	var proof merkletree.Proof
 	proof.Hashes = authpath
 	proof.Index = uint64(shares.PartyIndex)
 
-	verified, err := merkletree.VerifyProof([]byte(concat), &proof, root)
+	verified, err := merkletree.VerifyProof(leaf, &proof, root)
 	if err != nil {
 		return false, err
 	}
@@ -163,25 +294,37 @@ func (zk *LigeroZK) verify_fst_authpath(shares Shares, seeds []int, authpath [][
 
 }
 
-func (zk *LigeroZK) verify_opened_columns(open_cols []OpenedColumn, root []byte) (bool, error) {
+// verify_opened_columns checks the opened columns against the root with the multi-proof, or with the
+// authentication path of every column for proofs without multi-proof
+func (zk *LigeroZK) verify_opened_columns(open_cols []OpenedColumn, multiproof [][]byte, root []byte, version int) (bool, error) {
 	if len(open_cols) == 0 || len(root) == 0 {
 		return false, fmt.Errorf("opened columns or root cannot be empty")
 	}
 
-	for _, col := range open_cols {
-		list := make([]int, len(col.List)+1)
-		list = append(list, col.List...)
-		list = append(list, col.Merkle_nonce)
-		concatenated, err := ConvertColumnToString(list)
-
+	leaves := make([][]byte, len(open_cols))
+	indices := make([]int, len(open_cols))
+	authpaths := false
+	for i, col := range open_cols {
+		leaf, err := column_leaf(col.List, col.leaf_masks(), col.Merkle_nonce, version)
 		if err != nil {
 			return false, err
 		}
+		leaves[i], indices[i] = leaf, col.Index
+		authpaths = authpaths || len(col.Authpath) > 0
+	}
 
+	if !authpaths {
+		return verify_multiproof(zk.n_encode, indices, leaves, multiproof, root)
+	}
+	if len(multiproof) > 0 {
+		return false, fmt.Errorf("opened columns have both a multi-proof and authentication paths")
+	}
+
+	for i, col := range open_cols {
 		var proof merkletree.Proof
 		proof.Hashes = col.Authpath
 		proof.Index = uint64(col.Index)
-		verified, err := merkletree.VerifyProof([]byte(concatenated), &proof, root)
+		verified, err := merkletree.VerifyProof(leaves[i], &proof, root)
 		if err != nil {
 			return false, err
 		}
@@ -197,24 +340,40 @@ func (zk *LigeroZK) verify_opened_columns(open_cols []OpenedColumn, root []byte)
 }
 
 func (zk *LigeroZK) verify_code_proof(q_code []int, randomness []int, open_cols []OpenedColumn) (bool, error) {
+	// with the NTT the code test is interpolated once and evaluated at every opened column
+	var coeffs []int
+	if zk.npss.NTT() {
+		var err error
+		coeffs, err = zk.npss.Interpolate(q_code, zk.code_stride())
+		if err != nil {
+			return false, fmt.Errorf("code test failed: %v", err)
+		}
+	}
+
 	//generate x coordicates
 	length := len(q_code)
 	x_sample := make([]int, length)
 	for i := 0; i < length; i++ {
-		x_sample[i] = i + 1
+		x_sample[i] = zk.npss.SharePoint(i)
 	}
 	for _, col := range open_cols {
-		x := col.Index + 1
-		result1, err := zk.Interpolate_at_Point_Code_Test(x_sample, q_code, x, zk.q)
-		if err != nil {
-			return false, fmt.Errorf("code test failed: x_samples and y_samples length are different")
+		x := zk.npss.SharePoint(col.Index)
+		var result1 int
+		if coeffs != nil {
+			result1 = zk.npss.Evaluate(coeffs, x)
+		} else {
+			var err error
+			result1, err = zk.Interpolate_at_Point_Code_Test(x_sample, q_code, x, zk.q)
+			if err != nil {
+				return false, fmt.Errorf("code test failed: x_samples and y_samples length are different")
+			}
 		}
 
-		result2, err := MulList(randomness, col.List, zk.q)
+		result2, err := mul_list(zk.field, randomness, col.List)
 		if err != nil {
 			return false, fmt.Errorf("code test failed: inputs length are different so that multiplication cannot be done")
 		}
-		result2 = mod(result2+col.Code_mask, zk.q)
+		result2 = zk.field.AddInt(result2, col.Code_mask)
 
 		if result1 != result2 {
 			return false, fmt.Errorf("code test failed: failed to evaluate the opened column")
@@ -223,140 +382,109 @@ func (zk *LigeroZK) verify_code_proof(q_code []int, randomness []int, open_cols
 	return true, nil
 }
 
-func (zk *LigeroZK) verify_quadratic_constraints(q_quadra []int, randomness []int, open_cols []OpenedColumn) (bool, error) {
-	//generate x coordicates
-	length := len(q_quadra)
-	x_sample := make([]int, length)
-	for i := 0; i < length; i++ {
-		x_sample[i] = i + 1
-	}
-	for j := 0; j < zk.l; j++ {
-		x := mod(-j-1, zk.q)
-		result, err := zk.Interpolate_at_Point(x_sample, q_quadra, x, zk.q)
+// secret_slots returns the values at the l secret slots of the polynomial through the values of a test at
+// all columns. With the NTT the polynomial is interpolated once and has to have less than n_coeffs coefficients.
+func (zk *LigeroZK) secret_slots(values []int, n_coeffs int) ([]int, error) {
+	if zk.npss.NTT() {
+		coeffs, err := zk.npss.Interpolate(values, 1)
 		if err != nil {
-			return false, fmt.Errorf("quadratic test failed: failed to evaluat polynomial")
+			return nil, err
 		}
-		if result != 0 {
-			return false, fmt.Errorf("quadratic test failed: constraints are not satisfied")
+		for i := n_coeffs; i < len(coeffs); i++ {
+			if coeffs[i] != 0 {
+				return nil, fmt.Errorf("polynomial has degree above %d", n_coeffs-1)
+			}
 		}
+		return zk.npss.EvaluateSecrets(coeffs)
 	}
 
-	col_test := zk.check_quadra_with_opened_column(q_quadra, randomness, open_cols)
-
-	if !col_test {
-		return false, fmt.Errorf("quadratic test failed: failed to evaluate the opened column")
-	}
-
-	return true, nil
-}
-
-func (zk *LigeroZK) verify_linear_proof(shares Shares, key []int, q_linear []int, randomness []int, open_cols []OpenedColumn) (bool, error) {
-
-	row_test := zk.check_shares_with_opened_column(shares, key, open_cols)
-	if !row_test {
-		return false, fmt.Errorf("linear test failed: failed to evaluate shares with the opened columns")
+	//generate x coordicates
+	length := len(values)
+	if length != zk.n_encode {
+		return nil, fmt.Errorf("%w: test has %d values for %d columns", ErrDimensionMismatch, length, zk.n_encode)
 	}
-
-	// generate x coordicates
-	length := len(q_linear)
 	x_sample := make([]int, length)
 	for i := 0; i < length; i++ {
-		x_sample[i] = i + 1
+		x_sample[i] = zk.npss.SharePoint(i)
 	}
 
-	for j := 0; j < zk.l; j++ {
-		x := mod(-j-1, zk.q)
-		result, err := zk.Interpolate_at_Point(x_sample, q_linear, x, zk.q)
+	// the polynomial through the first n_coeffs columns has to pass through the values at the other columns
+	table := zk.glob_constants_code_test.basis
+	if n_coeffs != zk.npss.Degree() {
+		table = zk.glob_constants_quadra.basis
+	}
+	for i := n_coeffs; i < length; i++ {
+		y, err := zk.interpolate(table, x_sample[:n_coeffs], values[:n_coeffs], x_sample[i])
 		if err != nil {
-			return false, fmt.Errorf("linear test failed: failed to evaluat polynomial")
+			return nil, err
 		}
-		if result != 0 {
-			return false, fmt.Errorf(("linear test failed: shares are not generated correctly"))
+		if y != values[i] {
+			return nil, fmt.Errorf("polynomial has degree above %d", n_coeffs-1)
 		}
 	}
 
-	col_test := zk.check_linear_with_opened_column(q_linear, randomness, open_cols)
-
-	if !col_test {
-		return false, fmt.Errorf("linear test failed: failed to evaluate the opened column")
+	slots := make([]int, zk.l)
+	for j := range slots {
+		result, err := zk.Interpolate_at_Point(x_sample, values, zk.npss.SecretPoint(j), zk.q)
+		if err != nil {
+			return nil, err
+		}
+		slots[j] = result
 	}
-
-	return true, nil
+	return slots, nil
 }
 
+// check_shares_with_opened_column checks that the share rows of every opened column encode the shares of the
+// server. Only the share rows are evaluated and only at the opened columns, keys of the predicate rows are
+// not known to the verifier.
 func (zk *LigeroZK) check_shares_with_opened_column(shares Shares, key []int, open_cols []OpenedColumn) bool {
-	size := len(shares.Values)
-	claims := make([]Claim, size)
-
-	// Prepare claims for each party concurrently
-	var wg sync.WaitGroup
-	wg.Add(size)
-	for i := 0; i < size; i++ {
-		go func(i int) {
-			defer wg.Done()
-			shList := make([]int, zk.n_shares)
-			for j := 0; j < len(shares.Index); j++ {
-				shList[shares.Index[j]] = shares.Values[i][j]
-			}
-			claims[i] = Claim{Secret: 0, Shares: shList}
-		}(i)
+	if len(shares.Values) != zk.m*zk.l || len(key) != zk.n_shares+1 {
+		return false
 	}
-	wg.Wait()
-
-	// Prepare and encode extended witness concurrently
-	extended_witness, err := zk.prepare_extended_witness(claims)
-	if err != nil {
-		log.Fatal(err)
+	for _, index := range shares.Index {
+		if index < 0 || index >= zk.n_shares {
+			return false
+		}
 	}
 
-	encodedWitnesses, err := zk.encode_extended_witness(extended_witness, key)
-	if err != nil {
-		log.Fatal(err)
+	indices := make([]int, len(open_cols))
+	for i, col := range open_cols {
+		if len(col.List) != zk.n_rows() {
+			return false
+		}
+		indices[i] = col.Index
 	}
-	// Check shares with opened columns concurrently
-	var result bool
-	var mu sync.Mutex
-	var wg2 sync.WaitGroup
-	wg2.Add(len(open_cols))
 
-	for _, col := range open_cols {
-		go func(col OpenedColumn) {
-			defer wg2.Done()
-
-			foundMismatch := false
-			for bl := 0; bl < len(encodedWitnesses); bl = bl + 1 + zk.n_shares {
-				for i := 0; i < len(shares.Index); i++ {
-					rw := bl + shares.Index[i] + 1
-					if encodedWitnesses[rw][col.Index] != col.List[rw] {
-						foundMismatch = true
-						break
-					}
-				}
-				if foundMismatch {
-					break
+	crs := NewCryptoRandSource()
+	row := make([]int, zk.l)
+	for bl := 0; bl < zk.m; bl++ {
+		for i, index := range shares.Index {
+			for j := range row {
+				if len(shares.Values[bl*zk.l+j]) != len(shares.Index) {
+					return false
 				}
+				row[j] = shares.Values[bl*zk.l+j][i]
 			}
-			mu.Lock()
-			if !foundMismatch {
-				result = true
+
+			rw := bl*zk.block_size() + index + 1
+			values, err := zk.npss.SplitAt(row, zk.row_seed(&crs, key, rw), indices)
+			if err != nil {
+				return false
 			}
-			mu.Unlock()
-		}(col)
+			for c, col := range open_cols {
+				if values[c] != col.List[rw] {
+					return false
+				}
+			}
+		}
 	}
-	wg2.Wait()
 
-	return result
+	return true
 }
 
 func (zk *LigeroZK) check_quadra_with_opened_column(test_value []int, randomness []int, open_cols []OpenedColumn) bool {
 	for _, col := range open_cols {
-		result := 0
-		index := 0
-		for i := 0; i < len(col.List); i = i + zk.n_shares + 1 {
-			result += randomness[index] * col.List[i] * (1 - col.List[i])
-			index += 1
-		}
-		result = mod(result+col.Quadra_mask, zk.q)
+		result := zk.field.AddInt(zk.eval_quadratic(col.List, randomness), col.Quadra_mask)
 
 		if test_value[col.Index] != result {
 			return false
@@ -368,19 +496,19 @@ func (zk *LigeroZK) check_quadra_with_opened_column(test_value []int, randomness
 
 func (zk *LigeroZK) check_linear_with_opened_column(test_value []int, randomness []int, open_cols []OpenedColumn) bool {
 	for _, col := range open_cols {
-		result := 0
-		index := 0
+		result := zk.field.AddInt(zk.eval_linear(col.List, randomness), col.Linear_mask)
 
-		for row := 0; row < len(col.List); row = row + zk.n_shares + 1 {
-			temp := col.List[row]
-			for j := 1; j < zk.n_shares+1; j++ {
-				temp = temp - col.List[row+j]
-			}
-			result = result + temp*randomness[index]
-			index += 1
+		if test_value[col.Index] != result {
+			return false
 		}
+	}
 
-		result = mod(result+col.Linear_mask, zk.q)
+	return true
+}
+
+func (zk *LigeroZK) check_sum_with_opened_column(test_value []int, randomness []int, open_cols []OpenedColumn) bool {
+	for _, col := range open_cols {
+		result := zk.field.AddInt(zk.eval_sum(col.List, col.Index, randomness), col.Sum_mask)
 
 		if test_value[col.Index] != result {
 			return false
@@ -390,9 +518,22 @@ func (zk *LigeroZK) check_linear_with_opened_column(test_value []int, randomness
 	return true
 }
 
+// GetSize returns the number of bytes of the proof and of the shares in it, with 8 bytes per field element
 func (zk *LigeroZK) GetSize(proof Proof) (int64, int64) {
-	col_test_size := len(proof.ColumnTest) * (5 + len(proof.ColumnTest[0].List)*8 + len(proof.ColumnTest[0].Authpath)*len(proof.ColumnTest[0].Authpath[0]))
+	col_test_size := 0
+	for _, col := range proof.ColumnTest {
+		col_test_size += 5 + len(col.List)*8 + len(col.Repeated_masks)*8 + hashes_size(col.Authpath)
+	}
+	col_test_size += hashes_size(proof.Multiproof)
 	shares_size := len(proof.Shares.Values)*8*len(proof.Shares.Index) + len(proof.Shares.Index)*8 + 8
-	proof_size := (len(proof.CodeTest)+len(proof.QuadraTest)+len(proof.LinearTest)+len(proof.Seeds))*8 + len(proof.MerkleRoot) + len(proof.FST_root) + len(proof.FST_authpath)*len(proof.FST_authpath[0]) + col_test_size
+	proof_size := (len(proof.CodeTest)+len(proof.QuadraTest)+len(proof.LinearTest)+len(proof.SumTest)+len(proof.Seeds))*8 + len(proof.MerkleRoot) + len(proof.FST_root) + hashes_size(proof.FST_authpath) + col_test_size
 	return int64(proof_size), int64(shares_size)
 }
+
+func hashes_size(hashes [][]byte) int {
+	size := 0
+	for _, hash := range hashes {
+		size += len(hash)
+	}
+	return size
+}
@@ -61,6 +61,18 @@ func (c *CryptoRandSource) Int63(q int64) int64 {
 	return int64(binary.LittleEndian.Uint64(b[:])&(1<<63-1)) % q
 }
 
+// Read fills p with the key stream, a seeded CryptoRandSource is a deterministic io.Reader for Params.Rand
+func (c *CryptoRandSource) Read(p []byte) (int, error) {
+	if c.mainCipher == nil {
+		return 0, errors.New("crypto seed not set")
+	}
+	for i := range p {
+		p[i] = 0
+	}
+	c.mainCipher.XORKeyStream(p, p)
+	return len(p), nil
+}
+
 func RandVector(seed []byte, length int, q int) []int {
 	random_vector := make([]int, length)
 
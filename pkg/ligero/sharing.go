@@ -0,0 +1,112 @@
+package ligero
+
+import (
+	"fmt"
+	"io"
+
+	"example.com/SMC/pkg/rss"
+	"example.com/SMC/pkg/shamir"
+	"gonum.org/v1/gonum/stat/combin"
+)
+
+// Schemes the inputs are shared among the servers with, the empty scheme is the replicated secret sharing
+const (
+	SharingRSS    = "rss"    // replicated secret sharing, Binomial(n_server, t) shares per secret
+	SharingShamir = "shamir" // Shamir secret sharing, one share per server
+)
+
+// SecretSharing splits a secret into the shares of the servers, reconstructs it and recovers the share
+// of a server from the shares of the others. RobustReconstruct also returns the positions in parties of the
+// servers whose shares were inconsistent.
+type SecretSharing interface {
+	Split(secret int) ([]int, [][]rss.Share, error)
+	Reconstruct(parties [][]rss.Share) (int, error)
+	RobustReconstruct(parties [][]rss.Share) (int, []int, error)
+	Recover(parties [][]rss.Share, index int) (int, error)
+}
+
+// NewSecretSharing creates the secret sharing of the given scheme, drawing its randomness from r
+func NewSecretSharing(sharing string, N, T, Q int, r io.Reader) (SecretSharing, error) {
+	switch sharing {
+	case "", SharingRSS:
+		return rss.NewReplicatedSecretSharingWithReader(N, T, Q, r)
+	case SharingShamir:
+		return shamir.NewShamirSecretSharingWithReader(N, T, Q, r)
+	}
+	return nil, fmt.Errorf("%w: unknown sharing %q", ErrInvalidParams, sharing)
+}
+
+// SharesPerServer returns the number of shares of a secret each server receives with the given scheme
+func SharesPerServer(sharing string, N, T int) int {
+	if sharing == SharingShamir {
+		return 1
+	}
+	return combin.Binomial(N-1, T)
+}
+
+// ShareIndices returns the indices of the shares of a secret the server of a party receives with the given scheme:
+// with replicated secret sharing the shares of the combinations of t servers the party is not part of
+func ShareIndices(sharing string, N, T, party int) []int {
+	if sharing == SharingShamir {
+		return []int{party}
+	}
+	var indices []int
+	for i, combination := range combin.Combinations(N, T) {
+		excluded := false
+		for _, p := range combination {
+			excluded = excluded || p == party
+		}
+		if !excluded {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// n_shares returns the number of shares a secret splits into with the given scheme
+func n_shares(sharing string, N, T int) (int, error) {
+	switch sharing {
+	case "", SharingRSS:
+		return combin.Binomial(N, T), nil
+	case SharingShamir:
+		return N, nil
+	}
+	return 0, fmt.Errorf("%w: unknown sharing %q", ErrInvalidParams, sharing)
+}
+
+// share_constraints returns the linear constraints of the shares of the block starting at row block.
+// Replicated shares have to sum up to the secret. Shamir shares have to lie on a polynomial of degree t
+// through the secret: the secret and every share beyond the first t+1 equal the interpolation of those.
+func (zk *LigeroZK) share_constraints(block int) [][]rowTerm {
+	if zk.sharing != SharingShamir {
+		terms := []rowTerm{{row: block, coeff: 1}}
+		for j := 1; j < zk.n_shares+1; j++ {
+			terms = append(terms, rowTerm{row: block + j, coeff: zk.q - 1})
+		}
+		return [][]rowTerm{terms}
+	}
+
+	x := make([]uint64, zk.t+1)
+	for i := range x {
+		x[i] = uint64(shamir.Point(i))
+	}
+	w := zk.field.LagrangeWeights(x)
+
+	// row -1 is the secret at point 0, row i the share i
+	var constraints [][]rowTerm
+	for i := -1; i < zk.n_shares; i++ {
+		if i >= 0 && i <= zk.t {
+			continue
+		}
+		z := uint64(0)
+		if i >= 0 {
+			z = uint64(shamir.Point(i))
+		}
+		terms := []rowTerm{{row: block + 1 + i, coeff: 1}}
+		for j, b := range zk.field.LagrangeBasis(x, w, z) {
+			terms = append(terms, rowTerm{row: block + 1 + j, coeff: int(zk.field.Neg(b))})
+		}
+		constraints = append(constraints, terms)
+	}
+	return constraints
+}
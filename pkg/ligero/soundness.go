@@ -0,0 +1,219 @@
+package ligero
+
+import (
+	crypto_rand "crypto/rand"
+	"fmt"
+	"io"
+	"math"
+)
+
+// RecommendedSoundnessBits is the soundness a proof should reach: with Fiat-Shamir a cheating prover can
+// grind for favorable challenges, so the soundness error bounds the work of an attack
+const RecommendedSoundnessBits = 128
+
+// Params are the parameters of NewLigeroZKFromParams that determine the soundness of a proof,
+// Repetitions 0 means a single repetition. Sharing is SharingRSS if empty. Rand is the source of the randomness
+// of the prover, crypto/rand if nil.
+type Params struct {
+	N_secret    int
+	M           int
+	N_server    int
+	T           int
+	Q           int
+	N_open      int
+	Repetitions int
+	Sharing     string
+	Rand        io.Reader `json:"-"`
+}
+
+// repetitions returns the number of repetitions of the tests
+func (p Params) repetitions() int {
+	if p.Repetitions == 0 {
+		return 1
+	}
+	return p.Repetitions
+}
+
+// rand returns the source of the randomness of the prover
+func (p Params) rand() io.Reader {
+	if p.Rand == nil {
+		return crypto_rand.Reader
+	}
+	return p.Rand
+}
+
+// Soundness is the soundness error of a proof, following the analysis of Ligero (Ames et al., CCS 2017).
+// Let n be the number of columns, k the number of coefficients of an encoded row and e < d/3 for the
+// minimum distance d = n-2k+2 of the code of the quadratic test. The Merkle leaf of a column commits to the
+// column and the masks of the tests at it before the challenges are drawn. An extended witness that is e-far
+// from the code passes the code test with probability at most (e+1)/q, a violated quadratic, linear or sum
+// constraint passes its random combination with probability at most 1/q. An extended witness e-far from the
+// code is caught unless none of the n_open opened columns is one of the e corrupted columns. Otherwise a test
+// that differs from the test of the decoded witness agrees with it at most at 2k-2 columns, the tests have
+// less than 2k-1 coefficients, so it is caught unless every opened column is one of these or a corrupted one.
+// Repeating the tests with r independent challenges multiplies the probabilities of the field, the opened
+// columns are the same for all repetitions.
+//
+// Field: ((e+4)/q)^r, reduced by a larger field or more repetitions
+// Opening: (1-e/n)^n_open + ((e+2k-2)/n)^n_open, reduced by opening more columns
+// Error: the soundness error Field + Opening
+// Bits: -log2(Error)
+type Soundness struct {
+	Field   float64
+	Opening float64
+	Error   float64
+	Bits    float64
+}
+
+// ComputeSoundness returns the soundness error of a proof generated with the parameters p
+func ComputeSoundness(p Params) (Soundness, error) {
+	if p.M <= 0 || p.M > p.N_secret {
+		return Soundness{}, fmt.Errorf("m has to be between 1 and n_secrets")
+	}
+	if p.N_secret%p.M != 0 {
+		return Soundness{}, fmt.Errorf("n_secrets has to be a multiple of m")
+	}
+	if 3*p.T+1 > p.N_server {
+		return Soundness{}, fmt.Errorf("n_server cannot be less than 3t+1")
+	}
+	if p.N_open <= 0 {
+		return Soundness{}, fmt.Errorf("n_open cannot be less than 1")
+	}
+	if p.Q <= 2 {
+		return Soundness{}, fmt.Errorf("q must be a prime number")
+	}
+	if p.repetitions() <= 0 {
+		return Soundness{}, fmt.Errorf("repetitions cannot be less than 1")
+	}
+
+	_, n, k, _ := code_size(p.N_secret, p.M, p.N_open, p.Q)
+	d := n - 2*k + 2
+	e := (d - 1) / 3
+	if e <= 0 {
+		return Soundness{Field: 1, Opening: 1, Error: 1}, nil
+	}
+
+	field := math.Pow(float64(e+4)/float64(p.Q), float64(p.repetitions()))
+	opening := math.Exp2(float64(p.N_open)*math.Log2(1-float64(e)/float64(n))) +
+		math.Exp2(float64(p.N_open)*math.Log2(math.Min(1, float64(e+2*k-2)/float64(n))))
+	total := math.Min(1, field+opening)
+	return Soundness{Field: field, Opening: opening, Error: total, Bits: -math.Log2(total)}, nil
+}
+
+// suggested_moduli are NTT-friendly primes below 2^63, from the smallest to the largest
+var suggested_moduli = []int{2013265921, 4611686018326724609}
+
+// max_open bounds the number of opened columns SuggestParams considers
+const max_open = 1 << 16
+
+// SuggestParams returns parameters for n_secret inputs shared among n_server servers with threshold t
+// that reach the given soundness: m balances the rows and columns of the extended witness, q is the smallest
+// suggested modulus whose field term allows the soundness and n_open the least number of opened columns
+func SuggestParams(N_secret, N_server, T int, bits float64) (Params, Soundness, error) {
+	M := balanced_m(N_secret)
+	best := 0.0
+	for _, Q := range suggested_moduli {
+		p := Params{N_secret: N_secret, M: M, N_server: N_server, T: T, Q: Q, Repetitions: 1}
+
+		// double n_open until the soundness is reached, the opening term shrinks with n_open
+		// while the field term grows slowly with it
+		lo, hi := 0, 1
+		for ; hi <= max_open; lo, hi = hi, 2*hi {
+			p.N_open = hi
+			s, err := ComputeSoundness(p)
+			if err != nil {
+				return Params{}, Soundness{}, err
+			}
+			best = math.Max(best, s.Bits)
+			if s.Bits >= bits {
+				break
+			}
+		}
+		if hi > max_open {
+			continue
+		}
+
+		// the least n_open in (lo, hi] that reaches the soundness
+		for lo+1 < hi {
+			p.N_open = (lo + hi) / 2
+			s, _ := ComputeSoundness(p)
+			if s.Bits >= bits {
+				hi = p.N_open
+			} else {
+				lo = p.N_open
+			}
+		}
+		p.N_open = hi
+		s, err := ComputeSoundness(p)
+		return p, s, err
+	}
+	return Params{}, Soundness{}, fmt.Errorf("cannot reach %.0f bits of soundness with one repetition, at most %.1f bits with q below 2^63", bits, best)
+}
+
+// balanced_m returns the least divisor of n_secret that is at least its square root
+func balanced_m(N_secret int) int {
+	d := int(math.Sqrt(float64(N_secret)))
+	for d > 1 && N_secret%d != 0 {
+		d--
+	}
+	if d < 1 {
+		return 1
+	}
+	return N_secret / d
+}
+
+// SuggestRepetitions keeps q of the parameters p and returns the least number of opened columns and then the
+// least number of repetitions that reach the given soundness, for deployments with a small field
+func SuggestRepetitions(p Params, bits float64) (Params, Soundness, error) {
+	// opened columns and repetitions each bring their term below half of the soundness error
+	p.Repetitions = 1
+	lo, hi := 0, 1
+	for ; hi <= max_open; lo, hi = hi, 2*hi {
+		p.N_open = hi
+		s, err := ComputeSoundness(p)
+		if err != nil {
+			return Params{}, Soundness{}, err
+		}
+		if -math.Log2(s.Opening) >= bits+1 {
+			break
+		}
+	}
+	if hi > max_open {
+		return Params{}, Soundness{}, fmt.Errorf("cannot reach %.0f bits of soundness with at most %d opened columns", bits, max_open)
+	}
+	for lo+1 < hi {
+		p.N_open = (lo + hi) / 2
+		s, _ := ComputeSoundness(p)
+		if -math.Log2(s.Opening) >= bits+1 {
+			hi = p.N_open
+		} else {
+			lo = p.N_open
+		}
+	}
+	p.N_open = hi
+
+	s, err := ComputeSoundness(p)
+	if err != nil {
+		return Params{}, Soundness{}, err
+	}
+	field_bits := -math.Log2(s.Field)
+	if field_bits <= 0 {
+		return Params{}, Soundness{}, fmt.Errorf("q is too small for %d opened columns", p.N_open)
+	}
+	p.Repetitions = int(math.Ceil((bits + 1) / field_bits))
+
+	s, err = ComputeSoundness(p)
+	return p, s, err
+}
+
+// CheckSoundness returns the soundness of the parameters p and an error if it is below min_bits
+func CheckSoundness(p Params, min_bits float64) (Soundness, error) {
+	s, err := ComputeSoundness(p)
+	if err != nil {
+		return s, err
+	}
+	if s.Bits < min_bits {
+		return s, fmt.Errorf("parameters give %.1f bits of soundness, less than the required %.1f bits", s.Bits, min_bits)
+	}
+	return s, nil
+}
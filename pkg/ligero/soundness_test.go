@@ -0,0 +1,105 @@
+package ligero
+
+import (
+	"testing"
+)
+
+func TestComputeSoundness(t *testing.T) {
+	p := Params{N_secret: 2000, M: 20, N_server: 4, T: 1, Q: 41543, N_open: 40}
+	s, err := ComputeSoundness(p)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if s.Bits <= 0 || s.Bits > 16 {
+		t.Fatalf("a 16-bit field cannot give %.1f bits of soundness", s.Bits)
+	}
+
+	// opening more columns only helps until the field term dominates
+	p.N_open = 240
+	more, err := ComputeSoundness(p)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if more.Opening >= s.Opening || more.Field < s.Field || more.Error < more.Field {
+		t.Fatalf("unexpected soundness terms %+v for n_open 240, %+v for n_open 40", more, s)
+	}
+
+	p.Q = 4611686018326724609
+	large, err := ComputeSoundness(p)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if large.Bits < 40 {
+		t.Fatalf("expected at least 40 bits of soundness with a 62-bit field, got %.1f", large.Bits)
+	}
+
+	for _, invalid := range []Params{{2000, 0, 4, 1, 41543, 40, 1, "", nil}, {2000, 20, 3, 1, 41543, 40, 1, "", nil}, {2000, 20, 4, 1, 41543, 0, 1, "", nil}, {2000, 30, 4, 1, 41543, 40, 1, "", nil}} {
+		_, err := ComputeSoundness(invalid)
+		if err == nil {
+			t.Fatalf("expected an error for %+v", invalid)
+		}
+	}
+
+	_, err = CheckSoundness(Params{2000, 20, 4, 1, 41543, 40, 1, "", nil}, 40)
+	if err == nil {
+		t.Fatalf("expected 41543 to be refused for 40 bits of soundness")
+	}
+}
+
+func TestSuggestParams(t *testing.T) {
+	for _, bits := range []float64{20, 40} {
+		p, s, err := SuggestParams(2000, 4, 1, bits)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if s.Bits < bits || p.N_secret%p.M != 0 {
+			t.Fatalf("suggested %+v gives %.1f bits, less than %.0f", p, s.Bits, bits)
+		}
+
+		// n_open is the least number of opened columns for q
+		p.N_open--
+		fewer, err := ComputeSoundness(p)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if fewer.Bits >= bits {
+			t.Fatalf("%d opened columns already give %.0f bits", p.N_open, bits)
+		}
+	}
+
+	_, _, err := SuggestParams(2000, 4, 1, 128)
+	if err == nil {
+		t.Fatalf("128 bits of soundness cannot be reached with one repetition")
+	}
+}
+
+func TestSuggestRepetitions(t *testing.T) {
+	// a 16-bit field reaches 128 bits of soundness by repeating the tests
+	p, s, err := SuggestRepetitions(Params{N_secret: 2000, M: 50, N_server: 4, T: 1, Q: 41543}, 128)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if p.Q != 41543 || p.Repetitions <= 1 || s.Bits < 128 {
+		t.Fatalf("suggested %+v gives %.1f bits", p, s.Bits)
+	}
+
+	checked, err := CheckSoundness(p, 128)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if checked != s {
+		t.Fatalf("soundness %+v differs from the suggested %+v", checked, s)
+	}
+
+	// one repetition less does not reach it
+	p.Repetitions--
+	fewer, _ := ComputeSoundness(p)
+	if fewer.Bits >= 128 {
+		t.Fatalf("%d repetitions already give 128 bits", p.Repetitions)
+	}
+
+	_, err = ComputeSoundness(Params{2000, 50, 4, 1, 41543, 40, -1, "", nil})
+	if err == nil {
+		t.Fatalf("expected an error for a negative number of repetitions")
+	}
+}
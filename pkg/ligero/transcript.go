@@ -0,0 +1,72 @@
+package ligero
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// ProtocolVersion is absorbed into every transcript, proofs of different versions never verify against each other
+const ProtocolVersion = 1
+
+const transcriptDomain = "smc-in-a-box/ligero"
+
+// Context is the statement a proof is bound to: a proof generated for one client in one experiment
+// does not verify for another client or experiment
+type Context struct {
+	Exp_ID    string
+	Client_ID string
+}
+
+// Transcript is the Fiat-Shamir transcript of a proof. Every message is absorbed with its label and length,
+// challenges are derived from all messages absorbed so far.
+type Transcript struct {
+	state [32]byte
+}
+
+// NewTranscript creates a transcript bound to the protocol version, the context and the parameters of zk
+func (zk *LigeroZK) NewTranscript(ctx Context) *Transcript {
+	ts := &Transcript{}
+	ts.AbsorbBytes("domain", []byte(transcriptDomain))
+	ts.AbsorbInts("version", []int{ProtocolVersion})
+	ts.AbsorbBytes("exp_id", []byte(ctx.Exp_ID))
+	ts.AbsorbBytes("client_id", []byte(ctx.Client_ID))
+	ts.AbsorbInts("params", []int{zk.n_server, zk.t, zk.q, zk.m, zk.n_open_col, zk.n_secret})
+	// absorbed only when repeated, transcripts of single repetition proofs are unchanged
+	if zk.repetitions > 1 {
+		ts.AbsorbInts("repetitions", []int{zk.repetitions})
+	}
+	ts.AbsorbBytes("predicate", []byte(zk.pred.String()))
+	return ts
+}
+
+// AbsorbBytes absorbs a labeled message into the transcript
+func (ts *Transcript) AbsorbBytes(label string, data []byte) {
+	h := sha256.New()
+	h.Write(ts.state[:])
+	writeLengthPrefixed(h.Write, []byte(label))
+	writeLengthPrefixed(h.Write, data)
+	copy(ts.state[:], h.Sum(nil))
+}
+
+// AbsorbInts absorbs a labeled vector of field elements into the transcript
+func (ts *Transcript) AbsorbInts(label string, data []int) {
+	ts.AbsorbBytes(label, ConvertToByteArray(data))
+}
+
+// Challenge derives a labeled challenge from the messages absorbed so far, the challenge is absorbed as well
+func (ts *Transcript) Challenge(label string) []byte {
+	h := sha256.New()
+	h.Write(ts.state[:])
+	writeLengthPrefixed(h.Write, []byte("challenge"))
+	writeLengthPrefixed(h.Write, []byte(label))
+	challenge := h.Sum(nil)
+	ts.AbsorbBytes(label, challenge)
+	return challenge
+}
+
+func writeLengthPrefixed(write func([]byte) (int, error), data []byte) {
+	var length [8]byte
+	binary.LittleEndian.PutUint64(length[:], uint64(len(data)))
+	write(length[:])
+	write(data)
+}
@@ -0,0 +1,34 @@
+package ligero
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTranscriptDomainSeparation(t *testing.T) {
+	zk, err := NewLigeroZK(3, 1, 4, 1, 10631, 3, BitPredicate())
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// moving bytes between the experiment and the client id must change the challenge
+	ts1 := zk.NewTranscript(Context{Exp_ID: "exp1", Client_ID: "c1"})
+	ts2 := zk.NewTranscript(Context{Exp_ID: "exp1c", Client_ID: "1"})
+	if bytes.Equal(ts1.Challenge("c"), ts2.Challenge("c")) {
+		t.Fatalf("challenges of different contexts are equal")
+	}
+
+	// challenges are deterministic and differ by label and position
+	ts1 = zk.NewTranscript(ctx)
+	ts2 = zk.NewTranscript(ctx)
+	c1 := ts1.Challenge("a")
+	if !bytes.Equal(c1, ts2.Challenge("a")) {
+		t.Fatalf("challenges of equal transcripts differ")
+	}
+	if bytes.Equal(c1, ts1.Challenge("a")) {
+		t.Fatalf("repeated challenge is equal to the previous one")
+	}
+	if bytes.Equal(ts1.Challenge("a"), ts2.Challenge("b")) {
+		t.Fatalf("challenges of different labels are equal")
+	}
+}
@@ -1,8 +1,8 @@
 package ligero
 
 import (
+	"encoding/binary"
 	"fmt"
-	"log"
 	"strings"
 
 	"example.com/SMC/pkg/rss"
@@ -10,7 +10,7 @@ import (
 
 func ConvertToColumnwise(matrix [][]int) ([][]int, error) {
 	if len(matrix) == 0 {
-		return nil, fmt.Errorf("matrix cannot be empty")
+		return nil, fmt.Errorf("%w: matrix cannot be empty", ErrDimensionMismatch)
 	}
 	result := make([][]int, len(matrix[0]))
 	for j := 0; j < len(matrix[0]); j++ {
@@ -25,7 +25,7 @@ func ConvertToColumnwise(matrix [][]int) ([][]int, error) {
 
 func ConvertColumnToString(list []int) (string, error) {
 	if len(list) == 0 {
-		return "", fmt.Errorf("list cannot be empty")
+		return "", fmt.Errorf("%w: list cannot be empty", ErrDimensionMismatch)
 	}
 
 	col := make([]string, len(list))
@@ -41,7 +41,7 @@ func ConvertColumnToString(list []int) (string, error) {
 
 func ConvertSharesToColumnwise(shares [][]rss.Share) ([][]rss.Share, error) {
 	if len(shares) == 0 {
-		return nil, fmt.Errorf("shares cannot be empty")
+		return nil, fmt.Errorf("%w: shares cannot be empty", ErrDimensionMismatch)
 	}
 	result := make([][]rss.Share, len(shares[0]))
 	for j := 0; j < len(shares[0]); j++ {
@@ -54,18 +54,74 @@ func ConvertSharesToColumnwise(shares [][]rss.Share) ([][]rss.Share, error) {
 	return result, nil
 }
 
+// ConvertToByteArray serializes field elements as fixed-width 8-byte little-endian values
 func ConvertToByteArray(input []int) []byte {
-	size := len(input)
-	if size == 0 {
-		log.Fatal("cannot convert empty integer array to byte array")
+	result := make([]byte, 8*len(input))
+	for j, value := range input {
+		binary.LittleEndian.PutUint64(result[8*j:], uint64(value))
 	}
+	return result
+}
+
+// leaf encodings of the Merkle trees, proofs record the encoding they were committed with
+const (
+	LeafString = iota // every field element as 64 ASCII bits, used by proofs without Leaf_version
+	LeafBytes         // every field element as 8 little-endian bytes
+)
+
+// LeafVersion is the leaf encoding of newly generated proofs
+const LeafVersion = LeafBytes
 
-	list := make([]string, size)
-	for j := 0; j < size; j++ {
-		list[j] = fmt.Sprintf("%064b", input[j])
+// column_leaf serializes a column of the encoded extended witness, the masks of the tests at the column and
+// its nonce into a Merkle leaf
+func column_leaf(column []int, masks []int, nonce int, version int) ([]byte, error) {
+	switch version {
+	case LeafString:
+		// legacy leaves are prefixed by len(column)+1 zeros
+		list := make([]int, len(column)+1)
+		list = append(list, column...)
+		list = append(list, masks...)
+		list = append(list, nonce)
+		concatenated, err := ConvertColumnToString(list)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(concatenated), nil
+	case LeafBytes:
+		list := make([]int, 0, len(column)+len(masks)+1)
+		list = append(list, column...)
+		list = append(list, masks...)
+		list = append(list, nonce)
+		return ConvertToByteArray(list), nil
 	}
+	return nil, fmt.Errorf("unknown leaf version %d", version)
+}
 
-	//concatenate values in the column to a string
-	concatenated := strings.Join(list, "")
-	return []byte(concatenated)
+// fst_leaf serializes the shares of a party and the seeds of its share rows into a leaf of the FST Merkle tree
+func fst_leaf(shares Shares, seeds []int, version int) ([]byte, error) {
+	list := make([]int, 0, len(shares.Values)*len(shares.Index)+len(shares.Index))
+	for _, values := range shares.Values {
+		if len(values) != len(shares.Index) {
+			return nil, fmt.Errorf("%w: number of shares does not match number of indices", ErrDimensionMismatch)
+		}
+		list = append(list, values...)
+	}
+	for _, index := range shares.Index {
+		if index < 0 || index >= len(seeds) {
+			return nil, fmt.Errorf("%w: share index %d is out of range", ErrDimensionMismatch, index)
+		}
+		list = append(list, seeds[index])
+	}
+
+	switch version {
+	case LeafString:
+		concatenated, err := ConvertColumnToString(list)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(concatenated), nil
+	case LeafBytes:
+		return ConvertToByteArray(list), nil
+	}
+	return nil, fmt.Errorf("unknown leaf version %d", version)
 }
@@ -0,0 +1,13 @@
+package packed
+
+import "errors"
+
+// Errors of the package wrap one of the following, callers tell them apart with errors.Is
+var (
+	// ErrInvalidParams is returned for parameters a PackedSecretSharing cannot be created with
+	ErrInvalidParams = errors.New("invalid parameters")
+	// ErrDimensionMismatch is returned for secrets, shares or indices that do not fit the parameters
+	ErrDimensionMismatch = errors.New("dimension mismatch")
+	// ErrInvalidShares is returned for shares with too many errors to decode the secrets
+	ErrInvalidShares = errors.New("invalid shares")
+)
@@ -0,0 +1,201 @@
+package packed
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+
+	"example.com/SMC/pkg/field"
+)
+
+// For NTT-friendly q the secrets and the randomness are placed on the subgroup of d-th roots of unity,
+// d the smallest power of two >= t+k, and the shares on the coset shift*<omega_n> of the n-th roots of unity.
+// Split is then an inverse NTT of size d followed by an NTT of size n, O(n log n) instead of O(n(t+k)).
+// The polynomial has d-k random values, at least t, and degree less than d.
+//
+// d: the number of points that determine a shared polynomial
+// omega_d: a primitive d-th root of unity
+// omega_n: a primitive n-th root of unity
+// shift: a quadratic non-residue, the coset does not intersect the roots of unity
+
+type ntt_domain struct {
+	d       int
+	omega_d uint64
+	omega_n uint64
+	shift   uint64
+}
+
+// NTTFriendly reports whether q is a prime with a subgroup of n-th roots of unity and a coset
+// of it outside the subgroup, n a power of two
+func NTTFriendly(Q, N int) bool {
+	if N <= 0 || N&(N-1) != 0 {
+		return false
+	}
+	f, err := field.NewInt(Q)
+	if err != nil {
+		return false
+	}
+	return N < 1<<f.TwoAdicity()
+}
+
+// NewNTTPackedSecretSharing creates a packed secret sharing that encodes with the NTT,
+// n has to be a power of two and q NTT-friendly for n
+func NewNTTPackedSecretSharing(N, T, K, Q int) (*PackedSecretSharing, error) {
+	return NewNTTPackedSecretSharingWithReader(N, T, K, Q, rand.Reader)
+}
+
+// NewNTTPackedSecretSharingWithReader creates a packed secret sharing that encodes with the NTT and draws the
+// random values of SplitRandom from r, see NewPackedSecretSharingWithReader
+func NewNTTPackedSecretSharingWithReader(N, T, K, Q int, r io.Reader) (*PackedSecretSharing, error) {
+	p, err := new_packed(N, T, K, Q, r)
+	if err != nil {
+		return nil, err
+	}
+
+	if !NTTFriendly(Q, N) {
+		return nil, fmt.Errorf("%w: q-1 is not divisible by 2n for n = %d", ErrInvalidParams, N)
+	}
+
+	d := 1
+	for d < T+K {
+		d *= 2
+	}
+	if d > N {
+		return nil, fmt.Errorf("%w: n cannot be less than %d, the power of two above t+k", ErrInvalidParams, d)
+	}
+
+	omega_d, err := p.field.RootOfUnity(d)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidParams, err)
+	}
+	omega_n, err := p.field.RootOfUnity(N)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidParams, err)
+	}
+
+	p.ntt = &ntt_domain{d: d, omega_d: omega_d, omega_n: omega_n, shift: p.field.NonResidue()}
+	return p, nil
+}
+
+// NTT reports whether p encodes with the NTT
+func (p *PackedSecretSharing) NTT() bool {
+	return p.ntt != nil
+}
+
+func (p *PackedSecretSharing) split_ntt(secrets []int, random []uint64) ([]Share, error) {
+	coeffs, err := p.coefficients(secrets, random)
+	if err != nil {
+		return nil, err
+	}
+
+	// values of the polynomial on the coset
+	values := make([]uint64, p.n)
+	copy(values, coeffs)
+	p.scale(values, p.ntt.shift)
+	p.field.NTT(values, p.ntt.omega_n)
+
+	shares := make([]Share, p.n)
+	for i := range shares {
+		shares[i] = Share{Index: p.SharePoint(i), Value: int(values[i])}
+	}
+	return shares, nil
+}
+
+func (p *PackedSecretSharing) split_ntt_at(secrets []int, random []uint64, indices []int) ([]int, error) {
+	coeffs, err := p.coefficients(secrets, random)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]int, len(indices))
+	for j, i := range indices {
+		x := p.field.Mul(p.ntt.shift, p.field.Exp(p.ntt.omega_n, uint64(i)))
+		y := uint64(0)
+		for c := len(coeffs) - 1; c >= 0; c-- {
+			y = p.field.Add(p.field.Mul(y, x), coeffs[c])
+		}
+		values[j] = int(y)
+	}
+	return values, nil
+}
+
+// coefficients returns the d coefficients of the polynomial through the secrets and the random values
+func (p *PackedSecretSharing) coefficients(secrets []int, random []uint64) ([]uint64, error) {
+	if len(secrets) > p.k {
+		return nil, fmt.Errorf("%w: cannot split more than k secrets", ErrDimensionMismatch)
+	}
+
+	values := make([]uint64, p.ntt.d)
+	for i, secret := range secrets {
+		values[i] = p.field.Reduce(secret)
+	}
+
+	copy(values[p.k:], random)
+
+	p.field.InverseNTT(values, p.ntt.omega_d)
+	return values, nil
+}
+
+// scale multiplies the i-th coefficient by c^i, the polynomial f(x) becomes f(c*x)
+func (p *PackedSecretSharing) scale(coeffs []uint64, c uint64) {
+	power := uint64(1)
+	for i := range coeffs {
+		coeffs[i] = p.field.Mul(coeffs[i], power)
+		power = p.field.Mul(power, c)
+	}
+}
+
+// Interpolate returns the coefficients of the polynomial through the values at the share points
+// 0, stride, 2*stride, ...; len(values)*stride has to be n
+func (p *PackedSecretSharing) Interpolate(values []int, stride int) ([]int, error) {
+	if p.ntt == nil {
+		return nil, fmt.Errorf("%w: interpolation requires an NTT-friendly q", ErrInvalidParams)
+	}
+
+	size := len(values)
+	if size == 0 || stride <= 0 || size*stride != p.n {
+		return nil, fmt.Errorf("%w: cannot interpolate %d values with stride %d from %d shares", ErrDimensionMismatch, size, stride, p.n)
+	}
+
+	coeffs := make([]uint64, size)
+	for i, value := range values {
+		coeffs[i] = p.field.Reduce(value)
+	}
+	p.field.InverseNTT(coeffs, p.field.Exp(p.ntt.omega_n, uint64(stride)))
+	p.scale(coeffs, p.field.Inv(p.ntt.shift))
+
+	result := make([]int, size)
+	for i, c := range coeffs {
+		result[i] = int(c)
+	}
+	return result, nil
+}
+
+// Evaluate returns the value of the polynomial with the given coefficients at x
+func (p *PackedSecretSharing) Evaluate(coeffs []int, x int) int {
+	y := 0
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		y = p.field.AddInt(p.field.MulInt(y, x), coeffs[i])
+	}
+	return y
+}
+
+// EvaluateSecrets returns the values of the polynomial with the given coefficients at the k secret points
+func (p *PackedSecretSharing) EvaluateSecrets(coeffs []int) ([]int, error) {
+	if p.ntt == nil {
+		return nil, fmt.Errorf("%w: evaluation requires an NTT-friendly q", ErrInvalidParams)
+	}
+
+	// x^d = 1 on the d-th roots of unity, fold the coefficients before a NTT of size d
+	folded := make([]uint64, p.ntt.d)
+	for i, c := range coeffs {
+		folded[i%p.ntt.d] = p.field.Add(folded[i%p.ntt.d], p.field.Reduce(c))
+	}
+	p.field.NTT(folded, p.ntt.omega_d)
+
+	secrets := make([]int, p.k)
+	for i := range secrets {
+		secrets[i] = int(folded[i])
+	}
+	return secrets, nil
+}
@@ -1,27 +1,35 @@
 package packed
 
 import (
+	"crypto/rand"
 	"fmt"
-	"math/big"
+	"io"
+
+	"example.com/SMC/pkg/field"
 )
 
 // n: the number of shares that a vector of secrets are split into
 // t: the maximum number of shares that may be seen
 // without learning anything about the secret
 // k: the number of secrets shared together
-// q: a modulus
+// q: a prime modulus below 2^63, shares are stored as int
 // t + k: the minimum number of shares needed to reconstruct the secret
+//
+// By default secrets are placed at -1, ..., -k and shares at 1, ..., n, and polynomials are
+// evaluated with lagrange interpolation. NewNTTPackedSecretSharing places them on roots of unity instead,
+// see ntt.go.
+
+// A PackedSecretSharing is immutable after construction and can be used from concurrent goroutines.
 
 type PackedSecretSharing struct {
-	flag_num            []bool
-	glob_constant_num   [][]int
-	glob_constant_denum []int
-	n                   int
-	t                   int
-	k                   int
-	q                   int
-	glob_num            int
-	flag_denum          bool
+	share_basis [][]int // lagrange basis polynomials of the t+k secret points at each share point
+	n           int
+	t           int
+	k           int
+	q           int
+	field       *field.Field
+	ntt         *ntt_domain
+	rand        io.Reader
 }
 
 type Share struct {
@@ -30,32 +38,83 @@ type Share struct {
 }
 
 func NewPackedSecretSharing(N, T, K, Q int) (*PackedSecretSharing, error) {
+	return NewPackedSecretSharingWithReader(N, T, K, Q, rand.Reader)
+}
+
+// NewPackedSecretSharingWithReader creates a packed secret sharing that draws the random values of SplitRandom
+// from r, crypto/rand if r is nil. A deterministic r reproduces the shares, e.g. in tests; SplitRandom is then only
+// safe for concurrent use if r is. Split derives the random values from its seed instead.
+func NewPackedSecretSharingWithReader(N, T, K, Q int, r io.Reader) (*PackedSecretSharing, error) {
+	p, err := new_packed(N, T, K, Q, r)
+	if err != nil {
+		return nil, err
+	}
+
+	// the polynomial through the secret points is evaluated at every share point by Split
+	x_samples := make([]uint64, p.Degree())
+	for i := range x_samples {
+		x_samples[i] = uint64(p.SecretPoint(i))
+	}
+	weights := p.field.LagrangeWeights(x_samples)
+	p.share_basis = make([][]int, N)
+	for i := range p.share_basis {
+		p.share_basis[i] = to_ints(p.field.LagrangeBasis(x_samples, weights, uint64(p.SharePoint(i))))
+	}
+	return p, nil
+}
+
+// new_packed checks the parameters and creates a packed secret sharing without encoding tables
+func new_packed(N, T, K, Q int, r io.Reader) (*PackedSecretSharing, error) {
 	if T+K > N {
-		return nil, fmt.Errorf("n cannot be less than t+k")
+		return nil, fmt.Errorf("%w: n cannot be less than t+k", ErrInvalidParams)
 	}
 
 	//constrainrs on t and k
 	if K < 1 {
-		return nil, fmt.Errorf("k must be at least 1")
+		return nil, fmt.Errorf("%w: k must be at least 1", ErrInvalidParams)
+	}
+
+	//q has to be a prime number below 2^63
+	f, err := field.NewInt(Q)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidParams, err)
 	}
 
-	//q has to be a prime number
-	if !big.NewInt(int64(Q)).ProbablyPrime(0) {
-		return nil, fmt.Errorf("q must be a prime number")
+	if r == nil {
+		r = rand.Reader
 	}
 
-	return &PackedSecretSharing{n: N, t: T, k: K, q: Q, flag_num: make([]bool, N), glob_constant_num: make([][]int, N), glob_num: 1, flag_denum: false, glob_constant_denum: make([]int, N)}, nil
+	return &PackedSecretSharing{n: N, t: T, k: K, q: Q, field: f, rand: r}, nil
 
 }
 
 // Split takes k secrets and generates n shares.
 // Each returned share was attached a tag used to reconstruct the secrets.
 func (p *PackedSecretSharing) Split(secrets []int, seed int) ([]Share, error) {
+	return p.split(secrets, p.seeded_values(seed))
+}
+
+// SplitRandom takes k secrets and generates n shares like Split, with random values drawn from the reader
+// of the packed secret sharing instead of derived from a seed
+func (p *PackedSecretSharing) SplitRandom(secrets []int) ([]Share, error) {
+	random, err := p.drawn_values()
+	if err != nil {
+		return nil, err
+	}
+	return p.split(secrets, random)
+}
+
+// split shares the secrets with the random values of the polynomial
+func (p *PackedSecretSharing) split(secrets []int, random []uint64) ([]Share, error) {
 	if len(secrets) == 0 {
-		return nil, fmt.Errorf("cannot split an empty secret")
+		return nil, fmt.Errorf("%w: cannot split an empty secret", ErrDimensionMismatch)
 	}
 
-	x_samples, y_samples, err := p.sample_packed_polynomial(secrets, seed)
+	if p.ntt != nil {
+		return p.split_ntt(secrets, random)
+	}
+
+	_, y_samples, err := p.sample_packed_polynomial(secrets, random)
 
 	if err != nil {
 		return nil, err
@@ -64,167 +123,197 @@ func (p *PackedSecretSharing) Split(secrets []int, seed int) ([]Share, error) {
 	shares := make([]Share, p.n)
 
 	for idx := range shares {
-		xCoordinate := idx + 1
-		shares[idx].Index = xCoordinate
-
-		shares[idx].Value = p.interpolate_at_point(x_samples, y_samples, xCoordinate)
+		shares[idx].Index = p.SharePoint(idx)
+		shares[idx].Value = p.evaluate_share(y_samples, idx)
 	}
 
 	return shares, nil
 
 }
 
+// SplitAt returns the values of the shares of Split(secrets, seed) at the share indices 0 <= i < n only,
+// without computing the other shares
+func (p *PackedSecretSharing) SplitAt(secrets []int, seed int, indices []int) ([]int, error) {
+	if len(secrets) == 0 {
+		return nil, fmt.Errorf("%w: cannot split an empty secret", ErrDimensionMismatch)
+	}
+	for _, i := range indices {
+		if i < 0 || i >= p.n {
+			return nil, fmt.Errorf("%w: share index %d is out of range", ErrDimensionMismatch, i)
+		}
+	}
+
+	if p.ntt != nil {
+		return p.split_ntt_at(secrets, p.seeded_values(seed), indices)
+	}
+
+	_, y_samples, err := p.sample_packed_polynomial(secrets, p.seeded_values(seed))
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]int, len(indices))
+	for j, i := range indices {
+		values[j] = p.evaluate_share(y_samples, i)
+	}
+	return values, nil
+}
+
 // Reconstruct takes t+k shares and reconstruct k secrets
 func (p *PackedSecretSharing) Reconstruct(parts []Share) ([]int, error) {
 	//need t+k shares to reconstruct
-	if len(parts) < p.t+p.k {
-		return nil, fmt.Errorf("cannot reconstruct, as number of shares less than t+k")
+	if len(parts) < p.Degree() {
+		return nil, fmt.Errorf("%w: cannot reconstruct, as number of shares less than %d", ErrDimensionMismatch, p.Degree())
 	}
 
 	if len(parts) > p.n {
-		return nil, fmt.Errorf("cannot reconstruct, as number of shares more than n")
+		return nil, fmt.Errorf("%w: cannot reconstruct, as number of shares more than n", ErrDimensionMismatch)
 	}
 
-	var x_samples []int
-	var y_samples []int
+	x_samples := make([]uint64, len(parts))
+	seen := make(map[uint64]bool, len(parts))
 	for i := 0; i < len(parts); i++ {
-		x_samples = append(x_samples, parts[i].Index)
-		y_samples = append(y_samples, parts[i].Value)
+		x_samples[i] = p.field.Reduce(parts[i].Index)
+		if seen[x_samples[i]] {
+			return nil, fmt.Errorf("%w: cannot reconstruct from two shares at point %d", ErrDimensionMismatch, x_samples[i])
+		}
+		seen[x_samples[i]] = true
 	}
 
+	// the shares at hand have their own weights, unlike the secret points of Split
+	weights := p.field.LagrangeWeights(x_samples)
 	secrets := make([]int, p.k)
 	for i := 0; i < p.k; i++ {
-		xCoordinate := mod(-i-1, p.q)
-		secrets[i] = p.interpolate_at_point(x_samples, y_samples, xCoordinate)
+		basis := p.field.LagrangeBasis(x_samples, weights, uint64(p.SecretPoint(i)))
+		for j := range parts {
+			secrets[i] = p.field.AddInt(secrets[i], p.field.MulInt(parts[j].Value, int(basis[j])))
+		}
 	}
 	return secrets, nil
 
 }
 
-// sample_packed_polynomial constructs a random polynomial of t+k-1 degree
-func (p *PackedSecretSharing) sample_packed_polynomial(secrets []int, seed int) ([]int, []int, error) {
-	x_samples := make([]int, p.k+p.t)
-	for i := 0; i < p.k+p.t; i++ {
-		x_samples[i] = mod(-i-1, p.q)
+// RobustReconstruct decodes k secrets from at least t+k shares with the Berlekamp-Welch algorithm, correcting up
+// to (len(parts)-t-k)/2 wrong shares, and returns them with the positions in parts of the wrong shares
+func (p *PackedSecretSharing) RobustReconstruct(parts []Share) ([]int, []int, error) {
+	if len(parts) < p.Degree() {
+		return nil, nil, fmt.Errorf("%w: cannot reconstruct, as number of shares less than %d", ErrDimensionMismatch, p.Degree())
 	}
 
-	randomness_values := make([]int, p.t)
-	seedValue := int64(seed)
-	crs := NewCryptoRandSource()
-	crs.Seed(seedValue)
-	for i := 0; i < p.t; i++ {
-		randomNumber := crs.Int63()
-		randomness_values[i] = int(randomNumber % int64(p.q))
+	x_samples := make([]uint64, len(parts))
+	y_samples := make([]uint64, len(parts))
+	seen := make(map[uint64]bool, len(parts))
+	for i := range parts {
+		x_samples[i] = p.field.Reduce(parts[i].Index)
+		if seen[x_samples[i]] {
+			return nil, nil, fmt.Errorf("%w: cannot reconstruct from two shares at point %d", ErrDimensionMismatch, x_samples[i])
+		}
+		seen[x_samples[i]] = true
+		y_samples[i] = p.field.Reduce(parts[i].Value)
 	}
 
-	y_samples := append(secrets, randomness_values...)
+	coeffs, wrong, err := p.field.BerlekampWelch(x_samples, y_samples, p.Degree())
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidShares, err)
+	}
 
-	return x_samples, y_samples, nil
+	secrets := make([]int, p.k)
+	for i := range secrets {
+		secrets[i] = int(p.field.Eval(coeffs, uint64(p.SecretPoint(i))))
+	}
+	return secrets, wrong, nil
 }
 
-// interpolate_at_point takes t+k sample points and returns
-// the value at a given x using a lagrange interpolation.
-func (p *PackedSecretSharing) interpolate_at_point(x_samples []int, y_samples []int, x int) int {
-	if !p.flag_denum {
-		p.glob_constant_denum = make([]int, len(x_samples))
-		p.glob_constant_denum = p.lagrange_constants_for_point(x_samples)
-		p.flag_denum = true
+// Degree returns the number of points that determine a shared polynomial, its degree is at most Degree()-1
+func (p *PackedSecretSharing) Degree() int {
+	if p.ntt != nil {
+		return p.ntt.d
 	}
+	return p.t + p.k
+}
 
-	if !p.flag_num[x-1] {
-		p.glob_constant_num[x-1] = make([]int, len(x_samples))
-
-		num := 1
-		for j := 0; j < len(x_samples); j++ {
-			if x == x_samples[j] {
-				fmt.Println("ERROR: EQUAL. Numerators goes to zero!!")
-			}
-			num = mod(num*(x_samples[j]-x), p.q)
-		}
-
-		for i := 0; i < len(x_samples); i++ {
-
-			p.glob_constant_num[x-1][i] = mod(p.inverse(x_samples[i]-x)*num, p.q)
-		}
-
-		p.flag_num[x-1] = true
-
+// SecretPoint returns the evaluation point of the i-th secret, for i >= k of the i-th random value
+func (p *PackedSecretSharing) SecretPoint(i int) int {
+	if p.ntt != nil {
+		return int(p.field.Exp(p.ntt.omega_d, uint64(i)))
 	}
+	return mod(-i-1, p.q)
+}
 
-	y := 0
-	for i := 0; i < len(y_samples); i++ {
-		y = y + y_samples[i]*p.glob_constant_denum[i]*p.glob_constant_num[x-1][i]
+// SharePoint returns the evaluation point of the i-th share
+func (p *PackedSecretSharing) SharePoint(i int) int {
+	if p.ntt != nil {
+		return int(p.field.Mul(p.ntt.shift, p.field.Exp(p.ntt.omega_n, uint64(i))))
 	}
-	return mod(y, p.q)
+	return i + 1
 }
 
-func (p *PackedSecretSharing) lagrange_constants_for_point(x_samples []int) []int {
-
-	constants := make([]int, len(x_samples))
-	for i := range constants {
-		constants[i] = 0
+// sample_packed_polynomial constructs a random polynomial of t+k-1 degree with the random values
+func (p *PackedSecretSharing) sample_packed_polynomial(secrets []int, random []uint64) ([]int, []int, error) {
+	if len(secrets) > p.k {
+		return nil, nil, fmt.Errorf("%w: cannot split more than k secrets", ErrDimensionMismatch)
 	}
 
-	for i := 0; i < len(constants); i++ {
-		xi := x_samples[i]
-		denum := 1
-		for j := 0; j < len(constants); j++ {
-			if j != i {
-				xj := x_samples[j]
-				denum = mod(denum*(xj-xi), p.q)
-			}
-		}
-		constants[i] = mod(p.inverse(denum), p.q)
+	x_samples := make([]int, p.k+p.t)
+	for i := 0; i < p.k+p.t; i++ {
+		x_samples[i] = p.SecretPoint(i)
 	}
 
-	return constants
+	y_samples := make([]int, 0, len(secrets)+len(random))
+	y_samples = append(y_samples, secrets...)
+	y_samples = append(y_samples, to_ints(random)...)
+
+	return x_samples, y_samples, nil
 }
 
-// from http://www.ucl.ac.uk/~ucahcjm/combopt/ext_gcd_python_programs.pdf
-func egcd_binary(a int, b int) int {
-	u, v, s, t, r := 1, 0, 0, 1, 0
-	for (mod(a, 2) == 0) && (mod(b, 2) == 0) {
-		a, b, r = a/2, b/2, r+1
+// n_random returns the number of random values of a shared polynomial
+func (p *PackedSecretSharing) n_random() int {
+	if p.ntt != nil {
+		return p.ntt.d - p.k
 	}
+	return p.t
+}
 
-	alpha, beta := a, b
-
-	for mod(a, 2) == 0 {
-		a = a / 2
-		if (mod(u, 2) == 0) && (mod(v, 2) == 0) {
-			u, v = u/2, v/2
-		} else {
-			u, v = (u+beta)/2, (v-alpha)/2
-		}
-
+// seeded_values returns the random values of a shared polynomial derived from the seed
+func (p *PackedSecretSharing) seeded_values(seed int) []uint64 {
+	crs := NewCryptoRandSource()
+	crs.Seed(int64(seed))
+	values := make([]uint64, p.n_random())
+	for i := range values {
+		values[i] = uint64(crs.Int63() % int64(p.q))
 	}
+	return values
+}
 
-	for a != b {
-		if mod(b, 2) == 0 {
-			b = b / 2
-			if (mod(s, 2) == 0) && (mod(t, 2) == 0) {
-				s, t = s/2, t/2
-			} else {
-				s, t = (s+beta)/2, (t-alpha)/2
-			}
-		} else if b < a {
-			a, b, u, v, s, t = b, a, s, t, u, v
-		} else {
-
-			b, s, t = b-a, s-u, t-v
+// drawn_values returns the random values of a shared polynomial read from the reader as field elements
+func (p *PackedSecretSharing) drawn_values() ([]uint64, error) {
+	values := make([]uint64, p.n_random())
+	for i := range values {
+		v, err := p.field.Rand(p.rand)
+		if err != nil {
+			return nil, err
 		}
-
+		values[i] = v
 	}
-
-	return s
+	return values, nil
 }
 
-// inverse calculates the inverse of a number
-func (p *PackedSecretSharing) inverse(a int) int {
+// evaluate_share returns the value at the i-th share point of the polynomial with the values y_samples at
+// the t+k secret points
+func (p *PackedSecretSharing) evaluate_share(y_samples []int, i int) int {
+	y := 0
+	for j, v := range y_samples {
+		y = p.field.AddInt(y, p.field.MulInt(v, p.share_basis[i][j]))
+	}
+	return y
+}
 
-	a = (a + p.q) % p.q
-	b := egcd_binary(a, p.q)
-	return b
+func to_ints(values []uint64) []int {
+	result := make([]int, len(values))
+	for i, v := range values {
+		result[i] = int(v)
+	}
+	return result
 }
 
 // mod computes a%b and a could be negative number
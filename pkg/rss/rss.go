@@ -3,15 +3,19 @@ package rss
 import (
 	"crypto/rand"
 	"fmt"
-	"math/big"
+	"io"
+	"sort"
 
+	"example.com/SMC/pkg/field"
 	"gonum.org/v1/gonum/stat/combin"
 )
 
 type ReplicatedSecretSharing struct {
-	n int
-	t int
-	q int
+	n     int
+	t     int
+	q     int
+	field *field.Field
+	rand  io.Reader // source of the random shares
 }
 
 type Party struct {
@@ -25,15 +29,31 @@ type Share struct {
 }
 
 func NewReplicatedSecretSharing(N, T, Q int) (*ReplicatedSecretSharing, error) {
+	return NewReplicatedSecretSharingWithReader(N, T, Q, rand.Reader)
+}
+
+// NewReplicatedSecretSharingWithReader creates a replicated secret sharing that draws the random shares from r,
+// crypto/rand if r is nil. A deterministic r reproduces the shares, e.g. in tests; Split is then only safe for
+// concurrent use if r is.
+func NewReplicatedSecretSharingWithReader(N, T, Q int, r io.Reader) (*ReplicatedSecretSharing, error) {
 	if T > N {
-		return nil, fmt.Errorf("n cannot be less than t")
+		return nil, fmt.Errorf("%w: n cannot be less than t", ErrInvalidParams)
+	}
+
+	if T < 0 {
+		return nil, fmt.Errorf("%w: t cannot be negative", ErrInvalidParams)
+	}
+
+	f, err := field.NewInt(Q)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidParams, err)
 	}
 
-	if !big.NewInt(int64(Q)).ProbablyPrime(0) {
-		return nil, fmt.Errorf("q must be a prime number")
+	if r == nil {
+		r = rand.Reader
 	}
 
-	return &ReplicatedSecretSharing{n: N, t: T, q: Q}, nil
+	return &ReplicatedSecretSharing{n: N, t: T, q: Q, field: f, rand: r}, nil
 
 }
 
@@ -47,15 +67,14 @@ func (rss *ReplicatedSecretSharing) Split(secret int) ([]int, [][]Share, error)
 	shares := make([]int, n_sh)
 	shares[n_sh-1] = secret
 	for i := 0; i < n_sh-1; i++ {
-		val, err := rand.Int(rand.Reader, big.NewInt(int64(rss.q)))
+		val, err := rss.field.Rand(rss.rand)
 		if err != nil {
 			return nil, nil, err
 		}
-		shares[i] = int(val.Int64())
-		temp := shares[n_sh-1]
-		shares[n_sh-1] = temp - shares[i]
+		shares[i] = int(val)
+		shares[n_sh-1] = rss.field.SubInt(shares[n_sh-1], shares[i])
 	}
-	shares[n_sh-1] = mod(shares[n_sh-1], rss.q)
+	shares[n_sh-1] = int(rss.field.Reduce(shares[n_sh-1]))
 
 	// Associate the above shares to respective parties
 	shParty := make(map[int][]Share)
@@ -77,35 +96,233 @@ func (rss *ReplicatedSecretSharing) Split(secret int) ([]int, [][]Share, error)
 
 }
 
+// Reconstruct sums up the majority value of every share among the parties holding it
 func (rss *ReplicatedSecretSharing) Reconstruct(parties [][]Share) (int, error) {
-	//generate a map
-	//key: index of the shares the srecret splits to
-	//value:a list values associated to the key
+	secret, _, err := rss.RobustReconstruct(parties)
+	return secret, err
+}
+
+// RobustReconstruct sums up the majority value of every share among the parties holding it and returns the secret
+// with the positions in parties of the parties holding a share out of range or outvoted by the majority
+func (rss *ReplicatedSecretSharing) RobustReconstruct(parties [][]Share) (int, []int, error) {
+	n_sh := combin.Binomial(rss.n, rss.t)
 	mapping := make(map[int][]int)
 	for _, party := range parties {
 		for _, sh := range party {
-			mapping[sh.Index] = append(mapping[sh.Index], sh.Value)
+			if sh.Index >= 0 && sh.Index < n_sh {
+				mapping[sh.Index] = append(mapping[sh.Index], sh.Value)
+			}
 		}
-
 	}
 
-	if len(mapping) != combin.Binomial(rss.n, rss.t) {
-		return 0, fmt.Errorf("reconstruct failed: missing shares")
+	if len(mapping) != n_sh {
+		return 0, nil, fmt.Errorf("%w: reconstruct failed: missing shares", ErrInvalidShares)
 	}
 
+	majority := make(map[int]int, n_sh)
 	result := 0
-	for _, val := range mapping {
+	for index, val := range mapping {
 		temp, err := findMajority(val, rss.t)
 		if err != nil {
-			return 0, err
+			return 0, nil, fmt.Errorf("share %d: %w", index, err)
+		}
+		majority[index] = temp
+		result = rss.field.AddInt(result, temp)
+	}
+
+	var cheaters []int
+	for i, party := range parties {
+		for _, sh := range party {
+			if value, ok := majority[sh.Index]; !ok || value != sh.Value {
+				cheaters = append(cheaters, i)
+				break
+			}
+		}
+	}
+
+	return result, cheaters, nil
+}
+
+// Recover returns the share with the given index held by a majority of the parties,
+// e.g. for a server that did not receive a valid share
+func (rss *ReplicatedSecretSharing) Recover(parties [][]Share, index int) (int, error) {
+	var values []int
+	for _, party := range parties {
+		for _, sh := range party {
+			if sh.Index == index {
+				values = append(values, sh.Value)
+			}
+		}
+	}
+
+	if len(values) == 0 {
+		return 0, fmt.Errorf("%w: recover failed: missing share %d", ErrInvalidShares, index)
+	}
+	return findMajority(values, rss.t)
+}
+
+// ToShamir converts the replicated shares of a party into its share of a Shamir sharing of the same secret,
+// the value at party+1 of a polynomial of degree t, without interaction (Cramer, Damgård and Ishai, TCC 2005).
+// The share not held by the parties of a combination A is weighted by the polynomial of degree t that is 1 at 0
+// and 0 at a+1 for every a in A.
+func (rss *ReplicatedSecretSharing) ToShamir(party int, shares []Share) (int, error) {
+	combinations := combin.Combinations(rss.n, rss.t)
+	values, err := rss.own(party, shares, combinations)
+	if err != nil {
+		return 0, err
+	}
+
+	x := uint64(party + 1)
+	result := uint64(0)
+	for index, value := range values {
+		// prod (a+1-x)/(a+1) over the parties a not holding the share
+		weight := uint64(1)
+		for _, a := range combinations[index] {
+			xa := uint64(a + 1)
+			weight = rss.field.Mul(weight, rss.field.Mul(rss.field.Sub(xa, x), rss.field.Inv(xa)))
+		}
+		result = rss.field.Add(result, rss.field.Mul(weight, rss.field.Reduce(value)))
+	}
+	return int(result), nil
+}
+
+// MulLocal returns the share of a party in an additive sharing of the product of two secrets, computed from its
+// replicated shares x and y of them without interaction. The product of a share of x and a share of y is added by
+// the first party holding both, which exists when n > 2t. Splitting the results of the parties again and adding up
+// the received shares gives replicated shares of the product.
+func (rss *ReplicatedSecretSharing) MulLocal(party int, x, y []Share) (int, error) {
+	groups, values, err := rss.MulGroups(party, x, y)
+	if err != nil {
+		return 0, err
+	}
+
+	result := 0
+	for g, group := range groups {
+		if group[0] == party {
+			result = rss.field.AddInt(result, values[g])
+		}
+	}
+	return result, nil
+}
+
+// MulGroups returns the products of the shares of x and y of a party grouped by the parties holding both shares of
+// a product: the groups of Groups the party belongs to, and for each of them the sum of the products of the shares
+// held by exactly its parties. Every party of a group computes the same sum, the sums of all groups add up to the
+// product of the secrets.
+func (rss *ReplicatedSecretSharing) MulGroups(party int, x, y []Share) ([][]int, []int, error) {
+	if 2*rss.t >= rss.n {
+		return nil, nil, fmt.Errorf("%w: multiplication needs n > 2t", ErrInvalidParams)
+	}
+
+	combinations := combin.Combinations(rss.n, rss.t)
+	xs, err := rss.own(party, x, combinations)
+	if err != nil {
+		return nil, nil, err
+	}
+	ys, err := rss.own(party, y, combinations)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var groups [][]int
+	position := make(map[string]int)
+	for _, group := range Groups(rss.n, rss.t) {
+		if contains(group, party) {
+			position[fmt.Sprint(group)] = len(groups)
+			groups = append(groups, group)
+		}
+	}
+
+	values := make([]uint64, len(groups))
+	for i, xi := range xs {
+		for j, yj := range ys {
+			g := position[fmt.Sprint(holders(rss.n, combinations[i], combinations[j]))]
+			values[g] = rss.field.Add(values[g], rss.field.Mul(rss.field.Reduce(xi), rss.field.Reduce(yj)))
+		}
+	}
+
+	result := make([]int, len(values))
+	for g, value := range values {
+		result[g] = int(value)
+	}
+	return groups, result, nil
+}
+
+// Groups returns the sets of parties holding both shares of a product of two shares of n parties, t of which do
+// not hold a share: the parties in neither of the two combinations of t parties, in ascending order. The groups
+// are sorted and have at least n-2t parties.
+func Groups(n, t int) [][]int {
+	combinations := combin.Combinations(n, t)
+	seen := make(map[string]bool)
+	var groups [][]int
+	for _, a := range combinations {
+		for _, b := range combinations {
+			group := holders(n, a, b)
+			if len(group) > 0 && !seen[fmt.Sprint(group)] {
+				seen[fmt.Sprint(group)] = true
+				groups = append(groups, group)
+			}
 		}
-		result += temp
 	}
 
-	result = mod(result, rss.q)
+	sort.Slice(groups, func(i, j int) bool {
+		a, b := groups[i], groups[j]
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	return groups
+}
+
+// AddShares adds up the replicated shares of a party of two secrets, which must have the same indices
+func (rss *ReplicatedSecretSharing) AddShares(a, b []Share) ([]Share, error) {
+	if len(a) != len(b) {
+		return nil, fmt.Errorf("%w: %d shares cannot be added to %d shares", ErrInvalidShares, len(b), len(a))
+	}
 
+	result := make([]Share, len(a))
+	for i := range a {
+		if a[i].Index != b[i].Index {
+			return nil, fmt.Errorf("%w: share %d cannot be added to share %d", ErrInvalidShares, b[i].Index, a[i].Index)
+		}
+		result[i] = Share{Index: a[i].Index, Value: rss.field.AddInt(a[i].Value, b[i].Value)}
+	}
 	return result, nil
+}
+
+// own maps the indices of the shares of a party to their values, the party must hold all its shares and no other
+func (rss *ReplicatedSecretSharing) own(party int, shares []Share, combinations [][]int) (map[int]int, error) {
+	if party < 0 || party >= rss.n {
+		return nil, fmt.Errorf("%w: party %d out of range", ErrInvalidShares, party)
+	}
 
+	values := make(map[int]int, len(shares))
+	for _, sh := range shares {
+		_, seen := values[sh.Index]
+		if sh.Index < 0 || sh.Index >= len(combinations) || contains(combinations[sh.Index], party) || seen {
+			return nil, fmt.Errorf("%w: party %d does not hold share %d", ErrInvalidShares, party, sh.Index)
+		}
+		values[sh.Index] = sh.Value
+	}
+
+	if len(values) != combin.Binomial(rss.n-1, rss.t) {
+		return nil, fmt.Errorf("%w: party %d misses shares", ErrInvalidShares, party)
+	}
+	return values, nil
+}
+
+// holders returns the parties not in a and not in b, which hold the shares not held by the parties of a and of b
+func holders(n int, a, b []int) []int {
+	var parties []int
+	for party := 0; party < n; party++ {
+		if !contains(a, party) && !contains(b, party) {
+			parties = append(parties, party)
+		}
+	}
+	return parties
 }
 
 func contains(slice []int, val int) bool {
@@ -141,10 +358,5 @@ func findMajority(list []int, t int) (int, error) {
 		return list[index], nil
 	}
 
-	return 0, fmt.Errorf("reconstruct failed: no majority element")
-}
-
-// mod computes a%b and a could be negative number
-func mod(a, b int) int {
-	return (a%b + b) % b
+	return 0, fmt.Errorf("%w: reconstruct failed: no majority element", ErrInvalidShares)
 }
@@ -1,7 +1,13 @@
 package rss
 
 import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
 	"testing"
+
+	"example.com/SMC/pkg/field"
 )
 
 func TestSplitReconstruct(t *testing.T) {
@@ -29,3 +35,312 @@ func TestSplitReconstruct(t *testing.T) {
 	}
 
 }
+
+func TestSplitReconstructLargeModulus(t *testing.T) {
+	// 2^61-1, sums of shares overflow int without reduction
+	q := 1<<61 - 1
+	secret := q - 5
+
+	rss, err := NewReplicatedSecretSharing(4, 1, q)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	_, parties, err := rss.Split(secret)
+	if err != nil {
+		t.Fatalf("Split(%v) failed with error %s", secret, err)
+	}
+
+	recon, err := rss.Reconstruct(parties)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if recon != secret {
+		t.Fatalf("reconstructed secrets do not match original secrets: %v %v", recon, secret)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	_, err := NewReplicatedSecretSharing(1, 2, 10631)
+	if !errors.Is(err, ErrInvalidParams) {
+		t.Fatalf("expected ErrInvalidParams for t > n, got %v", err)
+	}
+
+	rss, err := NewReplicatedSecretSharing(4, 1, 10631)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	_, parties, err := rss.Split(1)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// a single party misses shares, two parties disagreeing on a share have no majority
+	_, err = rss.Reconstruct(parties[:1])
+	if !errors.Is(err, ErrInvalidShares) {
+		t.Fatalf("expected ErrInvalidShares for missing shares, got %v", err)
+	}
+	parties[1][0].Value++
+	_, err = rss.Reconstruct(parties[:2])
+	if !errors.Is(err, ErrInvalidShares) {
+		t.Fatalf("expected ErrInvalidShares without majority, got %v", err)
+	}
+}
+
+func TestRobustReconstruct(t *testing.T) {
+	rss, err := NewReplicatedSecretSharing(7, 2, 10631)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	_, parties, err := rss.Split(5)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// every share is held by 5 parties, the ones of 2 lying parties are outvoted
+	parties[1][3].Value++
+	parties[4][0].Index = 1 << 20
+	secret, cheaters, err := rss.RobustReconstruct(parties)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if secret != 5 || !reflect.DeepEqual(cheaters, []int{1, 4}) {
+		t.Fatalf("expected secret 5 and cheaters [1 4], got %d and %v", secret, cheaters)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	rss, err := NewReplicatedSecretSharing(4, 1, 10631)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	shares, parties, err := rss.Split(1)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// the shares of party 0 are held by the other parties
+	for _, sh := range parties[0] {
+		value, err := rss.Recover(parties[1:], sh.Index)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if value != shares[sh.Index] {
+			t.Fatalf("recovered share %d does not match: %v %v", sh.Index, value, shares[sh.Index])
+		}
+	}
+
+	_, err = rss.Recover(parties, len(shares))
+	if !errors.Is(err, ErrInvalidShares) {
+		t.Fatalf("expected ErrInvalidShares for a missing share, got %v", err)
+	}
+}
+
+func TestToShamir(t *testing.T) {
+	q := 10631
+	rss, err := NewReplicatedSecretSharing(7, 2, q)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	_, parties, err := rss.Split(42)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	x := make([]uint64, len(parties))
+	y := make([]uint64, len(parties))
+	for i, party := range parties {
+		value, err := rss.ToShamir(i, party)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		x[i], y[i] = uint64(i+1), uint64(value)
+	}
+
+	// the converted shares lie on a polynomial of degree t through the secret
+	f, err := field.New(uint64(q))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, subset := range [][]int{{0, 1, 2}, {6, 3, 4}} {
+		xs := []uint64{x[subset[0]], x[subset[1]], x[subset[2]]}
+		w := f.LagrangeWeights(xs)
+		secret := uint64(0)
+		for i, b := range f.LagrangeBasis(xs, w, 0) {
+			secret = f.Add(secret, f.Mul(b, y[subset[i]]))
+		}
+		if secret != 42 {
+			t.Fatalf("parties %v reconstruct %d, expected 42", subset, secret)
+		}
+	}
+
+	// shares of another party or missing shares cannot be converted
+	_, err = rss.ToShamir(0, parties[1])
+	if !errors.Is(err, ErrInvalidShares) {
+		t.Fatalf("expected ErrInvalidShares for shares of another party, got %v", err)
+	}
+	_, err = rss.ToShamir(0, parties[0][1:])
+	if !errors.Is(err, ErrInvalidShares) {
+		t.Fatalf("expected ErrInvalidShares for missing shares, got %v", err)
+	}
+}
+
+func TestMul(t *testing.T) {
+	q := 10631
+	for _, params := range [][2]int{{4, 1}, {5, 2}, {7, 3}} {
+		n := params[0]
+		rss, err := NewReplicatedSecretSharing(n, params[1], q)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		x, y := rand.Intn(q), rand.Intn(q)
+		_, xs, err := rss.Split(x)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		_, ys, err := rss.Split(y)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// the local products are an additive sharing of the product
+		sum := 0
+		reshared := make([][][]Share, n)
+		for i := 0; i < n; i++ {
+			z, err := rss.MulLocal(i, xs[i], ys[i])
+			if err != nil {
+				t.Fatalf("n=%d: err: %v", n, err)
+			}
+			sum = (sum + z) % q
+			_, reshared[i], err = rss.Split(z)
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+		}
+		if sum != x*y%q {
+			t.Fatalf("n=%d: local products add up to %d, expected %d", n, sum, x*y%q)
+		}
+
+		// adding up the reshared local products gives replicated shares of the product
+		parties := make([][]Share, n)
+		for j := 0; j < n; j++ {
+			parties[j] = reshared[0][j]
+			for i := 1; i < n; i++ {
+				parties[j], err = rss.AddShares(parties[j], reshared[i][j])
+				if err != nil {
+					t.Fatalf("err: %v", err)
+				}
+			}
+		}
+		product, err := rss.Reconstruct(parties)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if product != x*y%q {
+			t.Fatalf("n=%d: reconstructed %d, expected %d", n, product, x*y%q)
+		}
+	}
+}
+
+func TestMulGroups(t *testing.T) {
+	q := 10631
+	for _, params := range [][2]int{{4, 1}, {5, 2}, {7, 2}} {
+		n, threshold := params[0], params[1]
+		rss, err := NewReplicatedSecretSharing(n, threshold, q)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		x, y := rand.Intn(q), rand.Intn(q)
+		_, xs, err := rss.Split(x)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		_, ys, err := rss.Split(y)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// every party of a group computes the same sum
+		sums := make(map[string]int)
+		for i := 0; i < n; i++ {
+			groups, values, err := rss.MulGroups(i, xs[i], ys[i])
+			if err != nil {
+				t.Fatalf("n=%d: err: %v", n, err)
+			}
+			for g, group := range groups {
+				if len(group) < n-2*threshold {
+					t.Fatalf("n=%d: group %v has less than n-2t parties", n, group)
+				}
+				key := fmt.Sprint(group)
+				if sum, ok := sums[key]; ok && sum != values[g] {
+					t.Fatalf("n=%d: parties of group %v disagree: %d and %d", n, group, sum, values[g])
+				}
+				sums[key] = values[g]
+			}
+		}
+
+		// the sums of all groups add up to the product
+		groups := Groups(n, threshold)
+		if len(sums) != len(groups) {
+			t.Fatalf("n=%d: parties computed %d groups, expected %d", n, len(sums), len(groups))
+		}
+		product := 0
+		for _, group := range groups {
+			product = (product + sums[fmt.Sprint(group)]) % q
+		}
+		if product != x*y%q {
+			t.Fatalf("n=%d: groups add up to %d, expected %d", n, product, x*y%q)
+		}
+	}
+}
+
+func TestMulErrors(t *testing.T) {
+	rss, err := NewReplicatedSecretSharing(4, 2, 10631)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	_, parties, err := rss.Split(3)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	_, err = rss.MulLocal(0, parties[0], parties[0])
+	if !errors.Is(err, ErrInvalidParams) {
+		t.Fatalf("expected ErrInvalidParams for n <= 2t, got %v", err)
+	}
+
+	rss, err = NewReplicatedSecretSharing(4, 1, 10631)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	_, parties, err = rss.Split(3)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	_, err = rss.MulLocal(0, parties[0], parties[1])
+	if !errors.Is(err, ErrInvalidShares) {
+		t.Fatalf("expected ErrInvalidShares for shares of another party, got %v", err)
+	}
+	_, err = rss.AddShares(parties[0], parties[1])
+	if !errors.Is(err, ErrInvalidShares) {
+		t.Fatalf("expected ErrInvalidShares for shares with other indices, got %v", err)
+	}
+}
+
+func TestSplitWithReader(t *testing.T) {
+	// readers with the same seed give the same shares
+	var shares [2][]int
+	for i := range shares {
+		rss, err := NewReplicatedSecretSharingWithReader(4, 1, 10631, rand.New(rand.NewSource(1)))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		shares[i], _, err = rss.Split(7)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	if !reflect.DeepEqual(shares[0], shares[1]) {
+		t.Fatalf("shares differ for the same reader: %v %v", shares[0], shares[1])
+	}
+}
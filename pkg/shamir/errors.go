@@ -0,0 +1,11 @@
+package shamir
+
+import "errors"
+
+// Errors of the package wrap one of the following, callers tell them apart with errors.Is
+var (
+	// ErrInvalidParams is returned for parameters a ShamirSecretSharing cannot be created with
+	ErrInvalidParams = errors.New("invalid parameters")
+	// ErrInvalidShares is returned for shares the secret cannot be reconstructed from
+	ErrInvalidShares = errors.New("invalid shares")
+)
@@ -0,0 +1,172 @@
+package shamir
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"sort"
+
+	"example.com/SMC/pkg/field"
+	"example.com/SMC/pkg/rss"
+)
+
+// ShamirSecretSharing splits a secret into one share per server: the share with index i is the value at
+// Point(i) of a random polynomial of degree t whose value at 0 is the secret. Unlike the replicated secret
+// sharing, the number of shares grows linearly with the number of servers.
+type ShamirSecretSharing struct {
+	n     int
+	t     int
+	q     int
+	field *field.Field
+	rand  io.Reader // source of the random coefficients
+}
+
+// Share is a share of a server, the same as of the replicated secret sharing so that servers handle both alike
+type Share = rss.Share
+
+// Point returns the evaluation point of the share with the given index
+func Point(index int) int {
+	return index + 1
+}
+
+func NewShamirSecretSharing(N, T, Q int) (*ShamirSecretSharing, error) {
+	return NewShamirSecretSharingWithReader(N, T, Q, rand.Reader)
+}
+
+// NewShamirSecretSharingWithReader creates a Shamir secret sharing that draws the random coefficients from r,
+// crypto/rand if r is nil. A deterministic r reproduces the shares, e.g. in tests; Split is then only safe for
+// concurrent use if r is.
+func NewShamirSecretSharingWithReader(N, T, Q int, r io.Reader) (*ShamirSecretSharing, error) {
+	if T >= N {
+		return nil, fmt.Errorf("%w: n has to be larger than t", ErrInvalidParams)
+	}
+
+	if T < 0 {
+		return nil, fmt.Errorf("%w: t cannot be negative", ErrInvalidParams)
+	}
+
+	f, err := field.NewInt(Q)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidParams, err)
+	}
+
+	// the points 1, ..., n have to be distinct and nonzero modulo q
+	if Q <= N {
+		return nil, fmt.Errorf("%w: q has to be a prime larger than n", ErrInvalidParams)
+	}
+
+	if r == nil {
+		r = rand.Reader
+	}
+
+	return &ShamirSecretSharing{n: N, t: T, q: Q, field: f, rand: r}, nil
+}
+
+// Split returns the shares of all servers and the share of each server
+func (s *ShamirSecretSharing) Split(secret int) ([]int, [][]Share, error) {
+	coeffs := make([]uint64, s.t+1)
+	coeffs[0] = s.field.Reduce(secret)
+	for i := 1; i <= s.t; i++ {
+		val, err := s.field.Rand(s.rand)
+		if err != nil {
+			return nil, nil, err
+		}
+		coeffs[i] = val
+	}
+
+	shares := make([]int, s.n)
+	parties := make([][]Share, s.n)
+	for i := range shares {
+		shares[i] = int(s.field.Eval(coeffs, uint64(Point(i))))
+		parties[i] = []Share{{Index: i, Value: shares[i]}}
+	}
+
+	return shares, parties, nil
+}
+
+// Reconstruct decodes the secret from the shares of the parties, correcting up to (n'-t-1)/2 wrong shares
+// of the n' distinct shares at hand
+func (s *ShamirSecretSharing) Reconstruct(parties [][]Share) (int, error) {
+	secret, _, err := s.RobustReconstruct(parties)
+	return secret, err
+}
+
+// RobustReconstruct decodes the secret from the shares of the parties with the Berlekamp-Welch algorithm and
+// returns it with the positions in parties of the parties holding a share that is not consistent with it
+func (s *ShamirSecretSharing) RobustReconstruct(parties [][]Share) (int, []int, error) {
+	coeffs, err := s.decode(parties)
+	if err != nil {
+		return 0, nil, err
+	}
+	return int(coeffs[0]), s.cheaters(parties, coeffs), nil
+}
+
+// Recover decodes the share with the given index from the shares of the parties,
+// e.g. for a server that did not receive a valid share
+func (s *ShamirSecretSharing) Recover(parties [][]Share, index int) (int, error) {
+	if index < 0 || index >= s.n {
+		return 0, fmt.Errorf("%w: share index %d out of range", ErrInvalidShares, index)
+	}
+	coeffs, err := s.decode(parties)
+	if err != nil {
+		return 0, err
+	}
+	return int(s.field.Eval(coeffs, uint64(Point(index)))), nil
+}
+
+// decode returns the coefficients of the polynomial of degree t the shares of the parties lie on,
+// shares out of range or with conflicting values for the same index are left out
+func (s *ShamirSecretSharing) decode(parties [][]Share) ([]uint64, error) {
+	values := make(map[int]uint64)
+	conflicting := make(map[int]bool)
+	for _, party := range parties {
+		for _, sh := range party {
+			if sh.Index < 0 || sh.Index >= s.n {
+				continue
+			}
+			value := s.field.Reduce(sh.Value)
+			if v, ok := values[sh.Index]; ok && v != value {
+				conflicting[sh.Index] = true
+			}
+			values[sh.Index] = value
+		}
+	}
+
+	indices := make([]int, 0, len(values))
+	for index := range values {
+		if !conflicting[index] {
+			indices = append(indices, index)
+		}
+	}
+	sort.Ints(indices)
+
+	if len(indices) < s.t+1 {
+		return nil, fmt.Errorf("%w: reconstruct failed: %d shares, at least %d required", ErrInvalidShares, len(indices), s.t+1)
+	}
+
+	x := make([]uint64, len(indices))
+	y := make([]uint64, len(indices))
+	for i, index := range indices {
+		x[i] = uint64(Point(index))
+		y[i] = values[index]
+	}
+	coeffs, _, err := s.field.BerlekampWelch(x, y, s.t+1)
+	if err != nil {
+		return nil, fmt.Errorf("%w: reconstruct failed: %w", ErrInvalidShares, err)
+	}
+	return coeffs, nil
+}
+
+// cheaters returns the positions of the parties holding a share out of range or off the polynomial
+func (s *ShamirSecretSharing) cheaters(parties [][]Share, coeffs []uint64) []int {
+	var result []int
+	for i, party := range parties {
+		for _, sh := range party {
+			if sh.Index < 0 || sh.Index >= s.n || s.field.Eval(coeffs, uint64(Point(sh.Index))) != s.field.Reduce(sh.Value) {
+				result = append(result, i)
+				break
+			}
+		}
+	}
+	return result
+}
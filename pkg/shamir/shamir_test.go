@@ -0,0 +1,171 @@
+package shamir
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"example.com/SMC/pkg/rss"
+)
+
+func TestSplitReconstruct(t *testing.T) {
+	for _, q := range []int{10631, 1<<61 - 1} {
+		secret := q - 5
+
+		sss, err := NewShamirSecretSharing(16, 5, q)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		shares, parties, err := sss.Split(secret)
+		if err != nil {
+			t.Fatalf("Split(%v) failed with error %s", secret, err)
+		}
+		if len(shares) != 16 || len(parties[3]) != 1 || parties[3][0].Index != 3 {
+			t.Fatalf("unexpected shares %v", parties)
+		}
+
+		// any t+1 shares reconstruct the secret
+		for _, subset := range [][][]Share{parties, parties[:6], parties[10:]} {
+			recon, err := sss.Reconstruct(subset)
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			if recon != secret {
+				t.Fatalf("reconstructed secrets do not match original secrets: %v %v", recon, secret)
+			}
+		}
+
+		recovered, err := sss.Recover(parties[:6], 12)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if recovered != shares[12] {
+			t.Fatalf("recovered share does not match: %v %v", recovered, shares[12])
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	for _, params := range [][3]int{{4, 4, 10631}, {4, -1, 10631}, {4, 1, 3}, {4, 1, 10632}} {
+		_, err := NewShamirSecretSharing(params[0], params[1], params[2])
+		if !errors.Is(err, ErrInvalidParams) {
+			t.Fatalf("expected ErrInvalidParams for %v, got %v", params, err)
+		}
+	}
+
+	sss, err := NewShamirSecretSharing(4, 1, 10631)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	_, parties, err := sss.Split(1)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// a single share does not determine the secret
+	_, err = sss.Reconstruct(parties[:1])
+	if !errors.Is(err, ErrInvalidShares) {
+		t.Fatalf("expected ErrInvalidShares for missing shares, got %v", err)
+	}
+
+	// two modified shares of four cannot be corrected
+	parties[2][0].Value++
+	parties[3][0].Value++
+	_, err = sss.Reconstruct(parties)
+	if !errors.Is(err, ErrInvalidShares) {
+		t.Fatalf("expected ErrInvalidShares for inconsistent shares, got %v", err)
+	}
+	_, err = sss.Recover(parties[:2], 4)
+	if !errors.Is(err, ErrInvalidShares) {
+		t.Fatalf("expected ErrInvalidShares for an invalid index, got %v", err)
+	}
+}
+
+func TestRobustReconstruct(t *testing.T) {
+	sss, err := NewShamirSecretSharingWithReader(16, 5, 10631, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	shares, parties, err := sss.Split(42)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// up to t wrong shares of 3t+1 are corrected and their parties named, as is a share out of range
+	for _, i := range []int{0, 4, 7, 11} {
+		parties[i][0].Value = (parties[i][0].Value + 1000) % 10631
+	}
+	parties[13][0].Index = 16
+	secret, cheaters, err := sss.RobustReconstruct(parties)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if secret != 42 || !reflect.DeepEqual(cheaters, []int{0, 4, 7, 11, 13}) {
+		t.Fatalf("expected secret 42 and cheaters [0 4 7 11 13], got %d and %v", secret, cheaters)
+	}
+
+	share, err := sss.Recover(parties, 4)
+	if err != nil || share != shares[4] {
+		t.Fatalf("expected share %d, got %d (%v)", shares[4], share, err)
+	}
+
+	// two parties sending the same index disagree, neither is used to decode, which leaves 13 shares
+	// correcting 3 errors
+	parties[11][0].Value = shares[11]
+	parties[14][0].Index = 15
+	_, cheaters, err = sss.RobustReconstruct(parties)
+	if err != nil || !reflect.DeepEqual(cheaters, []int{0, 4, 7, 13, 14}) {
+		t.Fatalf("expected cheaters [0 4 7 13 14], got %v (%v)", cheaters, err)
+	}
+}
+
+func TestReconstructConvertedShares(t *testing.T) {
+	nrss, err := rss.NewReplicatedSecretSharing(7, 2, 10631)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	sss, err := NewShamirSecretSharing(7, 2, 10631)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	_, replicated, err := nrss.Split(42)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// the share of party i converted from its replicated shares is the share with index i
+	parties := make([][]Share, len(replicated))
+	for i, party := range replicated {
+		value, err := nrss.ToShamir(i, party)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		parties[i] = []Share{{Index: i, Value: value}}
+	}
+	parties[5][0].Value++
+
+	secret, cheaters, err := sss.RobustReconstruct(parties)
+	if err != nil || secret != 42 || !reflect.DeepEqual(cheaters, []int{5}) {
+		t.Fatalf("expected secret 42 and cheaters [5], got %d and %v (%v)", secret, cheaters, err)
+	}
+}
+
+func TestSplitWithReader(t *testing.T) {
+	// readers with the same seed give the same shares
+	var shares [2][]int
+	for i := range shares {
+		sss, err := NewShamirSecretSharingWithReader(7, 2, 10631, rand.New(rand.NewSource(1)))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		shares[i], _, err = sss.Split(7)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	if !reflect.DeepEqual(shares[0], shares[1]) {
+		t.Fatalf("shares differ for the same reader: %v %v", shares[0], shares[1])
+	}
+}
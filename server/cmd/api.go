@@ -3,9 +3,12 @@ package main
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
+	"math"
 	"time"
 
+	"example.com/SMC/pkg/dp"
 	"example.com/SMC/pkg/ligero"
 	"example.com/SMC/server/config"
 	"example.com/SMC/server/sqlstore"
@@ -39,6 +42,11 @@ func NewExperimentService(db *sqlstore.DB) *ExperimentService {
 }
 
 func (c *ClientService) CreateClientShare(request ClientRequest, cfg *config.Server) error {
+	err := checkParty(request.Proof.Shares, cfg)
+	if err != nil {
+		return err
+	}
+
 	exp, err := c.db.GetExperiment(request.Exp_ID)
 	if err != nil {
 		return err
@@ -62,7 +70,7 @@ func (c *ClientService) CreateClientShare(request ClientRequest, cfg *config.Ser
 	}
 
 	//insert share to client share table
-	shares, err := json.Marshal(Shares{Index: request.Proof.Shares.Index, Values: request.Proof.Shares.Values})
+	shares, err := json.Marshal(Shares{Index: request.Proof.Shares.Index, Values: request.Proof.Shares.Values, PartyIndex: cfg.Share_Index})
 	if err != nil {
 		return err
 	}
@@ -72,61 +80,139 @@ func (c *ClientService) CreateClientShare(request ClientRequest, cfg *config.Ser
 		return err
 	}
 
-	zk, err := ligero.NewLigeroZK(cfg.N_secrets, cfg.M, cfg.N, cfg.T, cfg.Q, cfg.N_open)
+	return nil
+}
+
+// checkParty fails unless the shares of a proof are the ones of the party of the server, the proof of the shares of
+// another party verifies as well but its shares would be converted and aggregated as the server's
+func checkParty(shares ligero.Shares, cfg *config.Server) error {
+	if shares.PartyIndex != cfg.Share_Index {
+		return fmt.Errorf("shares of party %d sent to party %d", shares.PartyIndex, cfg.Share_Index)
+	}
+	if !equal(shares.Index, ligero.ShareIndices(cfg.Sharing, cfg.N, cfg.T, cfg.Share_Index)) {
+		return fmt.Errorf("shares %v are not the shares of party %d", shares.Index, cfg.Share_Index)
+	}
+	return nil
+}
+
+// equal reports whether two share indices are the same
+func equal(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// NewVerifier creates the verifier of the client proofs of an experiment
+func (c *ClientService) NewVerifier(exp_id string, cfg *config.Server) (*ligero.LigeroZK, error) {
+	exp, err := c.db.GetExperiment(exp_id)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	if *exp == (sqlstore.Experiment{}) {
+		return nil, errors.New("experiment does not exist when server creates verifier")
+	}
+
+	pred, err := ligero.ParsePredicate(exp.Predicate)
+	if err != nil {
+		return nil, err
+	}
+
+	return ligero.NewLigeroZKFromParams(cfg.LigeroParams(), pred)
+}
+
+// VerifyClientShares verifies the proofs of client requests of an experiment as a batch and creates a complaint
+// record for each of them
+func (c *ClientService) VerifyClientShares(exp_id string, requests []ClientRequest, zk *ligero.LigeroZK, cfg *config.Server) error {
+	ctxs := make([]ligero.Context, len(requests))
+	proofs := make([]ligero.Proof, len(requests))
+	for i, request := range requests {
+		//the proof has to be bound to the experiment and client the request claims
+		ctxs[i] = ligero.Context{Exp_ID: request.Exp_ID, Client_ID: request.Client_ID}
+		proofs[i] = request.Proof
 	}
 
 	proof_verify_start := time.Now() //proof verification start time
-	verify, err := zk.VerifyProof(request.Proof)
+	verified, errs := zk.VerifyBatch(ctxs, proofs)
 	proof_verify_end := time.Since(proof_verify_start) //proof verification computing time
 	logger.WithFields(logrus.Fields{
-		"exp_id":      request.Exp_ID,
-		"client_id":   request.Client_ID,
+		"exp_id":      exp_id,
+		"batch_size":  len(requests),
 		"verify_time": proof_verify_end.String(),
-		"is_verified": verify,
 	}).Info("")
 	total_verify_time += proof_verify_end
-	//creat complaint record based on proof verification result
-	if !verify {
-		log.Printf("%s failed to verify %s proof for %s -- %s\n", cfg.Server_ID, request.Client_ID, request.Exp_ID, err)
 
-		/**
-		//test s6 should complaint but not complaint
-		if cfg.Server_ID == "s6"{
-			_ = c.db.InsertComplaint(request.Exp_ID, cfg.Server_ID, request.Client_ID, false, request.Proof.MerkleRoot)
+	//creat complaint record based on proof verification result
+	for i, request := range requests {
+		logger.WithFields(logrus.Fields{
+			"exp_id":      exp_id,
+			"client_id":   request.Client_ID,
+			"is_verified": verified[i],
+		}).Info("")
+
+		if !verified[i] {
+			log.Printf("%s failed to verify %s proof for %s -- %s\n", cfg.Server_ID, request.Client_ID, exp_id, errs[i])
+
+			/**
+			//test s6 should complaint but not complaint
+			if cfg.Server_ID == "s6"{
+				_ = c.db.InsertComplaint(request.Exp_ID, cfg.Server_ID, request.Client_ID, false, request.Proof.MerkleRoot)
+			} else {
+				err = c.db.InsertComplaint(request.Exp_ID, cfg.Server_ID, request.Client_ID, true, request.Proof.MerkleRoot)
+				if err != nil {
+					panic(err)
+				}
+			}**/
 		} else {
-			err = c.db.InsertComplaint(request.Exp_ID, cfg.Server_ID, request.Client_ID, true, request.Proof.MerkleRoot)
-			if err != nil {
-				panic(err)
-			}
-		}**/
+			log.Printf("%s succeed to verify %s proof for %s\n", cfg.Server_ID, request.Client_ID, exp_id)
+
+			/**
+			//test s6 should not complaint but complaint
+			if cfg.Server_ID == "s6" && request.Client_ID == "c1" {
+				_ = c.db.InsertComplaint(request.Exp_ID, cfg.Server_ID, request.Client_ID, true, request.Proof.MerkleRoot)
+			} else {
+				err = c.db.InsertComplaint(request.Exp_ID, cfg.Server_ID, request.Client_ID, false, request.Proof.MerkleRoot)
+				if err != nil {
+					panic(err)
+				}
+			}**/
+		}
 
-		err = c.db.InsertComplaint(request.Exp_ID, cfg.Server_ID, request.Client_ID, true, request.Proof.MerkleRoot)
+		err := c.db.InsertComplaint(exp_id, cfg.Server_ID, request.Client_ID, !verified[i], request.Proof.MerkleRoot)
 		if err != nil {
 			return err
 		}
-	} else {
-		log.Printf("%s succeed to verify %s proof for %s\n", cfg.Server_ID, request.Client_ID, request.Exp_ID)
+	}
 
-		/**
-		//test s6 should not complaint but complaint
-		if cfg.Server_ID == "s6" && request.Client_ID == "c1" {
-			_ = c.db.InsertComplaint(request.Exp_ID, cfg.Server_ID, request.Client_ID, true, request.Proof.MerkleRoot)
-		} else {
-			err = c.db.InsertComplaint(request.Exp_ID, cfg.Server_ID, request.Client_ID, false, request.Proof.MerkleRoot)
-			if err != nil {
-				panic(err)
-			}
-		}**/
+	return nil
+}
 
-		err = c.db.InsertComplaint(request.Exp_ID, cfg.Server_ID, request.Client_ID, false, request.Proof.MerkleRoot)
-		if err != nil {
-			return err
-		}
+// CheckPredicate checks that a client proves the predicate of the experiment, a proof of another predicate
+// would only fail verification later
+func (c *ClientService) CheckPredicate(request ClientRequest) error {
+	exp, err := c.db.GetExperiment(request.Exp_ID)
+	if err != nil {
+		return err
+	}
 
+	if *exp == (sqlstore.Experiment{}) {
+		return fmt.Errorf("experiment %s does not exist", request.Exp_ID)
 	}
 
+	expected, err := ligero.ParsePredicate(exp.Predicate)
+	if err != nil {
+		return err
+	}
+	pred, err := ligero.ParsePredicate(request.Predicate)
+	if err != nil || pred.String() != expected.String() {
+		return fmt.Errorf("client proves predicate %q, experiment %s has predicate %q", request.Predicate, request.Exp_ID, expected.String())
+	}
 	return nil
 }
 
@@ -172,6 +258,34 @@ func (s *ServerService) CreateMaskedShares(request MaskedShareRequest) error {
 
 }
 
+func (s *ServerService) CreateProductShares(request ProductShareRequest) error {
+	exp, err := s.db.GetExperiment(request.Exp_ID)
+	if err != nil {
+		return err
+	}
+	if *exp == (sqlstore.Experiment{}) {
+		return errors.New("experiment does not exist when create other servers' product shares")
+	}
+
+	log.Printf("server received product shares from %s\n", request.Server_ID)
+	shares, err := json.Marshal(request.Groups)
+	if err != nil {
+		return err
+	}
+	noise, err := json.Marshal(request.Noise)
+	if err != nil {
+		return err
+	}
+
+	err = s.db.InsertProductShare(request.Exp_ID, request.Server_ID, request.Party, shares, noise)
+	if err != nil {
+		return err
+	}
+
+	return nil
+
+}
+
 func (s *ServerService) CreateValidClient(exp_id, client_id string) error {
 	exp, err := s.db.GetExperiment(exp_id)
 	if err != nil {
@@ -190,12 +304,137 @@ func (s *ServerService) CreateValidClient(exp_id, client_id string) error {
 
 }
 
-func (e *ExperimentService) CreateExperiment(request Experiment) error {
-	err := e.db.InsertExperiment(request.Exp_ID, request.ClientShareDue, request.ComplaintDue, request.ShareBroadcastDue, request.Owner)
+func (e *ExperimentService) CreateExperiment(request Experiment, cfg *config.Server) error {
+	pred, err := ligero.ParsePredicate(request.Predicate)
+	if err != nil {
+		return err
+	}
+
+	//products are computed on replicated shares, every product of two shares is held by a group of servers, an
+	//honest one of which checks the sums of the products reshared by the others
+	if len(request.Products) > 0 {
+		if cfg.Sharing != ligero.SharingRSS && cfg.Sharing != "" {
+			return fmt.Errorf("products need %s sharing", ligero.SharingRSS)
+		}
+		if 3*cfg.T >= cfg.N {
+			return fmt.Errorf("products need n > 3t, got n=%d t=%d", cfg.N, cfg.T)
+		}
+		if len(cfg.Product_share_urls) != cfg.N {
+			return fmt.Errorf("products need %d product share urls, got %d", cfg.N, len(cfg.Product_share_urls))
+		}
+	}
+	for _, pair := range request.Products {
+		for _, input_index := range pair {
+			if input_index < 0 || input_index >= cfg.N_secrets {
+				return fmt.Errorf("product of input %d out of range", input_index)
+			}
+		}
+	}
+	products, err := json.Marshal(request.Products)
+	if err != nil {
+		return err
+	}
+
+	//every server shares its noise among all servers, which add the shares of the noise of the at least n-t servers
+	//that reshared by the reshare due to their aggregated shares
+	if request.Noise != nil {
+		err = request.Noise.Validate()
+		if err != nil {
+			return err
+		}
+		if 2*cfg.T >= cfg.N {
+			return fmt.Errorf("noise needs n > 2t, got n=%d t=%d", cfg.N, cfg.T)
+		}
+		//noise of a smaller sensitivity than a single client can cause does not give the privacy it is debited for
+		sensitivity, err := minSensitivity(pred, cfg.N_secrets, request.Products, request.Noise.Mechanism)
+		if err != nil {
+			return err
+		}
+		if request.Noise.Sensitivity < sensitivity*(1-1e-9) {
+			return fmt.Errorf("sensitivity %g is below %g, the change of the sums by a single client with predicate %q", request.Noise.Sensitivity, sensitivity, request.Predicate)
+		}
+		if len(cfg.Product_share_urls) != cfg.N {
+			return fmt.Errorf("noise needs %d product share urls, got %d", cfg.N, len(cfg.Product_share_urls))
+		}
+	}
+	if len(request.Products) > 0 || request.Noise != nil {
+		share_broadcast_due, _ := time.Parse("2006-01-02 15:04:05.999999999 +0000 UTC", request.ShareBroadcastDue)
+		reshare_due, err := time.Parse("2006-01-02 15:04:05.999999999 +0000 UTC", request.ReshareDue)
+		if err != nil || !reshare_due.After(share_broadcast_due) {
+			return fmt.Errorf("products and noise need a reshare due after the share broadcast due, got %q", request.ReshareDue)
+		}
+	}
+	noise, err := json.Marshal(request.Noise)
+	if err != nil {
+		return err
+	}
+
+	//the privacy loss of the experiment is debited from the budget of its population, exact sums have no bounded loss
+	if request.Noise == nil && cfg.Epsilon_budget > 0 {
+		return fmt.Errorf("experiments on %q need noise to fit into the privacy budget", request.Population)
+	}
+	if request.Noise != nil {
+		max_epsilon, max_delta := budget(cfg)
+		err = e.db.DebitPrivacyBudget(request.Population, request.Exp_ID, request.Noise.Epsilon, request.Noise.Delta, max_epsilon, max_delta)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = e.db.InsertExperiment(request.Exp_ID, request.ClientShareDue, request.ComplaintDue, request.ShareBroadcastDue, request.ReshareDue, request.Owner, request.Predicate, string(products), string(noise))
 
 	if err != nil {
+		if request.Noise != nil {
+			//the budget is not spent on an experiment that does not exist
+			refund_err := e.db.DeletePrivacyDebit(request.Population, request.Exp_ID)
+			if refund_err != nil {
+				log.Printf("cannot refund the privacy debit of %s: %s\n", request.Exp_ID, refund_err)
+			}
+		}
 		return err
 	}
 
 	return nil
 }
+
+// minSensitivity returns the largest change of the sums of the inputs and products and of the number of valid clients
+// by a single client whose input satisfies the predicate, in the L2 norm for the gaussian and in the L1 norm for the
+// laplace mechanism
+func minSensitivity(pred ligero.Predicate, n_secrets int, pairs [][2]int, mechanism string) (float64, error) {
+	max_abs, l1, l2, ok := ligero.Norms(pred, n_secrets)
+	if !ok {
+		return 0, fmt.Errorf("noise needs a predicate bounding the inputs, got %q", pred)
+	}
+	product := max_abs * max_abs
+	if mechanism == dp.MechanismGaussian {
+		return math.Sqrt(l2*l2 + float64(len(pairs))*product*product + 1), nil
+	}
+	return l1 + float64(len(pairs))*product + 1, nil
+}
+
+// PrivacyBudgets returns the privacy loss and the remaining budget of every population experiments were run on
+func (e *ExperimentService) PrivacyBudgets(cfg *config.Server) ([]PrivacyBudget, error) {
+	spent, err := e.db.GetPrivacySpent()
+	if err != nil {
+		return nil, err
+	}
+
+	max_epsilon, max_delta := budget(cfg)
+	budgets := make([]PrivacyBudget, len(spent))
+	for i, p := range spent {
+		budgets[i] = PrivacyBudget{Population: p.Population, Experiments: p.Experiments, Epsilon_spent: p.Epsilon, Delta_spent: p.Delta}
+		if cfg.Epsilon_budget > 0 {
+			epsilon, delta := math.Max(max_epsilon-p.Epsilon, 0), math.Max(max_delta-p.Delta, 0)
+			budgets[i].Epsilon_remaining, budgets[i].Delta_remaining = &epsilon, &delta
+		}
+	}
+	return budgets, nil
+}
+
+// budget returns the privacy budget of every population, unlimited without an epsilon budget
+func budget(cfg *config.Server) (float64, float64) {
+	if cfg.Epsilon_budget > 0 {
+		return cfg.Epsilon_budget, cfg.Delta_budget
+	}
+	return math.Inf(1), math.Inf(1)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"example.com/SMC/pkg/dp"
+	"example.com/SMC/pkg/ligero"
+	"example.com/SMC/server/config"
+)
+
+func TestMinSensitivity(t *testing.T) {
+	for _, test := range []struct {
+		predicate string
+		pairs     [][2]int
+		mechanism string
+		expected  float64
+	}{
+		{"onehot", nil, dp.MechanismLaplace, 2},
+		{"onehot", nil, dp.MechanismGaussian, math.Sqrt(2)},
+		{"bit", [][2]int{{0, 1}, {1, 1}}, dp.MechanismLaplace, 4 + 2 + 1},
+		{"range:2", [][2]int{{0, 1}}, dp.MechanismGaussian, math.Sqrt(4*9 + 81 + 1)},
+	} {
+		pred, err := ligero.ParsePredicate(test.predicate)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		sensitivity, err := minSensitivity(pred, 4, test.pairs, test.mechanism)
+		if err != nil || math.Abs(sensitivity-test.expected) > 1e-9 {
+			t.Fatalf("%s %v %s: expected %v, but got %v (%v)", test.predicate, test.pairs, test.mechanism, test.expected, sensitivity, err)
+		}
+	}
+
+	// inputs that are not bounded cannot be noised
+	pred, err := ligero.ParsePredicate("product:0:1:2")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	_, err = minSensitivity(pred, 4, nil, dp.MechanismLaplace)
+	if err == nil {
+		t.Fatalf("sensitivity of unbounded inputs")
+	}
+}
+
+func TestCreateClientShareOfAnotherParty(t *testing.T) {
+	pred, err := ligero.ParsePredicate("bit")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	for _, sharing := range []string{ligero.SharingRSS, ligero.SharingShamir} {
+		cfg := &config.Server{Server_ID: "s1", Share_Index: 0, N: 4, T: 1, Q: 10631, N_secrets: 3, M: 1, N_open: 3, Sharing: sharing}
+		zk, err := ligero.NewLigeroZKFromParams(cfg.LigeroParams(), pred)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		ctx := ligero.Context{Exp_ID: "exp1", Client_ID: "c1"}
+		proofs, err := zk.GenerateProof(ctx, []int{1, 0, 1})
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// the proof for party 1 is fully consistent, but not the proof for the shares of the server
+		verify, err := zk.VerifyProof(ctx, *proofs[1])
+		if !verify {
+			t.Fatalf("%s: verification failed: %v", sharing, err)
+		}
+		request := ClientRequest{Exp_ID: "exp1", Client_ID: "c1", Proof: *proofs[1]}
+		if NewClientService(nil).CreateClientShare(request, cfg) == nil {
+			t.Fatalf("%s: server of party 0 accepted the shares of party 1", sharing)
+		}
+		relabeled := proofs[1].Shares
+		relabeled.PartyIndex = 0
+		if checkParty(relabeled, cfg) == nil {
+			t.Fatalf("%s: server of party 0 accepted the shares of party 1 relabeled as party 0", sharing)
+		}
+
+		if err := checkParty(proofs[0].Shares, cfg); err != nil {
+			t.Fatalf("%s: err: %v", sharing, err)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"example.com/SMC/pkg/ligero"
+)
+
+// clientBatches accumulates the stored client requests of each experiment until Batch_size of them are
+// verified together. The verifier of an experiment is immutable and shared by the batches of all handlers.
+// Requests are ingested after the response to the client, the batches of an experiment are closed at the
+// client share due once the requests in flight are pending, so that none of them is left unverified.
+type clientBatches struct {
+	mu        sync.Mutex
+	idle      *sync.Cond // signalled when the last request in flight of an experiment is ingested
+	pending   map[string][]ClientRequest
+	inflight  map[string]int
+	closed    map[string]bool
+	verifiers map[string]*ligero.LigeroZK
+}
+
+func newClientBatches() *clientBatches {
+	b := &clientBatches{
+		pending:   make(map[string][]ClientRequest),
+		inflight:  make(map[string]int),
+		closed:    make(map[string]bool),
+		verifiers: make(map[string]*ligero.LigeroZK),
+	}
+	b.idle = sync.NewCond(&b.mu)
+	return b
+}
+
+// begin registers a request of an experiment in flight, it returns false once the batches are closed
+func (b *clientBatches) begin(exp_id string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed[exp_id] {
+		return false
+	}
+	b.inflight[exp_id]++
+	return true
+}
+
+// end marks a request registered by begin as ingested, pending or dropped
+func (b *clientBatches) end(exp_id string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.inflight[exp_id]--
+	if b.inflight[exp_id] <= 0 {
+		delete(b.inflight, exp_id)
+		b.idle.Broadcast()
+	}
+}
+
+// close refuses further requests of an experiment and waits for the requests in flight
+func (b *clientBatches) close(exp_id string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed[exp_id] = true
+	for b.inflight[exp_id] > 0 {
+		b.idle.Wait()
+	}
+}
+
+// add appends a request to the batch of its experiment and returns the number of pending requests
+func (b *clientBatches) add(request ClientRequest) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.pending[request.Exp_ID] = append(b.pending[request.Exp_ID], request)
+	return len(b.pending[request.Exp_ID])
+}
+
+// take removes and returns the pending requests of an experiment
+func (b *clientBatches) take(exp_id string) []ClientRequest {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	requests := b.pending[exp_id]
+	delete(b.pending, exp_id)
+	return requests
+}
+
+// verifier returns the verifier of an experiment, created by create on first use
+func (b *clientBatches) verifier(exp_id string, create func() (*ligero.LigeroZK, error)) (*ligero.LigeroZK, error) {
+	b.mu.Lock()
+	zk, ok := b.verifiers[exp_id]
+	b.mu.Unlock()
+	if ok {
+		return zk, nil
+	}
+
+	// created without holding the lock, a verifier created meanwhile by another handler is kept
+	zk, err := create()
+	if err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if existing, ok := b.verifiers[exp_id]; ok {
+		return existing, nil
+	}
+	b.verifiers[exp_id] = zk
+	return zk, nil
+}
+
+// forget drops the verifier of an experiment
+func (b *clientBatches) forget(exp_id string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.verifiers, exp_id)
+}
+
+// batchSize returns the number of client requests verified together, at least 1
+func (s *Server) batchSize() int {
+	if s.cfg.Batch_size < 1 {
+		return 1
+	}
+	return s.cfg.Batch_size
+}
+
+// ingestClientShare stores a client request registered by batches.begin and verifies the batch of its
+// experiment once it is full or holds the last clients of the experiment
+func (s *Server) ingestClientShare(request ClientRequest) {
+	defer s.batches.end(request.Exp_ID)
+
+	clientService := NewClientService(s.store)
+	err := clientService.CreateClientShare(request, s.cfg)
+	if err != nil {
+		log.Printf("%s cannot create client share - error: %s\n", s.cfg.Server_ID, err)
+		return
+	}
+
+	pending := s.batches.add(request)
+	verified := int(s.store.CountComplaintsPerExperiment(request.Exp_ID))
+	if pending >= s.batchSize() || verified+pending >= client_size {
+		s.flushClientShares(request.Exp_ID)
+	}
+}
+
+// flushClientShares verifies the pending client requests of an experiment and writes their complaints
+func (s *Server) flushClientShares(exp_id string) {
+	requests := s.batches.take(exp_id)
+	if len(requests) == 0 {
+		return
+	}
+
+	clientService := NewClientService(s.store)
+	zk, err := s.batches.verifier(exp_id, func() (*ligero.LigeroZK, error) {
+		return clientService.NewVerifier(exp_id, s.cfg)
+	})
+	if err != nil {
+		log.Printf("%s cannot create verifier for %s - error: %s\n", s.cfg.Server_ID, exp_id, err)
+		return
+	}
+
+	err = clientService.VerifyClientShares(exp_id, requests, zk, s.cfg)
+	if err != nil {
+		log.Printf("%s cannot verify client shares - error: %s\n", s.cfg.Server_ID, err)
+	}
+
+	count := s.store.CountComplaintsPerExperiment(exp_id)
+	if int(count) == client_size {
+		real_client_share_due = time.Now().UTC() // time to start the step of assemble complaints and broadcast without waiting
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCloseWaitsForStragglers(t *testing.T) {
+	b := newClientBatches()
+
+	// a request answered before the due is still being stored when the due passes
+	if !b.begin("exp1") {
+		t.Fatalf("request refused before the due")
+	}
+
+	closed := make(chan []ClientRequest)
+	go func() {
+		b.close("exp1")
+		closed <- b.take("exp1")
+	}()
+
+	select {
+	case <-closed:
+		t.Fatalf("batches closed with a request in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	b.add(ClientRequest{Exp_ID: "exp1", Client_ID: "c1"})
+	b.end("exp1")
+
+	select {
+	case requests := <-closed:
+		if len(requests) != 1 || requests[0].Client_ID != "c1" {
+			t.Fatalf("expected the straggler to be flushed, got %v", requests)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("batches not closed after the request was stored")
+	}
+
+	// later requests are refused instead of staying pending
+	if b.begin("exp1") {
+		t.Fatalf("request accepted after the batches were closed")
+	}
+	if !b.begin("exp2") {
+		t.Fatalf("request of another experiment refused")
+	}
+}
+
+func TestCloseWithoutRequests(t *testing.T) {
+	b := newClientBatches()
+
+	// a request dropped before it was pending does not hold the batches open
+	if !b.begin("exp1") {
+		t.Fatalf("request refused before the due")
+	}
+	b.end("exp1")
+
+	done := make(chan struct{})
+	go func() {
+		b.close("exp1")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("batches not closed without requests in flight")
+	}
+	if requests := b.take("exp1"); len(requests) != 0 {
+		t.Fatalf("expected no pending requests, got %v", requests)
+	}
+}
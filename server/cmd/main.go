@@ -78,14 +78,19 @@ func main() {
 		"T":                       conf.T,
 		"Q":                       conf.Q,
 		"N_secrets":               conf.N_secrets,
+		"Sharing":                 conf.Sharing,
 		"M":                       conf.M,
 		"N_open":                  conf.N_open,
+		"Batch_size":              conf.Batch_size,
 		"Cert_path":               conf.Cert_path,
 		"Key_path":                conf.Key_path,
 		"Complaint_URLs":          conf.Complaint_urls,
 		"Masked_share_urls":       conf.Masked_share_urls,
 		"Dolev_complaint_urls":    conf.Dolev_complaint_urls,
 		"Dolev_masked_share_urls": conf.Dolev_masked_share_urls,
+		"Epsilon_budget":          conf.Epsilon_budget,
+		"Delta_budget":            conf.Delta_budget,
+		"Operator_addr":           conf.Operator_addr,
 	}).Info("")
 
 	s := NewServer(conf)
@@ -0,0 +1,483 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+
+	"example.com/SMC/pkg/dp"
+	"example.com/SMC/pkg/ligero"
+	"example.com/SMC/pkg/rss"
+	"example.com/SMC/server/sqlstore"
+)
+
+// productPairs returns the pairs of inputs whose products are summed in an experiment
+func productPairs(exp sqlstore.Experiment) ([][2]int, error) {
+	if exp.Products == "" {
+		return nil, nil
+	}
+	var pairs [][2]int
+	err := json.Unmarshal([]byte(exp.Products), &pairs)
+	if err != nil {
+		return nil, err
+	}
+	return pairs, nil
+}
+
+// productSharesHandler stores the shares reshared by another server before it answers, so that malformed or
+// unknown shares are refused
+func (s *Server) productSharesHandler(rw http.ResponseWriter, req *http.Request) {
+	var request ProductShareRequest
+
+	data, err := request.ReadJson(req)
+	if err != nil {
+		log.Printf("%s cannot read product share request - error: %s\n", s.cfg.Server_ID, err)
+		rw.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(rw, err)
+		return
+	}
+
+	err = NewServerService(s.store).CreateProductShares(data)
+	if err != nil {
+		log.Printf("error: %s\n", err)
+		rw.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(rw, err)
+		return
+	}
+	rw.WriteHeader(http.StatusOK)
+}
+
+// noiseParams returns the parameters of the noise added to the sums of an experiment, nil for none
+func noiseParams(exp sqlstore.Experiment) (*dp.Params, error) {
+	if exp.Noise == "" {
+		return nil, nil
+	}
+	var noise *dp.Params
+	err := json.Unmarshal([]byte(exp.Noise), &noise)
+	if err != nil {
+		return nil, err
+	}
+	return noise, nil
+}
+
+// reshare computes the sums over the valid clients of the products of every pair of inputs held by each group of
+// servers the server belongs to and draws the noise of the server for every sum, splits them into shares and sends
+// the shares of every server to it. The other servers of a group reshare the same sums, which lets the receivers
+// check them.
+func (s *Server) reshare(exp sqlstore.Experiment, pairs [][2]int, noise *dp.Params) error {
+	var nrss *rss.ReplicatedSecretSharing
+	if len(pairs) > 0 {
+		var ok bool
+		nrss, ok = s.sharing.(*rss.ReplicatedSecretSharing)
+		if !ok {
+			return errors.New("products need replicated secret sharing")
+		}
+	}
+
+	clientShares, err := s.store.GetValidClientShares(exp.Exp_ID)
+	if err != nil {
+		return err
+	}
+	if len(clientShares) == 0 {
+		return fmt.Errorf("client shares are empty: no valid client exists")
+	}
+
+	party := s.cfg.Share_Index
+	var groups [][]int
+	var local [][]int //sums of the products of every pair held by every group
+	for _, record := range clientShares {
+		var shares Shares
+		err = json.Unmarshal(record.Shares, &shares)
+		if err != nil {
+			return fmt.Errorf("cannot unmarshall %s shares: %w", record.Client_ID, err)
+		}
+		if len(pairs) == 0 {
+			break
+		}
+
+		for k, pair := range pairs {
+			if pair[0] >= len(shares.Values) || pair[1] >= len(shares.Values) {
+				return fmt.Errorf("%s has no input %d or %d", record.Client_ID, pair[0], pair[1])
+			}
+			if len(shares.Values[pair[0]]) != len(shares.Index) || len(shares.Values[pair[1]]) != len(shares.Index) {
+				return fmt.Errorf("%s has %d shares, expected %d", record.Client_ID, len(shares.Values[pair[0]]), len(shares.Index))
+			}
+			holders, values, err := nrss.MulGroups(party, rssShares(shares.Index, shares.Values[pair[0]]), rssShares(shares.Index, shares.Values[pair[1]]))
+			if err != nil {
+				return fmt.Errorf("%s: %w", record.Client_ID, err)
+			}
+			if local == nil {
+				groups, local = holders, make([][]int, len(holders))
+				for g := range local {
+					local[g] = make([]int, len(pairs))
+				}
+			}
+			for g, value := range values {
+				local[g][k] = s.field.AddInt(local[g][k], value)
+			}
+		}
+	}
+
+	//the noise of every input is followed by the noise of every pair and of the number of valid clients. At least
+	//n-t servers reshare by the reshare due and t of them may collude, the noise of any n-2t servers adds up to the
+	//noise of the mechanism.
+	var noises []int
+	if noise != nil {
+		noises = make([]int, s.cfg.N_secrets+len(pairs)+1)
+		for k := range noises {
+			noises[k], err = noise.Sample(s.cfg.N, 2*s.cfg.T, nil)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	groupShares := make([][]Shares, len(groups))
+	for g := range groups {
+		groupShares[g], err = s.split(local[g])
+		if err != nil {
+			return err
+		}
+	}
+	noiseShares, err := s.split(noises)
+	if err != nil {
+		return err
+	}
+
+	messages := make([]ProductShareRequest, s.cfg.N)
+	for j := range messages {
+		messages[j] = ProductShareRequest{Exp_ID: exp.Exp_ID, Server_ID: s.cfg.Server_ID, Party: party, Noise: noiseShares[j]}
+		for g, holders := range groups {
+			messages[j].Groups = append(messages[j].Groups, GroupShares{Holders: holders, Shares: groupShares[g][j]})
+		}
+	}
+
+	var wg sync.WaitGroup
+	for j, address := range s.cfg.Product_share_urls {
+		if j == party {
+			continue
+		}
+		wg.Add(1)
+		go func(addr string, message ProductShareRequest) {
+			defer wg.Done()
+			log.Printf("server %s is sending product shares to %s\n", s.cfg.Server_ID, addr)
+			writer := &message
+			send(addr, writer.ToJson())
+		}(address, messages[j])
+	}
+	wg.Wait()
+
+	shares, err := json.Marshal(messages[party].Groups)
+	if err != nil {
+		return err
+	}
+	noise_shares, err := json.Marshal(messages[party].Noise)
+	if err != nil {
+		return err
+	}
+	return s.store.InsertProductShare(exp.Exp_ID, s.cfg.Server_ID, party, shares, noise_shares)
+}
+
+// split splits every secret with the secret sharing of the servers, the shares of server j of secret k are
+// in Values[k] of the j-th result
+func (s *Server) split(secrets []int) ([]Shares, error) {
+	parties := make([]Shares, s.cfg.N)
+	for j := range parties {
+		parties[j] = Shares{Values: make([][]int, len(secrets)), PartyIndex: j}
+	}
+	for k, value := range secrets {
+		_, shares, err := s.sharing.Split(value)
+		if err != nil {
+			return nil, err
+		}
+		for j, part := range shares {
+			index := make([]int, len(part))
+			values := make([]int, len(part))
+			for idx, sh := range part {
+				index[idx], values[idx] = sh.Index, sh.Value
+			}
+			parties[j].Index = index
+			parties[j].Values[k] = values
+		}
+	}
+	return parties, nil
+}
+
+// reshared are the single shamir shares of a server of the sums of the products of every pair, of the checks of the
+// reshared sums and of the noise of every input and pair, and the parties whose resharings were added up
+type reshared struct {
+	products Shares
+	checks   Shares
+	noise    Shares
+	parties  []int
+}
+
+// resharing are the shares a party reshared, of the sum of every group it belongs to and of its noise
+type resharing struct {
+	groups map[string]Shares
+	noise  Shares
+}
+
+// collectReshared adds up the shares of the products and of the noise reshared by the servers. It reports false
+// while some server did not reshare before the reshare due; after it the resharings received are added up, which
+// fails if less than n-t servers reshared noise or no server of a group reshared its products.
+//
+// The product of two shares is held by a group of at least n-2t servers, so that a group has an honest server for
+// n > 3t. The sum of a group is taken from its first server that reshared, the differences of the sums of the other
+// servers of the group are the checks, which the output party reconstructs and which are 0 unless a server cheated.
+func (s *Server) collectReshared(exp_id string, n_pairs, n_noise int, due bool) (reshared, bool, error) {
+	records, err := s.store.GetProductSharesPerExperiment(exp_id)
+	if err != nil {
+		return reshared{}, false, err
+	}
+	if len(records) < s.cfg.N && !due {
+		return reshared{}, false, nil
+	}
+
+	//the shares reshared to the server are shares of its party, including the ones it reshared to itself
+	var own *resharing
+	party := s.cfg.Share_Index
+	for _, record := range records {
+		if record.Server_ID == s.cfg.Server_ID {
+			own, err = s.decodeResharing(record, n_pairs, n_noise, party)
+			if err != nil {
+				return reshared{}, true, fmt.Errorf("own resharing: %w", err)
+			}
+		}
+	}
+	if own == nil {
+		return reshared{}, true, fmt.Errorf("%s did not reshare", s.cfg.Server_ID)
+	}
+
+	//a party claimed by several servers is ignored
+	claims := make(map[int]int)
+	for _, record := range records {
+		claims[record.Party]++
+	}
+	resharings := make(map[int]*resharing)
+	for _, record := range records {
+		r, err := s.decodeResharing(record, n_pairs, n_noise, party)
+		if err == nil && claims[record.Party] > 1 {
+			err = fmt.Errorf("party %d is claimed by %d servers", record.Party, claims[record.Party])
+		}
+		if err != nil {
+			log.Printf("%s ignores the resharing of %s - error: %s\n", s.cfg.Server_ID, record.Server_ID, err)
+			continue
+		}
+		resharings[record.Party] = r
+	}
+
+	var result reshared
+	for p := 0; p < s.cfg.N; p++ {
+		if resharings[p] != nil {
+			result.parties = append(result.parties, p)
+		}
+	}
+	if n_noise > 0 && len(result.parties) < s.cfg.N-s.cfg.T {
+		return reshared{}, true, fmt.Errorf("%d of %d servers reshared noise, %d needed", len(result.parties), s.cfg.N, s.cfg.N-s.cfg.T)
+	}
+
+	if n_pairs > 0 {
+		for _, group := range rss.Groups(s.cfg.N, s.cfg.T) {
+			var holders []int
+			for _, p := range group {
+				if resharings[p] != nil {
+					holders = append(holders, p)
+				}
+			}
+			if len(holders) == 0 {
+				return reshared{}, true, fmt.Errorf("no server of group %v reshared its products", group)
+			}
+
+			first := resharings[holders[0]].groups[fmt.Sprint(group)]
+			result.products, err = s.addReshared(result.products, first, n_pairs)
+			if err != nil {
+				return reshared{}, true, err
+			}
+			for _, p := range holders[1:] {
+				diff, err := s.subReshared(resharings[p].groups[fmt.Sprint(group)], first)
+				if err != nil {
+					return reshared{}, true, err
+				}
+				result.checks = appendShares(result.checks, diff)
+			}
+		}
+	}
+	for _, p := range result.parties {
+		result.noise, err = s.addReshared(result.noise, resharings[p].noise, n_noise)
+		if err != nil {
+			return reshared{}, true, fmt.Errorf("noise of party %d: %w", p, err)
+		}
+	}
+
+	for _, shares := range []*Shares{&result.products, &result.checks, &result.noise} {
+		if len(shares.Values) > 0 {
+			*shares, err = s.toShamir(*shares)
+			if err != nil {
+				return reshared{}, true, err
+			}
+		}
+	}
+	return result, true, nil
+}
+
+// decodeResharing decodes the resharing of a server for the party, with the shares of every group of its party and
+// of n_noise noises. The shares have to be shares of the party with the indices of the shares of the party.
+func (s *Server) decodeResharing(record sqlstore.ProductShare, n_pairs, n_noise, party int) (*resharing, error) {
+	if record.Party < 0 || record.Party >= s.cfg.N {
+		return nil, fmt.Errorf("party %d out of range", record.Party)
+	}
+
+	var groups []GroupShares
+	if len(record.Shares) > 0 {
+		err := json.Unmarshal(record.Shares, &groups)
+		if err != nil {
+			return nil, fmt.Errorf("cannot unmarshall product shares: %w", err)
+		}
+	}
+	r := &resharing{groups: make(map[string]Shares)}
+	if len(record.Noise) > 0 {
+		err := json.Unmarshal(record.Noise, &r.noise)
+		if err != nil {
+			return nil, fmt.Errorf("cannot unmarshall noise shares: %w", err)
+		}
+	}
+
+	var expected [][]int
+	if n_pairs > 0 {
+		for _, group := range rss.Groups(s.cfg.N, s.cfg.T) {
+			for _, p := range group {
+				if p == record.Party {
+					expected = append(expected, group)
+				}
+			}
+		}
+	}
+	if len(groups) != len(expected) {
+		return nil, fmt.Errorf("products of %d groups, expected %d", len(groups), len(expected))
+	}
+
+	index := ligero.ShareIndices(s.cfg.Sharing, s.cfg.N, s.cfg.T, party)
+
+	for g, group := range groups {
+		if !equal(group.Holders, expected[g]) {
+			return nil, fmt.Errorf("products of group %v, expected %v", group.Holders, expected[g])
+		}
+		err := checkReshared(group.Shares, n_pairs, party, index)
+		if err != nil {
+			return nil, fmt.Errorf("products of group %v: %w", group.Holders, err)
+		}
+		r.groups[fmt.Sprint(group.Holders)] = group.Shares
+	}
+	err := checkReshared(r.noise, n_noise, party, index)
+	if err != nil {
+		return nil, fmt.Errorf("noise: %w", err)
+	}
+	return r, nil
+}
+
+// checkReshared checks that shares reshared to a party are shares of n values of the party, with a share of every
+// index, and have the given indices if they are not nil
+func checkReshared(shares Shares, n, party int, index []int) error {
+	if n == 0 {
+		return nil
+	}
+	if len(shares.Values) != n {
+		return fmt.Errorf("%d values, expected %d", len(shares.Values), n)
+	}
+	if shares.PartyIndex != party || (index != nil && !equal(shares.Index, index)) {
+		return fmt.Errorf("shares %v of party %d, expected shares %v of party %d", shares.Index, shares.PartyIndex, index, party)
+	}
+	for k, values := range shares.Values {
+		if len(values) != len(shares.Index) {
+			return fmt.Errorf("%d shares of value %d, expected %d", len(values), k, len(shares.Index))
+		}
+	}
+	return nil
+}
+
+// addReshared adds the shares of n values reshared by a server to the shares reshared by the other servers,
+// which must be shares of the same party with the same indices
+func (s *Server) addReshared(sum, shares Shares, n int) (Shares, error) {
+	if n == 0 {
+		return sum, nil
+	}
+	if sum.Values == nil {
+		sum = Shares{Index: shares.Index, Values: make([][]int, n), PartyIndex: shares.PartyIndex}
+		for k := range sum.Values {
+			sum.Values[k] = make([]int, len(shares.Index))
+		}
+	}
+	err := checkReshared(shares, n, sum.PartyIndex, sum.Index)
+	if err != nil {
+		return Shares{}, err
+	}
+
+	for k, values := range shares.Values {
+		for idx, value := range values {
+			sum.Values[k][idx] = s.field.AddInt(sum.Values[k][idx], value)
+		}
+	}
+	return sum, nil
+}
+
+// subReshared returns the shares of the differences of the values of a and b, shares of the same party with the
+// same indices
+func (s *Server) subReshared(a, b Shares) (Shares, error) {
+	err := checkReshared(a, len(b.Values), b.PartyIndex, b.Index)
+	if err != nil {
+		return Shares{}, err
+	}
+
+	diff := Shares{Index: a.Index, Values: make([][]int, len(a.Values)), PartyIndex: a.PartyIndex}
+	for k, values := range a.Values {
+		diff.Values[k] = make([]int, len(values))
+		for idx, value := range values {
+			diff.Values[k][idx] = s.field.SubInt(value, b.Values[k][idx])
+		}
+	}
+	return diff, nil
+}
+
+// appendShares appends the values of shares to the values of list, shares of the same party
+func appendShares(list, shares Shares) Shares {
+	list.Index, list.PartyIndex = shares.Index, shares.PartyIndex
+	list.Values = append(list.Values, shares.Values...)
+	return list
+}
+
+// addNoise adds the shares of the noise of every sum to the single shamir shares of the sums, the sums of the
+// inputs followed by the sums of the pairs and the number of valid clients
+func (s *Server) addNoise(noise Shares, sums ...Shares) error {
+	n := 0
+	for _, shares := range sums {
+		n += len(shares.Values)
+	}
+	if len(noise.Values) != n {
+		return fmt.Errorf("%d noise shares for %d sums", len(noise.Values), n)
+	}
+	k := 0
+	for _, sum := range sums {
+		for i := range sum.Values {
+			values := noise.Values[k]
+			if len(values) != 1 || len(sum.Values[i]) != 1 || !equal(noise.Index, sum.Index) {
+				return fmt.Errorf("noise %d does not fit the shares of its sum", k)
+			}
+			sum.Values[i][0] = s.field.AddInt(sum.Values[i][0], values[0])
+			k++
+		}
+	}
+	return nil
+}
+
+// rssShares pairs the indices of the shares of a party with their values
+func rssShares(index []int, values []int) []rss.Share {
+	shares := make([]rss.Share, len(values))
+	for idx, value := range values {
+		shares[idx] = rss.Share{Index: index[idx], Value: value}
+	}
+	return shares
+}
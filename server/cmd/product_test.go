@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"example.com/SMC/pkg/field"
+	"example.com/SMC/pkg/ligero"
+	"example.com/SMC/pkg/rss"
+	"example.com/SMC/server/config"
+	"example.com/SMC/server/sqlstore"
+)
+
+func TestAddReshared(t *testing.T) {
+	f, err := field.New(41)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	s := &Server{field: f}
+
+	sum := Shares{Index: []int{1, 2}, Values: [][]int{{40, 2}}, PartyIndex: 0}
+	for _, test := range []struct {
+		name   string
+		shares Shares
+		n      int
+	}{
+		{"values", Shares{Index: []int{1, 2}, Values: [][]int{{1, 1}, {1, 1}}, PartyIndex: 0}, 1},
+		{"party", Shares{Index: []int{1, 2}, Values: [][]int{{1, 1}}, PartyIndex: 1}, 1},
+		{"index", Shares{Index: []int{1, 3}, Values: [][]int{{1, 1}}, PartyIndex: 0}, 1},
+		{"shares", Shares{Index: []int{1, 2}, Values: [][]int{{1}}, PartyIndex: 0}, 1},
+		{"missing", Shares{}, 1},
+	} {
+		_, err := s.addReshared(sum, test.shares, test.n)
+		if err == nil {
+			t.Fatalf("%s: shares added up", test.name)
+		}
+	}
+
+	result, err := s.addReshared(sum, Shares{Index: []int{1, 2}, Values: [][]int{{1, 1}}, PartyIndex: 0}, 1)
+	if err != nil || !reflect.DeepEqual(result.Values, [][]int{{0, 3}}) {
+		t.Fatalf("expected [[0 3]], but got %v (%v)", result.Values, err)
+	}
+
+	// the first shares added up are not changed by later ones
+	first := Shares{Index: []int{1, 2}, Values: [][]int{{1, 1}}, PartyIndex: 0}
+	result, err = s.addReshared(Shares{}, first, 1)
+	if err == nil {
+		_, err = s.addReshared(result, first, 1)
+	}
+	if err != nil || !reflect.DeepEqual(first.Values, [][]int{{1, 1}}) {
+		t.Fatalf("first shares changed to %v (%v)", first.Values, err)
+	}
+}
+
+func TestDecodeResharing(t *testing.T) {
+	s := &Server{cfg: &config.Server{Share_Index: 0, N: 4, T: 1}}
+
+	// the resharing of party 2 to the given party
+	resharing := func(party int) []byte {
+		var groups []GroupShares
+		for _, group := range rss.Groups(4, 1) {
+			for _, p := range group {
+				if p == 2 {
+					index := ligero.ShareIndices(ligero.SharingRSS, 4, 1, party)
+					values := [][]int{make([]int, len(index))}
+					groups = append(groups, GroupShares{Holders: group, Shares: Shares{Index: index, Values: values, PartyIndex: party}})
+				}
+			}
+		}
+		data, err := json.Marshal(groups)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		return data
+	}
+
+	_, err := s.decodeResharing(sqlstore.ProductShare{Server_ID: "s3", Party: 2, Shares: resharing(0)}, 1, 0, 0)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	_, err = s.decodeResharing(sqlstore.ProductShare{Server_ID: "s3", Party: 2, Shares: resharing(1)}, 1, 0, 0)
+	if err == nil {
+		t.Fatalf("shares of party 1 accepted by the server of party 0")
+	}
+}
+
+func TestSubReshared(t *testing.T) {
+	f, err := field.New(41)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	s := &Server{field: f}
+
+	a := Shares{Index: []int{1, 2}, Values: [][]int{{5, 0}}, PartyIndex: 2}
+	diff, err := s.subReshared(a, Shares{Index: []int{1, 2}, Values: [][]int{{2, 1}}, PartyIndex: 2})
+	if err != nil || !reflect.DeepEqual(diff.Values, [][]int{{3, 40}}) {
+		t.Fatalf("expected [[3 40]], but got %v (%v)", diff.Values, err)
+	}
+
+	for _, b := range []Shares{
+		{Index: []int{1, 2}, Values: [][]int{{2, 1}, {2, 1}}, PartyIndex: 2},
+		{Index: []int{1, 2}, Values: [][]int{{2, 1}}, PartyIndex: 3},
+		{Index: []int{2, 3}, Values: [][]int{{2, 1}}, PartyIndex: 2},
+	} {
+		_, err := s.subReshared(a, b)
+		if err == nil {
+			t.Fatalf("shares %+v subtracted from %+v", b, a)
+		}
+	}
+}
+
+func TestAddNoise(t *testing.T) {
+	f, err := field.New(41)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	s := &Server{field: f}
+
+	shares := func() (Shares, Shares, Shares) {
+		return Shares{Index: []int{1}, Values: [][]int{{3}, {4}}}, Shares{Index: []int{1}, Values: [][]int{{5}}},
+			Shares{Index: []int{1}, Values: [][]int{{10}}}
+	}
+
+	// the noise of the inputs is followed by the noise of the products and of the number of clients
+	sums, products, count := shares()
+	err = s.addNoise(Shares{Index: []int{1}, Values: [][]int{{1}, {40}, {2}, {39}}}, sums, products, count)
+	if err != nil || !reflect.DeepEqual(sums.Values, [][]int{{4}, {3}}) || !reflect.DeepEqual(products.Values, [][]int{{7}}) ||
+		!reflect.DeepEqual(count.Values, [][]int{{8}}) {
+		t.Fatalf("expected [[4] [3]] [[7]] [[8]], but got %v %v %v (%v)", sums.Values, products.Values, count.Values, err)
+	}
+
+	for _, test := range []struct {
+		name  string
+		noise Shares
+	}{
+		{"missing", Shares{}},
+		{"sums", Shares{Index: []int{1}, Values: [][]int{{1}, {1}, {1}}}},
+		{"shares", Shares{Index: []int{1}, Values: [][]int{{1}, {1}, {1}, {1, 1}}}},
+		{"index", Shares{Index: []int{2}, Values: [][]int{{1}, {1}, {1}, {1}}}},
+	} {
+		sums, products, count := shares()
+		err := s.addNoise(test.noise, sums, products, count)
+		if err == nil {
+			t.Fatalf("%s: noise added", test.name)
+		}
+	}
+}
@@ -9,28 +9,44 @@ import (
 	"crypto/x509"
 	"encoding/json"
 	"encoding/pem"
+	"errors"
 	"fmt"
 	"io"
 	"log"
+	"mime"
 	"net/http"
 	"os"
 	"path/filepath"
 	"sync"
 	"time"
 
+	"example.com/SMC/pkg/field"
+	"example.com/SMC/pkg/ligero"
 	"example.com/SMC/pkg/rss"
+	"example.com/SMC/pkg/shamir"
 	"example.com/SMC/server/config"
 	"example.com/SMC/server/sqlstore"
 	"github.com/sirupsen/logrus"
 )
 
 type Server struct {
-	cfg   *config.Server
-	store *sqlstore.DB
+	cfg     *config.Server
+	store   *sqlstore.DB
+	field   *field.Field
+	sharing ligero.SecretSharing
+	batches *clientBatches
 }
 
 func NewServer(conf *config.Server) *Server {
-	return &Server{cfg: conf, store: sqlstore.NewDB(conf.Server_ID)}
+	f, err := field.NewInt(conf.Q)
+	if err != nil {
+		log.Fatalf("invalid modulus %d: %s", conf.Q, err)
+	}
+	sharing, err := ligero.NewSecretSharing(conf.Sharing, conf.N, conf.T, conf.Q, nil)
+	if err != nil {
+		log.Fatalf("invalid sharing: %s", err)
+	}
+	return &Server{cfg: conf, store: sqlstore.NewDB(conf.Server_ID), field: f, sharing: sharing, batches: newClientBatches()}
 }
 
 func (s *Server) Start() {
@@ -40,6 +56,8 @@ func (s *Server) Start() {
 	http.HandleFunc("/maskedShare/", s.serverMaskedSharesHandler)
 	http.HandleFunc("/dolevComplaint/", s.dolevComplaintHandler)
 	http.HandleFunc("/dolevMaskedShare/", s.dolevMaskedSharesHandler)
+	http.HandleFunc("/productShare/", s.productSharesHandler)
+	s.serveOperator()
 
 	log.Fatal(http.ListenAndServe(":"+s.cfg.Port, nil))
 
@@ -52,6 +70,8 @@ func (s *Server) StartTLS() {
 	http.HandleFunc("/maskedShare/", s.serverMaskedSharesHandler)
 	http.HandleFunc("/dolevComplaint/", s.dolevComplaintHandler)
 	http.HandleFunc("/dolevMaskedShare/", s.dolevMaskedSharesHandler)
+	http.HandleFunc("/productShare/", s.productSharesHandler)
+	s.serveOperator()
 
 	log.Fatal(http.ListenAndServeTLS(":"+s.cfg.Port, s.cfg.Cert_path, s.cfg.Key_path, nil))
 
@@ -108,48 +128,141 @@ func (s *Server) HandleExp(path string) {
 			"client_share_due":    exp.ClientShareDue,
 			"complaint_due":       exp.ComplaintDue,
 			"share_broadcast_due": exp.ShareBroadcastDue,
+			"reshare_due":         exp.ReshareDue,
 			"owner":               exp.Owner,
+			"predicate":           exp.Predicate,
+			"products":            exp.Products,
+			"noise":               exp.Noise,
+			"population":          exp.Population,
 		}).Info("")
 
-		err := expService.CreateExperiment(exp)
+		err := expService.CreateExperiment(exp, s.cfg)
 		if err != nil {
 			log.Printf("%s cannot creat experiment - error: %s\n", s.cfg.Server_ID, err)
 		}
 	}
 
+	budgets, err := expService.PrivacyBudgets(s.cfg)
+	if err != nil {
+		log.Printf("%s cannot retreive privacy budgets - error: %s\n", s.cfg.Server_ID, err)
+		return
+	}
+	for _, budget := range budgets {
+		fields := logrus.Fields{
+			"population":    budget.Population,
+			"experiments":   budget.Experiments,
+			"epsilon_spent": budget.Epsilon_spent,
+			"delta_spent":   budget.Delta_spent,
+		}
+		if budget.Epsilon_remaining != nil {
+			fields["epsilon_remaining"], fields["delta_remaining"] = *budget.Epsilon_remaining, *budget.Delta_remaining
+		}
+		logger.WithFields(fields).Info("privacy budget")
+	}
+
 }
 
 func (s *Server) clientRequestHandler(rw http.ResponseWriter, req *http.Request) {
-	rw.WriteHeader(http.StatusOK)
-
 	var request ClientRequest
+	var data ClientRequest
+	var err error
+
+	//proofs are sent in binary, json is kept as fallback for older clients
+	content_type, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	switch content_type {
+	case ligero.ContentTypeBinary:
+		data, err = request.ReadBinary(req, s.cfg.Q)
+	case "application/json", "":
+		data, err = request.ReadJson(req)
+	default:
+		rw.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+	if err != nil {
+		log.Printf("%s cannot read client request - error: %s\n", s.cfg.Server_ID, err)
+		rw.WriteHeader(errorStatus(err))
+		fmt.Fprintln(rw, err)
+		return
+	}
 
-	clientService := NewClientService(s.store)
-	data := request.ReadJson(req)
+	err = NewClientService(s.store).CheckPredicate(data)
+	if err != nil {
+		log.Printf("%s cannot accept client request - error: %s\n", s.cfg.Server_ID, err)
+		rw.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(rw, err)
+		return
+	}
 
-	go func() {
+	// requests are accepted until the batches of the experiment are closed at the client share due
+	if !s.batches.begin(data.Exp_ID) {
+		log.Printf("%s cannot accept client request of %s after client share due\n", s.cfg.Server_ID, data.Exp_ID)
+		rw.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(rw, "client share due of experiment %s passed\n", data.Exp_ID)
+		return
+	}
+	rw.WriteHeader(http.StatusOK)
 
-		err := clientService.CreateClientShare(data, s.cfg)
+	go s.ingestClientShare(data)
 
-		if err != nil {
-			log.Printf("%s cannot create client share - error: %s\n", s.cfg.Server_ID, err)
-		}
+}
 
-		client_count := s.store.CountComplaintsPerExperiment(data.Exp_ID)
-		if int(client_count) == client_size {
-			real_client_share_due = time.Now().UTC() // time to start the step of assemble complaints and broadcast without waiting
-		}
+// errorStatus maps an error of a request to the HTTP status of the response: malformed requests are the client's
+// fault, parameters that cannot be used are the server's. Proofs are verified after the response, so an invalid
+// proof is never reported to the client, it only shows up as a complaint against the client.
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ligero.ErrInvalidEncoding), errors.Is(err, ligero.ErrDimensionMismatch):
+		return http.StatusBadRequest
+	case errors.Is(err, ligero.ErrInvalidParams):
+		return http.StatusInternalServerError
+	}
+	return http.StatusBadRequest
+}
+
+// serveOperator serves the endpoints for operators on a listener of their own at Operator_addr, not on the port of
+// the clients and servers, and not at all without an Operator_addr
+func (s *Server) serveOperator() {
+	if s.cfg.Operator_addr == "" {
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/privacyBudget/", s.privacyBudgetHandler)
+	go func() {
+		log.Fatal(http.ListenAndServe(s.cfg.Operator_addr, mux))
 	}()
+}
+
+// privacyBudgetHandler shows operators the privacy loss and the remaining budget of every population
+func (s *Server) privacyBudgetHandler(rw http.ResponseWriter, req *http.Request) {
+	budgets, err := NewExperimentService(s.store).PrivacyBudgets(s.cfg)
+	if err != nil {
+		log.Printf("error: %s\n", err)
+		rw.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintln(rw, err)
+		return
+	}
 
+	rw.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(rw).Encode(budgets)
+	if err != nil {
+		log.Printf("error: %s\n", err)
+	}
 }
 
 func (s *Server) serverComplaintHandler(rw http.ResponseWriter, req *http.Request) {
-	rw.WriteHeader(http.StatusOK)
-
 	var request ComplaintRequest
 
+	data, err := request.ReadJson(req)
+	if err != nil {
+		log.Printf("%s cannot read complaint request - error: %s\n", s.cfg.Server_ID, err)
+		rw.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(rw, err)
+		return
+	}
+	rw.WriteHeader(http.StatusOK)
+
 	serverService := NewServerService(s.store)
-	data := request.ReadJson(req)
 
 	go func() {
 
@@ -169,12 +282,18 @@ func (s *Server) serverComplaintHandler(rw http.ResponseWriter, req *http.Reques
 }
 
 func (s *Server) serverMaskedSharesHandler(rw http.ResponseWriter, req *http.Request) {
-	rw.WriteHeader(http.StatusOK)
-
 	var request MaskedShareRequest
 
+	data, err := request.ReadJson(req)
+	if err != nil {
+		log.Printf("%s cannot read masked share request - error: %s\n", s.cfg.Server_ID, err)
+		rw.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(rw, err)
+		return
+	}
+	rw.WriteHeader(http.StatusOK)
+
 	serverService := NewServerService(s.store)
-	data := request.ReadJson(req)
 
 	go func() {
 
@@ -212,6 +331,12 @@ func (s *Server) WaitForEndOfClientShareBroadcast(ticker *time.Ticker) {
 
 			if currentTime.After(due) {
 
+				// requests still being stored are waited for, then the requests of a batch that did not fill up
+				// are verified before the complaints are sent
+				s.batches.close(exp.Exp_ID)
+				s.flushClientShares(exp.Exp_ID)
+				s.batches.forget(exp.Exp_ID)
+
 				get_complaints_start := time.Now()
 				complaints, err := s.store.GetComplaintsPerServer(exp.Exp_ID, s.cfg.Server_ID)
 				if err != nil {
@@ -350,7 +475,7 @@ func (s *Server) WaitForEndOfComplaintBroadcast(ticker *time.Ticker) {
 							for input_index, sh_list := range shares.Values {
 								for idx, value := range sh_list {
 									mask := s.getMask(c.Exp_ID, c.Client_ID, input_index, shares.Index[idx])
-									shares.Values[input_index][idx] = value + mask
+									shares.Values[input_index][idx] = s.field.AddInt(value, mask)
 								}
 							}
 
@@ -437,6 +562,46 @@ func (s *Server) WaitForEndOfShareBroadcast(ticker *time.Ticker) {
 
 			if currentTime.After(due) {
 
+				pairs, err := productPairs(exp)
+				if err != nil {
+					log.Printf("%s cannot read products of %s - error: %s\n", s.cfg.Server_ID, exp.Exp_ID, err)
+					continue
+				}
+				noise, err := noiseParams(exp)
+				if err != nil {
+					log.Printf("%s cannot read noise of %s - error: %s\n", s.cfg.Server_ID, exp.Exp_ID, err)
+					continue
+				}
+				n_noise := 0
+				if noise != nil {
+					n_noise = s.cfg.N_secrets + len(pairs) + 1
+				}
+
+				//shares were corrected at an earlier tick, the aggregated shares are sent once all servers reshared their
+				//products and noise or the reshare due passed
+				if exp.Products_Reshared {
+					reshare_due, _ := time.Parse("2006-01-02 15:04:05.999999999 +0000 UTC", exp.ReshareDue)
+					r, done, err := s.collectReshared(exp.Exp_ID, len(pairs), n_noise, currentTime.After(reshare_due))
+					if !done {
+						if err != nil {
+							log.Printf("%s cannot retreive product shares - error: %s\n", s.cfg.Server_ID, err)
+						}
+						continue
+					}
+					if err != nil {
+						//the experiment is aborted, the output party finds no shares of its sums
+						log.Printf("%s cannot add up product and noise shares of %s, sending no shares - error: %s\n", s.cfg.Server_ID, exp.Exp_ID, err)
+						err = s.store.UpdateRound3Completed(exp.Exp_ID)
+						if err != nil {
+							log.Printf("%s cannot set round3 to completed\n", s.cfg.Server_ID)
+							panic(err)
+						}
+						continue
+					}
+					s.sendAggregatedShares(exp, r)
+					continue
+				}
+
 				share_correct_start := time.Now() //share correction start time
 
 				valid_clients, err := s.store.GetValidClientsPerExperiment(exp.Exp_ID)
@@ -483,15 +648,16 @@ func (s *Server) WaitForEndOfShareBroadcast(ticker *time.Ticker) {
 						//remove invalid client from valid set
 						isRemoved := false
 						for _, list := range inputMaskedShares {
-							servers := make([][]rss.Share, len(list))
-							i := 0
-							for _, server_shares := range list {
-								servers[i] = server_shares
-								i++
+							servers := make([][]rss.Share, 0, len(list))
+							ids := make([]string, 0, len(list))
+							for server_id, server_shares := range list {
+								servers = append(servers, server_shares)
+								ids = append(ids, server_id)
+							}
+							_, cheaters, err := s.sharing.RobustReconstruct(servers)
+							for _, c := range cheaters {
+								log.Printf("%s received inconsistent masked shares of %s from %s\n", s.cfg.Server_ID, vc.Client_ID, ids[c])
 							}
-							nrss, _ := rss.NewReplicatedSecretSharing(s.cfg.N, s.cfg.T, s.cfg.Q)
-
-							_, err := nrss.Reconstruct(servers)
 							if err != nil {
 								log.Printf("%s reconstruct fail, need to remove client from valid set - err: %s\n", s.cfg.Server_ID, err)
 								err = s.store.DeleteValidClient(exp.Exp_ID, vc.Client_ID)
@@ -525,21 +691,21 @@ func (s *Server) WaitForEndOfShareBroadcast(ticker *time.Ticker) {
 								}
 
 								for input_index, server_sh := range inputMaskedShares {
-									masked_shares, err := computeMajority(server_sh, s.cfg.T)
-									if err != nil {
-										log.Println("cannot compute majority when doing share correction", err)
-										panic(err)
+									servers := make([][]rss.Share, 0, len(server_sh))
+									for _, sh := range server_sh {
+										servers = append(servers, sh)
 									}
 
-									for _, sh := range masked_shares {
-										mask := s.getMask(exp.Exp_ID, vc.Client_ID, input_index, sh.Index)
-
-										for i := 0; i < len(shares.Index); i++ {
-											if shares.Index[i] == sh.Index {
-												shares.Values[input_index][i] = sh.Value - mask
-											}
+									//recover each own share from the masked shares of the other servers
+									for i, index := range shares.Index {
+										masked_share, err := s.sharing.Recover(servers, index)
+										if err != nil {
+											log.Println("cannot recover share when doing share correction", err)
+											panic(err)
 										}
 
+										mask := s.getMask(exp.Exp_ID, vc.Client_ID, input_index, index)
+										shares.Values[input_index][i] = s.field.SubInt(masked_share, mask)
 									}
 
 								}
@@ -563,49 +729,114 @@ func (s *Server) WaitForEndOfShareBroadcast(ticker *time.Ticker) {
 
 				share_correct_end = time.Since(share_correct_start) //share correction computing time
 
-				clientShares, err := s.store.GetValidClientShares(exp.Exp_ID)
-				if err != nil {
-					log.Printf("%s cannot retreive valid client shares record\n", s.cfg.Server_ID)
-					panic(err)
-				}
+				if len(pairs) > 0 || noise != nil {
+					err = s.reshare(exp, pairs, noise)
+					if err != nil {
+						log.Println("cannot reshare products and noise", err)
+						panic(err)
+					}
 
-				//compute aggregated share
-				aggreShares, err := s.aggregateShares(clientShares)
-				if err != nil {
-					log.Println("cannot aggregate shares", err)
-					panic(err)
+					err = s.store.UpdateProductsReshared(exp.Exp_ID)
+					if err != nil {
+						log.Printf("%s cannot set products to reshared\n", s.cfg.Server_ID)
+						panic(err)
+					}
+					continue
 				}
 
-				/**
-				//test s6 change aggregated share to invalid value
-				if s.cfg.Server_ID == "s6" {
-					aggreShares = []rss.Share{{Index: 0, Value: 27597}, {Index: 2, Value: 28090}, {Index: 3, Value: 35626}, {Index: 4, Value: 36324}, {Index: 5, Value: 38150}}
-				}**/
+				s.sendAggregatedShares(exp, reshared{})
+			}
 
-				msg := AggregatedShareRequest{Exp_ID: exp.Exp_ID, Server_ID: s.cfg.Server_ID, Shares: aggreShares, Timestamp: time.Now().UTC().String()}
-				log.Printf("server %s is sending aggregated shares to %s\n", s.cfg.Server_ID, exp.Owner)
-				writer := &msg
-				send(exp.Owner, writer.ToJson())
+		}
 
-				//set round3 to completed
-				err = s.store.UpdateRound3Completed(exp.Exp_ID)
-				if err != nil {
-					log.Printf("%s cannot set round3 to completed\n", s.cfg.Server_ID)
-					panic(err)
-				}
-			}
+	}
+
+}
+
+// sendAggregatedShares sends the aggregated shares of the valid clients and the reshared shares of the products and
+// their checks to the owner of an experiment and completes it. The shares are not sent if they cannot be aggregated
+// or noised.
+func (s *Server) sendAggregatedShares(exp sqlstore.Experiment, r reshared) {
+	clientShares, err := s.store.GetValidClientShares(exp.Exp_ID)
+	if err != nil {
+		log.Printf("%s cannot retreive valid client shares record\n", s.cfg.Server_ID)
+		panic(err)
+	}
 
+	//set round3 to completed after the shares are sent, or cannot be
+	defer func() {
+		err := s.store.UpdateRound3Completed(exp.Exp_ID)
+		if err != nil {
+			log.Printf("%s cannot set round3 to completed\n", s.cfg.Server_ID)
+			panic(err)
 		}
+	}()
 
+	msg, err := s.aggregatedShareRequest(exp, clientShares, r)
+	if err != nil {
+		log.Printf("%s cannot aggregate the shares of %s, not sending them - error: %s\n", s.cfg.Server_ID, exp.Exp_ID, err)
+		return
 	}
 
+	log.Printf("server %s is sending aggregated shares to %s\n", s.cfg.Server_ID, exp.Owner)
+	send(exp.Owner, msg.ToJson())
 }
 
+// aggregatedShareRequest aggregates the shares of the valid clients into the request sent to the owner of an
+// experiment. With noise the shares of the noise are added first and the number of valid clients is sent as a
+// noised share in Count instead of in Clients.
+func (s *Server) aggregatedShareRequest(exp sqlstore.Experiment, clientShares []sqlstore.ClientShare, r reshared) (AggregatedShareRequest, error) {
+	aggreShares, err := s.aggregateShares(clientShares)
+	if err != nil {
+		return AggregatedShareRequest{}, err
+	}
+
+	/**
+	//test s6 change aggregated share to invalid value
+	if s.cfg.Server_ID == "s6" {
+		aggreShares = []rss.Share{{Index: 0, Value: 27597}, {Index: 2, Value: 28090}, {Index: 3, Value: 35626}, {Index: 4, Value: 36324}, {Index: 5, Value: 38150}}
+	}**/
+
+	//a shamir share of the number of clients, which every server knows, is the number itself
+	clients := len(clientShares)
+	var count Shares
+	noise, err := noiseParams(exp)
+	if err != nil {
+		return AggregatedShareRequest{}, fmt.Errorf("cannot read noise: %w", err)
+	}
+	if noise != nil {
+		count = Shares{Index: r.noise.Index, Values: [][]int{{clients % s.cfg.Q}}, PartyIndex: r.noise.PartyIndex}
+		clients = 0
+		err = s.addNoise(r.noise, aggreShares, r.products, count)
+		if err != nil {
+			return AggregatedShareRequest{}, fmt.Errorf("cannot add noise: %w", err)
+		}
+	}
+
+	return AggregatedShareRequest{Exp_ID: exp.Exp_ID, Server_ID: s.cfg.Server_ID, Shares: aggreShares, Products: r.products, Checks: r.checks, Count: count, Reshared: r.parties, Clients: clients, Timestamp: time.Now().UTC().String()}, nil
+}
+
+// getMask returns the mask of a share. With Shamir sharing the masks of an input are the shares of a random
+// polynomial of degree t, so that the masked shares can still be interpolated.
 func (s *Server) getMask(exp_id, client_id string, input_index, share_index int) int {
 	key := 1
 	crs := NewCryptoRandSource()
-	crs.Seed(key, exp_id, client_id, input_index, share_index)
-	mask := int(crs.Int63(int64(s.cfg.Q)))
+	if s.cfg.Sharing != ligero.SharingShamir {
+		crs.Seed(key, exp_id, client_id, input_index, share_index)
+		mask := int(crs.Int63(int64(s.cfg.Q)))
+		return mask
+	}
+
+	crs.Seed(key, exp_id, client_id, input_index)
+	coeffs := make([]int, s.cfg.T+1)
+	for i := range coeffs {
+		coeffs[i] = int(crs.Int63(int64(s.cfg.Q)))
+	}
+	x := shamir.Point(share_index)
+	mask := 0
+	for i := len(coeffs) - 1; i >= 0; i-- {
+		mask = s.field.AddInt(s.field.MulInt(mask, x), coeffs[i])
+	}
 	return mask
 }
 
@@ -617,25 +848,65 @@ func (s *Server) aggregateShares(clientShares []sqlstore.ClientShare) (Shares, e
 	var aggreShare Shares
 	err := json.Unmarshal(clientShares[0].Shares, &aggreShare)
 	if err != nil {
-		log.Fatalf("Cannot unmarshall %s shares when aggreating shares: %s", clientShares[0].Client_ID, err)
+		return Shares{}, fmt.Errorf("cannot unmarshal %s shares when aggregating shares: %w", clientShares[0].Client_ID, err)
 	}
 
 	for i := 1; i < len(clientShares); i++ {
 		var shares Shares
 		err = json.Unmarshal(clientShares[i].Shares, &shares)
 		if err != nil {
-			log.Fatalf("Cannot unmarshall %s shares when aggreating shares: %s", clientShares[i].Client_ID, err)
-
+			return Shares{}, fmt.Errorf("cannot unmarshal %s shares when aggregating shares: %w", clientShares[i].Client_ID, err)
+		}
+		if !sameShape(shares.Values, aggreShare.Values) {
+			return Shares{}, fmt.Errorf("shares of %s do not match the shares of %s", clientShares[i].Client_ID, clientShares[0].Client_ID)
 		}
 
 		for input_index, sh_list := range shares.Values {
 			for idx, value := range sh_list {
-				aggreShare.Values[input_index][idx] += value
+				aggreShare.Values[input_index][idx] = s.field.AddInt(aggreShare.Values[input_index][idx], value)
 			}
 		}
 	}
 
-	return aggreShare, nil
+	return s.toShamir(aggreShare)
+}
+
+// sameShape reports whether two share vectors have the same number of inputs and of shares per input
+func sameShape(a, b [][]int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if len(a[i]) != len(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// toShamir converts the replicated shares of the party of the server to a single shamir share per input, which is
+// linear and commutes with the sum, shamir shares are returned as they are
+func (s *Server) toShamir(shares Shares) (Shares, error) {
+	nrss, ok := s.sharing.(*rss.ReplicatedSecretSharing)
+	if !ok {
+		return shares, nil
+	}
+
+	party := s.cfg.Share_Index
+	converted := Shares{Index: []int{party}, Values: make([][]int, len(shares.Values)), PartyIndex: party}
+	for input_index, sh_list := range shares.Values {
+		if len(sh_list) != len(shares.Index) {
+			return Shares{}, fmt.Errorf("input %d has %d shares, expected %d", input_index, len(sh_list), len(shares.Index))
+		}
+
+		value, err := nrss.ToShamir(party, rssShares(shares.Index, sh_list))
+		if err != nil {
+			return Shares{}, err
+		}
+		converted.Values[input_index] = []int{value}
+	}
+
+	return converted, nil
 }
 
 func send(address string, data []byte) {
@@ -691,39 +962,15 @@ func count(complaints []sqlstore.Complaint) (int, int, int) {
 	return num_isNotComplain, len(rootCount), maxCount
 }
 
-func computeMajority(input map[string][]rss.Share, t int) ([]rss.Share, error) {
-	if len(input) <= 0 {
-		return nil, fmt.Errorf("masked shares map is empty")
-	}
-
-	shareValue := make(map[int][]int)
-	for _, shares := range input {
-		for _, sh := range shares {
-			v1, check1 := shareValue[sh.Index]
-			if check1 {
-				shareValue[sh.Index] = append(v1, sh.Value)
-			} else {
-				shareValue[sh.Index] = []int{sh.Value}
-			}
-		}
-	}
-
-	result := make([]rss.Share, len(shareValue))
-	for index, values := range shareValue {
-		val, err := FindMajority(values, t)
-		if err != nil {
-			return nil, err
-		}
-
-		result = append(result, rss.Share{Index: index, Value: val})
-	}
-
-	return result, nil
-}
-
 func (s *Server) dolevComplaintHandler(rw http.ResponseWriter, req *http.Request) {
 	var request DolevComplaintRequest
-	data := request.ReadJson(req)
+	data, err := request.ReadJson(req)
+	if err != nil {
+		log.Printf("%s cannot read dolev complaint request - error: %s\n", s.cfg.Server_ID, err)
+		rw.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(rw, err)
+		return
+	}
 	if data.Round_ID <= s.cfg.T+1 {
 		if data.Round_ID == len(data.Signatures) {
 			complaints := fmt.Sprintf("%+v", data.Msg.Complaints)
@@ -756,7 +1003,13 @@ func (s *Server) checkSigChain(msg string, sig_chain []Signature) bool {
 
 func (s *Server) dolevMaskedSharesHandler(rw http.ResponseWriter, req *http.Request) {
 	var request DolevMaskedShareRequest
-	data := request.ReadJson(req)
+	data, err := request.ReadJson(req)
+	if err != nil {
+		log.Printf("%s cannot read dolev masked share request - error: %s\n", s.cfg.Server_ID, err)
+		rw.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintln(rw, err)
+		return
+	}
 	if data.Round_ID <= s.cfg.T+1 {
 		if data.Round_ID == len(data.Signatures) {
 			mask_shares := fmt.Sprintf("%+v", data.Msg.MaskedShares)
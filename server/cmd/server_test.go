@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+
+	"example.com/SMC/pkg/field"
+	"example.com/SMC/server/config"
+	"example.com/SMC/server/sqlstore"
+)
+
+func TestClientRequestHandlerCorruptJson(t *testing.T) {
+	s := &Server{cfg: &config.Server{Server_ID: "s1", Q: 10631}, batches: newClientBatches()}
+
+	var truncated bytes.Buffer
+	writer := gzip.NewWriter(&truncated)
+	writer.Write([]byte(`{"Exp_ID": "exp1", "Client_ID": "c1", "Proof": {`))
+	writer.Close()
+
+	// a corrupt request is refused with 400 instead of stopping the server
+	for _, body := range [][]byte{truncated.Bytes(), []byte(`{"Exp_ID": "exp1"}`)} {
+		req := httptest.NewRequest(http.MethodPost, "/client/", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rw := httptest.NewRecorder()
+		s.clientRequestHandler(rw, req)
+		if rw.Code != http.StatusBadRequest {
+			t.Fatalf("expected status %d, but got %d", http.StatusBadRequest, rw.Code)
+		}
+	}
+}
+
+func TestServerRequestHandlersCorruptJson(t *testing.T) {
+	s := &Server{cfg: &config.Server{Server_ID: "s1", Q: 10631, T: 1}}
+
+	var truncated bytes.Buffer
+	writer := gzip.NewWriter(&truncated)
+	writer.Write([]byte(`{"Exp_ID": "exp1", "Server_ID": "s2", `))
+	writer.Close()
+
+	// corrupt requests of other servers are refused with 400 instead of stopping the server
+	handlers := map[string]struct {
+		handler http.HandlerFunc
+		body    []byte
+	}{
+		"complaint":           {s.serverComplaintHandler, truncated.Bytes()},
+		"masked share":        {s.serverMaskedSharesHandler, []byte(`not gzip`)},
+		"dolev complaint":     {s.dolevComplaintHandler, []byte(`{"Round_ID": `)},
+		"dolev masked shares": {s.dolevMaskedSharesHandler, []byte(`[1, 2]`)},
+	}
+	for name, h := range handlers {
+		rw := httptest.NewRecorder()
+		h.handler(rw, httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(h.body)))
+		if rw.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected status %d, but got %d", name, http.StatusBadRequest, rw.Code)
+		}
+	}
+}
+
+func TestAggregateSharesMalformed(t *testing.T) {
+	f, err := field.NewInt(10631)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	s := &Server{cfg: &config.Server{Server_ID: "s1", Q: 10631}, field: f}
+
+	valid := []byte(`{"Index": [0], "Values": [[1], [2]]}`)
+	for name, record := range map[string][]byte{
+		"undecodable":          []byte(`{"Index": [0], "Values": [[1]`),
+		"fewer inputs":         []byte(`{"Index": [0], "Values": [[1]]}`),
+		"more shares of input": []byte(`{"Index": [0], "Values": [[1], [2, 3]]}`),
+	} {
+		clientShares := []sqlstore.ClientShare{{Client_ID: "c1", Shares: valid}, {Client_ID: "c2", Shares: record}}
+		if _, err := s.aggregateShares(clientShares); err == nil {
+			t.Fatalf("%s: expected an error for the shares of c2", name)
+		}
+	}
+}
+
+func TestAggregatedShareRequestToJson(t *testing.T) {
+	request := AggregatedShareRequest{
+		Exp_ID:    "exp1",
+		Server_ID: "s2",
+		Timestamp: "2024-05-01 12:00:00 +0000 UTC",
+		Shares:    Shares{Index: []int{1}, Values: [][]int{{3}, {5}}, PartyIndex: 1},
+		Products:  Shares{Index: []int{1}, Values: [][]int{{7}}, PartyIndex: 1},
+		Checks:    Shares{Index: []int{1}, Values: [][]int{{0}, {11}}, PartyIndex: 1},
+		Count:     Shares{Index: []int{1}, Values: [][]int{{13}}, PartyIndex: 1},
+		Reshared:  []int{0, 1, 3},
+		Clients:   17,
+	}
+
+	// the output party decompresses and decodes the request, every field has to arrive
+	reader, err := gzip.NewReader(bytes.NewReader(request.ToJson()))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var received AggregatedShareRequest
+	err = json.NewDecoder(reader).Decode(&received)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !reflect.DeepEqual(received, request) {
+		t.Fatalf("expected %+v, but got %+v", request, received)
+	}
+}
+
+var update_aggregated = flag.Bool("update-aggregated", false, "rewrite the aggregated share requests in testdata")
+
+// aggregated_file holds the requests the servers of TestAggregatedShareRequest send, the output party reads them in
+// its tests
+const aggregated_file = "testdata/aggregated_shares.json"
+
+func TestAggregatedShareRequest(t *testing.T) {
+	const q = 41
+	f, err := field.NewInt(q)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	exp := sqlstore.Experiment{Exp_ID: "exp1", Products: "[[0,1]]", Noise: `{"Mechanism":"geometric","Epsilon":1,"Sensitivity":1}`}
+
+	// shamir shares of degree 1 of the given value for the party
+	share := func(value, slope, party int) int {
+		return (value + slope*(party+1)) % q
+	}
+
+	// 3 clients with the inputs (1, 0), (1, 1) and (0, 1), whose sums are 2 and 2 and the sum of whose products is 1.
+	// The noise of the sums is -1 and 2, of the products 1 and of the number of clients -2.
+	inputs := [][]int{{1, 0}, {1, 1}, {0, 1}}
+	noise := []int{q - 1, 2, 1, q - 2}
+	var requests [][]byte
+	for party := 0; party < 4; party++ {
+		s := &Server{cfg: &config.Server{Server_ID: fmt.Sprintf("s%d", party+1), Share_Index: party, N: 4, T: 1, Q: q}, field: f}
+
+		clientShares := make([]sqlstore.ClientShare, len(inputs))
+		for c, values := range inputs {
+			shares := Shares{Index: []int{party}, Values: make([][]int, len(values)), PartyIndex: party}
+			for k, value := range values {
+				shares.Values[k] = []int{share(value, c+k+1, party)}
+			}
+			record, err := json.Marshal(shares)
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			clientShares[c] = sqlstore.ClientShare{Client_ID: fmt.Sprintf("c%d", c+1), Shares: record}
+		}
+
+		r := reshared{
+			products: Shares{Index: []int{party}, Values: [][]int{{share(1, 5, party)}}, PartyIndex: party},
+			checks:   Shares{Index: []int{party}, Values: make([][]int, 14), PartyIndex: party},
+			noise:    Shares{Index: []int{party}, Values: make([][]int, len(noise)), PartyIndex: party},
+			parties:  []int{0, 1, 2, 3},
+		}
+		for i := range r.checks.Values {
+			r.checks.Values[i] = []int{share(0, i+1, party)}
+		}
+		for i, value := range noise {
+			r.noise.Values[i] = []int{share(value, i+2, party)}
+		}
+
+		request, err := s.aggregatedShareRequest(exp, clientShares, r)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if request.Clients != 0 || len(request.Count.Values) != 1 {
+			t.Fatalf("expected a share of the noised number of clients, but got %v clients and %v", request.Clients, request.Count)
+		}
+		request.Timestamp = "2024-05-01 12:00:00 +0000 UTC"
+
+		reader, err := gzip.NewReader(bytes.NewReader(request.ToJson()))
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		body, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		requests = append(requests, body)
+	}
+
+	if *update_aggregated {
+		//a request per line
+		data := bytes.Join(requests, []byte(",\n  "))
+		data = append(append([]byte("[\n  "), data...), "\n]\n"...)
+		err = os.WriteFile(aggregated_file, data, 0644)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(aggregated_file)
+	if err != nil {
+		t.Fatalf("err: %v (run the test with -update-aggregated to write it)", err)
+	}
+	var expected []json.RawMessage
+	err = json.Unmarshal(data, &expected)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(expected) != len(requests) {
+		t.Fatalf("expected %d requests, but got %d", len(expected), len(requests))
+	}
+	for i := range requests {
+		var compact bytes.Buffer
+		err = json.Compact(&compact, expected[i])
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if !bytes.Equal(compact.Bytes(), requests[i]) {
+			t.Fatalf("request of s%d changed:\nexpected %s\ngot      %s", i+1, compact.Bytes(), requests[i])
+		}
+	}
+}
@@ -3,12 +3,15 @@ package main
 import (
 	"bytes"
 	"compress/gzip"
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
 
+	"example.com/SMC/pkg/dp"
 	"example.com/SMC/pkg/ligero"
 )
 
@@ -17,6 +20,7 @@ type ClientRequest struct {
 	Client_ID string       `json:"Client_ID"`
 	Token     string       `json:"Token"`
 	Timestamp string       `json:"Timestamp"`
+	Predicate string       `json:"Predicate"`
 	Proof     ligero.Proof `json:"Proof"`
 }
 
@@ -57,8 +61,9 @@ type MaskedShareRequest struct {
 }
 
 type Shares struct {
-	Index  []int   `json:"Index"`
-	Values [][]int `json:"Values"`
+	Index      []int   `json:"Index"`
+	Values     [][]int `json:"Values"`
+	PartyIndex int     `json:"PartyIndex"`
 }
 
 type MaskedShare struct {
@@ -78,14 +83,54 @@ type AggregatedShareRequest struct {
 	Server_ID string `json:"Server_ID"`
 	Timestamp string `json:"Timestamp"`
 	Shares    Shares `json:"Shares"`
+	Products  Shares `json:"Products"`
+	Checks    Shares `json:"Checks"`   //differences of the product sums reshared by the servers of a group, all 0
+	Count     Shares `json:"Count"`    //noised number of valid clients aggregated, with noise
+	Reshared  []int  `json:"Reshared"` //parties whose reshared products and noise were added up
+	Clients   int    `json:"Clients"`  //number of valid clients aggregated, 0 with noise
+}
+
+// ProductShareRequest carries the shares of the local products and of the noise of a server for the receiving
+// server: for every group of servers the sending party belongs to the shares of its sum of the products held by the
+// group, with the shares of every pair of inputs of the experiment in Values, and the shares of the noise of every
+// input followed by every pair
+type ProductShareRequest struct {
+	Exp_ID    string        `json:"Exp_ID"`
+	Server_ID string        `json:"Server_ID"`
+	Party     int           `json:"Party"`
+	Groups    []GroupShares `json:"Groups"`
+	Noise     Shares        `json:"Noise"`
+}
+
+// GroupShares are the shares of the sums of the products held by the parties of a group
+type GroupShares struct {
+	Holders []int  `json:"Holders"`
+	Shares  Shares `json:"Shares"`
 }
 
 type Experiment struct {
-	Exp_ID            string `json:"Exp_ID"`
-	ClientShareDue    string `json:"ClientShareDue"`
-	ComplaintDue      string `json:"ComplaintDue"`
-	ShareBroadcastDue string `json:"ShareBroadcastDue"`
-	Owner             string `json:"Owner"`
+	Exp_ID            string     `json:"Exp_ID"`
+	ClientShareDue    string     `json:"ClientShareDue"`
+	ComplaintDue      string     `json:"ComplaintDue"`
+	ShareBroadcastDue string     `json:"ShareBroadcastDue"`
+	ReshareDue        string     `json:"ReshareDue"` //end of resharing products and noise, after ShareBroadcastDue
+	Owner             string     `json:"Owner"`
+	Bit_width         int        `json:"Bit_width"` //deprecated, the predicate "range:k" without Predicate
+	Predicate         string     `json:"Predicate"`
+	Products          [][2]int   `json:"Products"`   //pairs of inputs whose products are summed
+	Noise             *dp.Params `json:"Noise"`      //differential privacy of the sums, none if nil
+	Population        string     `json:"Population"` //clients whose privacy budget the experiment spends
+}
+
+// PrivacyBudget is the privacy loss of the experiments on a population and the rest of its budget, which is
+// unlimited if the remaining epsilon and delta are absent
+type PrivacyBudget struct {
+	Population        string   `json:"Population"`
+	Experiments       int      `json:"Experiments"`
+	Epsilon_spent     float64  `json:"Epsilon_spent"`
+	Delta_spent       float64  `json:"Delta_spent"`
+	Epsilon_remaining *float64 `json:"Epsilon_remaining,omitempty"`
+	Delta_remaining   *float64 `json:"Delta_remaining,omitempty"`
 }
 
 type Reader interface {
@@ -163,6 +208,27 @@ func (r *MaskedShareRequest) ToJson() []byte {
 	return compressedData.Bytes()
 }
 
+func (r *ProductShareRequest) ToJson() []byte {
+	message, err := json.Marshal(r)
+
+	if err != nil {
+		log.Fatalf("Cannot marshall product share request: %s", err)
+	}
+
+	// Compress the JSON data using Gzip
+	var compressedData bytes.Buffer
+	gzipWriter := gzip.NewWriter(&compressedData)
+	_, err = gzipWriter.Write(message)
+	if err != nil {
+		log.Fatalf("Cannot compress product share request: %s", err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		log.Fatal(err)
+	}
+
+	return compressedData.Bytes()
+}
+
 func (dr *DolevMaskedShareRequest) ToJson() []byte {
 	msg := &DolevMaskedShareRequest{
 		Round_ID:   dr.Round_ID,
@@ -181,13 +247,7 @@ func (dr *DolevMaskedShareRequest) ToJson() []byte {
 }
 
 func (s *AggregatedShareRequest) ToJson() []byte {
-	msg := &AggregatedShareRequest{
-		Exp_ID:    s.Exp_ID,
-		Server_ID: s.Server_ID,
-		Shares:    s.Shares,
-		Timestamp: s.Timestamp,
-	}
-	message, err := json.Marshal(msg)
+	message, err := json.Marshal(s)
 
 	if err != nil {
 		log.Fatalf("Cannot marshall aggregated share request: %s", err)
@@ -207,109 +267,132 @@ func (s *AggregatedShareRequest) ToJson() []byte {
 	return compressedData.Bytes()
 }
 
-func (c *ClientRequest) ReadJson(req *http.Request) ClientRequest {
+// ReadJson decodes a gzip compressed json client request, malformed requests fail with ligero.ErrInvalidEncoding
+func (c *ClientRequest) ReadJson(req *http.Request) (ClientRequest, error) {
+	var t ClientRequest
+
 	// Decompress the data using Gzip
 	gzipReader, err := gzip.NewReader(req.Body)
 	if err != nil {
-		log.Fatalf("Cannot decompress client request: %s", err)
+		return t, fmt.Errorf("%w: cannot decompress client request: %w", ligero.ErrInvalidEncoding, err)
 	}
 	defer gzipReader.Close()
 
 	decoder := json.NewDecoder(gzipReader)
 
-	var t ClientRequest
 	err = decoder.Decode(&t)
 	if err != nil {
-		log.Fatalf("Cannot decode client request: %s", err)
+		return t, fmt.Errorf("%w: cannot decode client request: %w", ligero.ErrInvalidEncoding, err)
 	}
 
-	return t
+	return t, nil
 }
 
-func (c *ComplaintRequest) ReadJson(req *http.Request) ComplaintRequest {
+// ReadBinary decodes a client request encoded by the client's ToBinary for the field with modulus q
+func (c *ClientRequest) ReadBinary(req *http.Request, q int) (ClientRequest, error) {
+	var t ClientRequest
+	data, err := io.ReadAll(req.Body)
+	if err != nil {
+		return t, err
+	}
+
+	fields := make([]string, 5)
+	for i := range fields {
+		length, n := binary.Uvarint(data)
+		if n <= 0 || length > uint64(len(data)-n) {
+			return t, fmt.Errorf("%w: cannot decode client request: invalid field %d", ligero.ErrInvalidEncoding, i)
+		}
+		fields[i] = string(data[n : n+int(length)])
+		data = data[n+int(length):]
+	}
+	t.Exp_ID, t.Client_ID, t.Token, t.Timestamp, t.Predicate = fields[0], fields[1], fields[2], fields[3], fields[4]
+
+	err = t.Proof.UnmarshalBinary(data, q)
+	if err != nil {
+		return t, fmt.Errorf("cannot decode client proof: %w", err)
+	}
+
+	return t, nil
+}
+
+// ReadJson decodes a complaint request, a request that cannot be decoded is refused without stopping the server
+func (c *ComplaintRequest) ReadJson(req *http.Request) (ComplaintRequest, error) {
+	var t ComplaintRequest
+
 	// Decompress the data using Gzip
 	gzipReader, err := gzip.NewReader(req.Body)
 	if err != nil {
-		log.Fatalf("Cannot decompress complaints request: %s", err)
+		return t, fmt.Errorf("cannot decompress complaints request: %w", err)
 	}
 	defer gzipReader.Close()
 
 	decoder := json.NewDecoder(gzipReader)
-
-	var t ComplaintRequest
 	err = decoder.Decode(&t)
 	if err != nil {
-		log.Fatalf("Cannot decode server complaint: %s", err)
+		return t, fmt.Errorf("cannot decode server complaint: %w", err)
 	}
-	return t
+	return t, nil
 }
 
-func (dc *DolevComplaintRequest) ReadJson(req *http.Request) DolevComplaintRequest {
+// ReadJson decodes a Dolev complaint request, a request that cannot be decoded is refused without stopping the server
+func (dc *DolevComplaintRequest) ReadJson(req *http.Request) (DolevComplaintRequest, error) {
 	decoder := json.NewDecoder(req.Body)
 	var t DolevComplaintRequest
 	err := decoder.Decode(&t)
 	if err != nil {
-		log.Fatalf("Cannot decode server delov complaint: %s", err)
+		return t, fmt.Errorf("cannot decode server delov complaint: %w", err)
 	}
-	return t
+	return t, nil
 }
 
-func (m *MaskedShareRequest) ReadJson(req *http.Request) MaskedShareRequest {
+// ReadJson decodes a masked share request, a request that cannot be decoded is refused without stopping the server
+func (m *MaskedShareRequest) ReadJson(req *http.Request) (MaskedShareRequest, error) {
+	var t MaskedShareRequest
+
 	// Decompress the data using Gzip
 	gzipReader, err := gzip.NewReader(req.Body)
 	if err != nil {
-		log.Fatalf("Cannot decompress masked share request: %s", err)
+		return t, fmt.Errorf("cannot decompress masked share request: %w", err)
 	}
 	defer gzipReader.Close()
 
 	decoder := json.NewDecoder(gzipReader)
-
-	var t MaskedShareRequest
 	err = decoder.Decode(&t)
 	if err != nil {
-		log.Fatalf("Cannot decode masked shares request: %s", err)
+		return t, fmt.Errorf("cannot decode masked shares request: %w", err)
 	}
-	return t
+	return t, nil
 }
 
-func (dm *DolevMaskedShareRequest) ReadJson(req *http.Request) DolevMaskedShareRequest {
-	decoder := json.NewDecoder(req.Body)
-	var t DolevMaskedShareRequest
-	err := decoder.Decode(&t)
+// ReadJson decodes a product share request, a request that cannot be decoded is refused without stopping the server
+func (p *ProductShareRequest) ReadJson(req *http.Request) (ProductShareRequest, error) {
+	var t ProductShareRequest
+
+	// Decompress the data using Gzip
+	gzipReader, err := gzip.NewReader(req.Body)
 	if err != nil {
-		log.Fatalf("Cannot decode server delov masked share: %s", err)
+		return t, fmt.Errorf("cannot decompress product share request: %w", err)
 	}
-	return t
-}
-
-func FindMajority(list []int, t int) (int, error) {
-	maxCount := 0
-	index := -1
-	n := len(list)
-	for i := 0; i < n; i++ {
-		count := 0
-		for j := 0; j < n; j++ {
-			if list[i] == list[j] {
-				count++
-			}
-
-		}
+	defer gzipReader.Close()
 
-		// update maxCount if count of
-		// current element is greater
-		if count > maxCount {
-			maxCount = count
-			index = i
-		}
+	decoder := json.NewDecoder(gzipReader)
+	err = decoder.Decode(&t)
+	if err != nil {
+		return t, fmt.Errorf("cannot decode product share request: %w", err)
 	}
+	return t, nil
+}
 
-	// if maxCount is greater than n/2
-	// return the corresponding element
-	if maxCount >= t+1 {
-		return list[index], nil
+// ReadJson decodes a Dolev masked share request, a request that cannot be decoded is refused without stopping
+// the server
+func (dm *DolevMaskedShareRequest) ReadJson(req *http.Request) (DolevMaskedShareRequest, error) {
+	decoder := json.NewDecoder(req.Body)
+	var t DolevMaskedShareRequest
+	err := decoder.Decode(&t)
+	if err != nil {
+		return t, fmt.Errorf("cannot decode server delov masked share: %w", err)
 	}
-
-	return 0, fmt.Errorf("reconstruct failed: no majority element")
+	return t, nil
 }
 
 func ReadServerInput(path string) []Experiment {
@@ -325,6 +408,29 @@ func ReadServerInput(path string) []Experiment {
 		log.Fatalf("%s", err)
 		return nil
 	}
+
+	for i := range items {
+		items[i].Predicate, err = experimentPredicate(items[i].Bit_width, items[i].Predicate)
+		if err != nil {
+			log.Fatalf("experiment %s: %s", items[i].Exp_ID, err)
+			return nil
+		}
+	}
 	return items
 
 }
+
+// experimentPredicate returns the predicate of an experiment. Bit_width is a deprecated alias of the predicate
+// "range:k" that cannot be combined with a predicate, experiments without either collect 0/1 inputs.
+func experimentPredicate(bit_width int, predicate string) (string, error) {
+	if bit_width != 0 && predicate != "" {
+		return "", fmt.Errorf("Bit_width is deprecated and cannot be combined with Predicate %q", predicate)
+	}
+	if predicate != "" {
+		return predicate, nil
+	}
+	if bit_width != 0 {
+		return ligero.RangePredicate(bit_width).String(), nil
+	}
+	return ligero.BitPredicate().String(), nil
+}
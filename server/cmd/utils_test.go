@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestExperimentPredicate(t *testing.T) {
+	for _, test := range []struct {
+		bit_width int
+		predicate string
+		expected  string
+	}{
+		{0, "", "bit"},
+		{8, "", "range:8"},
+		{0, "onehot", "onehot"},
+	} {
+		predicate, err := experimentPredicate(test.bit_width, test.predicate)
+		if err != nil || predicate != test.expected {
+			t.Fatalf("expected %s, but got %s (%v)", test.expected, predicate, err)
+		}
+	}
+
+	// the deprecated bit width cannot be combined with a predicate, which it would silently contradict
+	_, err := experimentPredicate(8, "onehot")
+	if err == nil {
+		t.Fatalf("bit width accepted with a predicate")
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"log"
 	"os"
+
+	"example.com/SMC/pkg/ligero"
 )
 
 type Server struct {
@@ -16,6 +18,7 @@ type Server struct {
 	Masked_share_urls       []string
 	Dolev_complaint_urls    []string
 	Dolev_masked_share_urls []string
+	Product_share_urls      []string
 	Share_Index             int
 	N                       int
 	T                       int
@@ -23,6 +26,13 @@ type Server struct {
 	N_secrets               int
 	M                       int
 	N_open                  int
+	Repetitions             int
+	Min_soundness_bits      float64
+	Batch_size              int
+	Sharing                 string
+	Epsilon_budget          float64 //total privacy loss of the experiments on a population, unlimited if 0
+	Delta_budget            float64
+	Operator_addr           string //address of the operator endpoints, e.g. 127.0.0.1:50100, not served if empty
 }
 
 func NewConfig() *Server {
@@ -45,5 +55,23 @@ func Load(path string) *Server {
 		log.Fatalf("unable to read from server config file: %s", err)
 		return nil
 	}
+
+	config.check_soundness()
 	return &config
 }
+
+// LigeroParams returns the parameters of the Ligero proofs, Repetitions 0 means a single repetition
+func (config *Server) LigeroParams() ligero.Params {
+	return ligero.Params{N_secret: config.N_secrets, M: config.M, N_server: config.N, T: config.T, Q: config.Q, N_open: config.N_open, Repetitions: config.Repetitions, Sharing: config.Sharing}
+}
+
+// check_soundness refuses Ligero parameters below Min_soundness_bits and warns below the recommended soundness
+func (config *Server) check_soundness() {
+	s, err := ligero.CheckSoundness(config.LigeroParams(), config.Min_soundness_bits)
+	if err != nil {
+		log.Fatalf("insecure ligero parameters in server config file: %s", err)
+	}
+	if s.Bits < ligero.RecommendedSoundnessBits {
+		log.Printf("warning: ligero parameters give %.1f bits of soundness, %d bits are recommended\n", s.Bits, ligero.RecommendedSoundnessBits)
+	}
+}
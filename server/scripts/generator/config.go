@@ -19,6 +19,7 @@ type Server struct {
 	Masked_share_urls       []string
 	Dolev_complaint_urls    []string
 	Dolev_masked_share_urls []string
+	Product_share_urls      []string
 	Share_Index             int
 	N                       int
 	T                       int
@@ -26,6 +27,12 @@ type Server struct {
 	N_secrets               int
 	M                       int
 	N_open                  int
+	Repetitions             int
+	Batch_size              int
+	Sharing                 string
+	Epsilon_budget          float64
+	Delta_budget            float64
+	Operator_addr           string
 }
 
 func GenerateServerConfigLocal(num int, ports []string, src string, des string) {
@@ -57,14 +64,16 @@ func GenerateServerConfigLocal(num int, ports []string, src string, des string)
 		config.Server_ID = "s" + strconv.Itoa(i+1)
 		config.Token = "stk" + strconv.Itoa(i+1)
 		config.Port = ports[i]
-		config.Share_Index = i + 1
+		config.Share_Index = i
 
 		c_urls := make([]string, num-1)
 		m_urls := make([]string, num-1)
 		dc_urls := make([]string, num-1)
 		dm_urls := make([]string, num-1)
+		p_urls := make([]string, num) //product share urls of all servers, including the server itself
 		index := 0
 		for j := 0; j < num; j++ {
+			p_urls[j] = "http://127.0.0.1:" + ports[j] + "/productShare/"
 			if j != i {
 				c_url := "http://127.0.0.1:" + ports[j] + "/complaint/"
 				c_urls[index] = c_url
@@ -82,6 +91,7 @@ func GenerateServerConfigLocal(num int, ports []string, src string, des string)
 
 		config.Complaint_urls = c_urls
 		config.Masked_share_urls = m_urls
+		config.Product_share_urls = p_urls
 
 		file, _ := json.MarshalIndent(config, "", " ")
 		fileName := fmt.Sprintf("config_%s.json", config.Server_ID)
@@ -124,8 +134,10 @@ func GenerateServerConfigCloud(num int, ip []string, src string, des string) {
 		m_urls := make([]string, num-1)
 		dc_urls := make([]string, num-1)
 		dm_urls := make([]string, num-1)
+		p_urls := make([]string, num) //product share urls of all servers, including the server itself
 		index := 0
 		for j := 0; j < num; j++ {
+			p_urls[j] = ip[j] + config.Port + "/productShare/"
 			if j != i {
 				c_url := ip[j] + config.Port + "/complaint/"
 				c_urls[index] = c_url
@@ -142,6 +154,7 @@ func GenerateServerConfigCloud(num int, ip []string, src string, des string) {
 
 		config.Complaint_urls = c_urls
 		config.Masked_share_urls = m_urls
+		config.Product_share_urls = p_urls
 
 		file, _ := json.MarshalIndent(config, "", " ")
 		fileName := fmt.Sprintf("config_%s.json", config.Server_ID)
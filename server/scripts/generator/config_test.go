@@ -1,6 +1,8 @@
 package generator_test
 
 import (
+	"encoding/json"
+	"os"
 	"testing"
 
 	"example.com/SMC/server/scripts/generator"
@@ -9,6 +11,25 @@ import (
 func TestGenerateGonfigLocal(t *testing.T) {
 	ports := []string{"50001", "50002", "50003", "50004", "50005", "50006"}
 	generator.GenerateServerConfigLocal(6, ports, "server_template.json", "./config/")
+
+	//every server reshares products and noise to all servers, including itself
+	data, err := os.ReadFile("./config/config_s2.json")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var config generator.Server
+	err = json.Unmarshal(data, &config)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(config.Product_share_urls) != 6 || config.Product_share_urls[1] != "http://127.0.0.1:50002/productShare/" {
+		t.Fatalf("expected the product share urls of 6 servers, got %v", config.Product_share_urls)
+	}
+
+	//the party of server s2 gets the second proof of a client
+	if config.Share_Index != 1 {
+		t.Fatalf("expected share index 1, got %d", config.Share_Index)
+	}
 }
 
 func TestGenerateGonfigCloud(t *testing.T) {
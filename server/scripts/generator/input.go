@@ -7,17 +7,33 @@ import (
 	"path/filepath"
 	"strconv"
 	"time"
+
+	"example.com/SMC/pkg/dp"
 )
 
 type Experiment struct {
-	Exp_ID            string `json:"Exp_ID"`
-	ClientShareDue    string `json:"ClientShareDue"`
-	ComplaintDue      string `json:"ComplaintDue"`
-	ShareBroadcastDue string `json:"ShareBroadcastDue"`
-	Owner             string `json:"Owner"`
+	Exp_ID            string     `json:"Exp_ID"`
+	ClientShareDue    string     `json:"ClientShareDue"`
+	ComplaintDue      string     `json:"ComplaintDue"`
+	ShareBroadcastDue string     `json:"ShareBroadcastDue"`
+	ReshareDue        string     `json:"ReshareDue,omitempty"`
+	Owner             string     `json:"Owner"`
+	Predicate         string     `json:"Predicate,omitempty"`
+	Products          [][2]int   `json:"Products,omitempty"`
+	Noise             *dp.Params `json:"Noise,omitempty"`
+	Population        string     `json:"Population,omitempty"`
+}
+
+// Options are the optional fields of the generated experiments, the same for every experiment
+type Options struct {
+	Predicate  string
+	Products   [][2]int
+	Noise      *dp.Params
+	Population string
 }
 
-func GenerateServerInput(exp_num int, start_time time.Time, t1 int, t2 int, owner string, des string) {
+// GenerateServerInput generates exp_num experiments, with products or noise ReshareDue = ClientShareDue + t3
+func GenerateServerInput(exp_num int, start_time time.Time, t1 int, t2 int, t3 int, owner string, options Options, des string) {
 	// Ensure the folder exists
 	err := os.MkdirAll(des, os.ModePerm)
 	if err != nil {
@@ -39,6 +55,13 @@ func GenerateServerInput(exp_num int, start_time time.Time, t1 int, t2 int, owne
 			ComplaintDue:      complaint_due,
 			ShareBroadcastDue: share_broadcast_due,
 			Owner:             owner,
+			Predicate:         options.Predicate,
+			Products:          options.Products,
+			Noise:             options.Noise,
+			Population:        options.Population,
+		}
+		if len(options.Products) > 0 || options.Noise != nil {
+			data.ReshareDue = client_share_due.Add(time.Duration(t3) * time.Minute).String()
 		}
 
 		dataList = append(dataList, data)
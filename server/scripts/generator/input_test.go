@@ -1,12 +1,34 @@
 package generator_test
 
 import (
+	"encoding/json"
+	"os"
 	"testing"
 	"time"
 
+	"example.com/SMC/pkg/dp"
 	"example.com/SMC/server/scripts/generator"
 )
 
 func TestGenerateServerInput(t *testing.T) {
-	generator.GenerateServerInput(2, time.Now(), 2, 5, "http://127.0.0.1:50000/serverShare/", "./input")
+	generator.GenerateServerInput(2, time.Now(), 2, 5, 6, "http://127.0.0.1:50000/serverShare/", generator.Options{}, "./input")
+	generator.GenerateServerInput(1, time.Now(), 2, 5, 6, "http://127.0.0.1:50000/serverShare/", generator.Options{
+		Predicate:  "bit",
+		Products:   [][2]int{{0, 1}},
+		Noise:      &dp.Params{Mechanism: dp.MechanismLaplace, Epsilon: 0.5, Sensitivity: 4},
+		Population: "pop1",
+	}, "./input")
+
+	data, err := os.ReadFile("./input/experiments.json")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var experiments []generator.Experiment
+	err = json.Unmarshal(data, &experiments)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(experiments) != 1 || experiments[0].ReshareDue <= experiments[0].ShareBroadcastDue || experiments[0].Noise == nil {
+		t.Fatalf("expected an experiment with noise resharing after the share broadcast due, got %+v", experiments)
+	}
 }
@@ -1,12 +1,15 @@
 package sqlstore
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"os"
 	"time"
 
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"gorm.io/gorm/logger"
 )
 
@@ -14,6 +17,12 @@ type DB struct {
 	DB *gorm.DB
 }
 
+// ErrBudgetExceeded is returned for privacy losses that do not fit into the privacy budget of a population
+var ErrBudgetExceeded = errors.New("privacy budget exceeded")
+
+// budget_tolerance is the relative rounding error of the sums of privacy losses that a budget tolerates
+const budget_tolerance = 1e-9
+
 func NewDB(id string) *DB {
 	db, err := SetupDatabase(id)
 	if err != nil {
@@ -46,7 +55,7 @@ func SetupDatabase(sid string) (*gorm.DB, error) {
 	log.Printf("Connection to %s Database Established\n", sid)
 
 	// Auto-migrate tables
-	if err := db.AutoMigrate(&Experiment{}, &Client{}, &ClientShare{}, &Complaint{}, &ValidClient{}, &MaskedShare{}); err != nil {
+	if err := db.AutoMigrate(&Experiment{}, &Client{}, &ClientShare{}, &Complaint{}, &ValidClient{}, &MaskedShare{}, &ProductShare{}, &PrivacyDebit{}, &PrivacyBudget{}); err != nil {
 		return nil, err
 	}
 
@@ -347,6 +356,31 @@ func (db *DB) CountMaskedSharesPerExperiment(exp_id string) int64 {
 	return count
 }
 
+// insert the shares of the local products and of the noise reshared by a server
+func (db *DB) InsertProductShare(exp_id, server_id string, party int, shares, noise []byte) error {
+	product_share := ProductShare{
+		Exp_ID:    exp_id,
+		Server_ID: server_id,
+		Party:     party,
+		Shares:    shares,
+		Noise:     noise,
+	}
+	result := db.DB.Create(&product_share)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+func (db *DB) GetProductSharesPerExperiment(exp_id string) ([]ProductShare, error) {
+	var product_shares []ProductShare
+	r := db.DB.Find(&product_shares, "exp_id = ? ", exp_id)
+	if r.Error != nil {
+		return nil, r.Error
+	}
+	return product_shares, nil
+}
+
 func (db *DB) InsertEchoMaskedShare(exp_id, server_id, mask_shares string) error {
 	echo := EchoMaskedShare{
 		Exp_ID:       exp_id,
@@ -371,13 +405,17 @@ func (db *DB) GetEchoMaskedShare(exp_id, server_id, mask_shares string) ([]EchoM
 }
 
 // create experiment record in the experiment tables
-func (db *DB) InsertExperiment(exp_id, due1, due2, due3, owner string) error {
+func (db *DB) InsertExperiment(exp_id, due1, due2, due3, due4, owner, predicate, products, noise string) error {
 	exp := &Experiment{
 		Exp_ID:            exp_id,
 		ClientShareDue:    due1,
 		ComplaintDue:      due2,
 		ShareBroadcastDue: due3,
+		ReshareDue:        due4,
 		Owner:             owner,
+		Predicate:         predicate,
+		Products:          products,
+		Noise:             noise,
 		Round1_Completed:  false,
 		Round2_Completed:  false,
 		Round3_Completed:  false,
@@ -482,6 +520,78 @@ func (db *DB) UpdateRound3Completed(exp_id string) error {
 	return nil
 }
 
+// set local products of the inputs to reshared
+func (db *DB) UpdateProductsReshared(exp_id string) error {
+	r := db.DB.Model(&Experiment{}).Where("exp_ID = ?", exp_id).Update("Products_Reshared", true)
+	if r.Error != nil {
+		return r.Error
+	}
+	return nil
+}
+
+// debit the privacy loss of an experiment from the budget of a population, refusing it if the losses of the
+// population would add up to more than max_epsilon or max_delta. The budget row of the population is locked until
+// the debit is written, so that concurrent debits cannot both fit into the remaining budget.
+func (db *DB) DebitPrivacyBudget(population, exp_id string, epsilon, delta, max_epsilon, max_delta float64) error {
+	//the row is created outside of the transaction, inserting an existing row would take a shared lock on it
+	budget := PrivacyBudget{Population: population}
+	r := db.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&budget)
+	if r.Error != nil {
+		return r.Error
+	}
+
+	return db.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&budget, "population = ?", population).Error
+		if err != nil {
+			return err
+		}
+
+		spent, err := privacySpent(tx.Where("population = ?", population))
+		if err != nil {
+			return err
+		}
+		total_epsilon, total_delta := epsilon, delta
+		if len(spent) > 0 {
+			total_epsilon, total_delta = total_epsilon+spent[0].Epsilon, total_delta+spent[0].Delta
+		}
+		if total_epsilon > max_epsilon*(1+budget_tolerance) || total_delta > max_delta*(1+budget_tolerance) {
+			return fmt.Errorf("%w: %s would spend epsilon %g and delta %g of %q, the budget is epsilon %g and delta %g", ErrBudgetExceeded, exp_id, total_epsilon, total_delta, population, max_epsilon, max_delta)
+		}
+
+		debit := PrivacyDebit{
+			Population: population,
+			Exp_ID:     exp_id,
+			Epsilon:    epsilon,
+			Delta:      delta,
+		}
+		return tx.Create(&debit).Error
+	})
+}
+
+// get the privacy losses of every population
+func (db *DB) GetPrivacySpent() ([]PrivacySpent, error) {
+	return privacySpent(db.DB)
+}
+
+// privacySpent adds up the privacy debits of the query per population
+func privacySpent(query *gorm.DB) ([]PrivacySpent, error) {
+	var spent []PrivacySpent
+	r := query.Model(&PrivacyDebit{}).Select("population, count(*) as experiments, sum(epsilon) as epsilon, sum(delta) as delta").Group("population").Order("population").Scan(&spent)
+	if r.Error != nil {
+		return nil, r.Error
+	}
+	return spent, nil
+}
+
+// delete the privacy debit of an experiment, e.g. of an experiment that could not be created
+func (db *DB) DeletePrivacyDebit(population, exp_id string) error {
+	r := db.DB.Where("population = ? and exp_id = ?", population, exp_id).Delete(&PrivacyDebit{})
+	if r.Error != nil {
+		return r.Error
+	}
+	return nil
+}
+
 // delete experiment record from experiment table
 func (db *DB) DeleteExperiment(exp_id string) error {
 	r := db.DB.Delete(&Experiment{Exp_ID: exp_id})
@@ -2,7 +2,10 @@ package sqlstore
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"reflect"
+	"sync"
 	"testing"
 )
 
@@ -295,3 +298,69 @@ func TestComplaint(t *testing.T) {
 	DeleteDB("test.db")
 
 }
+
+func TestPrivacyBudget(t *testing.T) {
+	db := NewDB("test")
+	_ = db.DeletePrivacyDebit("pop1", "exp1")
+	_ = db.DeletePrivacyDebit("pop1", "exp2")
+	_ = db.DeletePrivacyDebit("pop1", "exp3")
+
+	//0.5 and 0.25 of epsilon 1 are spent, 0.5 more exceeds the budget
+	err := db.DebitPrivacyBudget("pop1", "exp1", 0.5, 0, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.DebitPrivacyBudget("pop1", "exp2", 0.25, 0, 1, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.DebitPrivacyBudget("pop1", "exp3", 0.5, 0, 1, 0)
+	if !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("expected ErrBudgetExceeded, got %v", err)
+	}
+	err = db.DebitPrivacyBudget("pop1", "exp3", 0.1, 1e-6, 1, 0)
+	if !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("expected ErrBudgetExceeded for delta, got %v", err)
+	}
+
+	spent, err := db.GetPrivacySpent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range spent {
+		if p.Population == "pop1" && (p.Experiments != 2 || p.Epsilon != 0.75 || p.Delta != 0) {
+			t.Fatalf("spent=%+v, want 2 experiments with epsilon 0.75", p)
+		}
+	}
+}
+
+func TestConcurrentPrivacyBudget(t *testing.T) {
+	db := NewDB("test")
+	for i := 0; i < 8; i++ {
+		_ = db.DeletePrivacyDebit("pop2", fmt.Sprintf("exp%d", i))
+	}
+
+	//only 4 of 8 concurrent debits of 0.25 fit into epsilon 1
+	var wg sync.WaitGroup
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = db.DebitPrivacyBudget("pop2", fmt.Sprintf("exp%d", i), 0.25, 0, 1, 0)
+		}(i)
+	}
+	wg.Wait()
+
+	debited := 0
+	for _, err := range errs {
+		if err == nil {
+			debited++
+		} else if !errors.Is(err, ErrBudgetExceeded) {
+			t.Fatal(err)
+		}
+	}
+	if debited != 4 {
+		t.Fatalf("%d debits of 0.25 fit into epsilon 1", debited)
+	}
+}
@@ -46,15 +46,49 @@ type MaskedShare struct {
 	Shares    []byte `gorm:"type:longblob"`
 }
 
+type ProductShare struct {
+	Exp_ID    string `gorm:"primaryKey"`
+	Server_ID string `gorm:"primaryKey"`
+	Party     int    //party index of the resharing server
+	Shares    []byte `gorm:"type:longblob"`
+	Noise     []byte `gorm:"type:longblob"`
+}
+
 type Experiment struct {
 	Exp_ID            string `gorm:"primaryKey"`
 	ClientShareDue    string
 	ComplaintDue      string
 	ShareBroadcastDue string
+	ReshareDue        string //end of resharing products and noise
 	Owner             string
-	Round1_Completed  bool //round1: client share submission
-	Round2_Completed  bool //round2:complaint broadcast
-	Round3_Completed  bool //round3:masked shares broadcast
+	Predicate         string //validity predicate of client inputs
+	Products          string //json pairs of inputs whose products are summed
+	Noise             string //json parameters of the noise added to the sums
+	Round1_Completed  bool   //round1: client share submission
+	Round2_Completed  bool   //round2:complaint broadcast
+	Round3_Completed  bool   //round3:masked shares broadcast
+	Products_Reshared bool   //local products of the inputs and noise reshared among servers
+}
+
+// PrivacyDebit is an entry of the privacy budget ledger, the privacy loss of an experiment on a population
+type PrivacyDebit struct {
+	Population string `gorm:"primaryKey"`
+	Exp_ID     string `gorm:"primaryKey"`
+	Epsilon    float64
+	Delta      float64
+}
+
+// PrivacyBudget is the row of a population locked while the privacy loss of an experiment is debited from its budget
+type PrivacyBudget struct {
+	Population string `gorm:"primaryKey"`
+}
+
+// PrivacySpent adds up the privacy losses of the experiments on a population
+type PrivacySpent struct {
+	Population  string
+	Experiments int
+	Epsilon     float64
+	Delta       float64
 }
 
 type EchoComplaint struct {